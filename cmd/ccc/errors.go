@@ -0,0 +1,100 @@
+package main
+
+import "fmt"
+
+// knownCommands are the top-level commands accepted by parseArgs.
+var knownCommands = []string{"clean", "list", "cache", "config", "prune", "report", "trash", "audit", "watch"}
+
+// knownSubcommands are the subcommands accepted by each command. Commands
+// without an entry take no subcommand.
+var knownSubcommands = map[string][]string{
+	"clean":  {"projects", "orphans", "config"},
+	"list":   {"projects", "orphans", "config", "duplicates", "corrupt", "sessions"},
+	"cache":  {"clear"},
+	"config": {"consolidate", "lint"},
+	"trash":  {"list", "empty"},
+}
+
+// knownFlags are the long flags accepted by parseArgs, used for suggestions.
+var knownFlags = []string{
+	"--help", "--version", "--dry-run", "--yes", "--yes-to-modify", "--timeout",
+	"--older-than", "--newer-than", "--age-from", "--keep-latest", "--stale-only", "--explain", "--recursive",
+	"--include-unavailable", "--include-global-local", "--no-cache", "--concurrency", "--absolute-time",
+	"--summary-only", "--include-unknown", "--include-empty", "--logs-older-than", "--todo-pattern", "--audit-format", "--audit-log",
+	"--verbose", "--quiet", "--json", "--json-stream", "--diff", "--group-by-entry", "--trash", "--include-claude-home", "--max-delete", "--force", "--fail-fast", "--save-plan", "--apply-plan", "--machine-totals", "--claude-home", "--sort-preview", "--group-by-disk", "--keep-newest-session", "--keep-no-cwd", "--input", "--skip-unknown", "--show-audit-lines", "--select", "--include-locks", "--measure-disk", "--explain-orphan", "--no-kept", "--top",
+	"--since", "--action", "--interval", "--clean", "--normalize",
+	"--ignore-case", "--output", "--assume-missing", "--confirm-assume-missing", "--verify-marker",
+	"--agent", "--project", "--only", "--paths-only", "--format",
+}
+
+// ErrUnknownCommand is returned by parseArgs for an unrecognized command.
+type ErrUnknownCommand struct {
+	Command    string
+	Suggestion string // Closest known command, empty if none is close
+}
+
+func (e *ErrUnknownCommand) Error() string {
+	return fmt.Sprintf("unknown command: %s", e.Command)
+}
+
+// ErrUnknownSubcommand is returned by parseArgs for a subcommand the command
+// does not accept.
+type ErrUnknownSubcommand struct {
+	Command    string
+	Subcommand string
+	Suggestion string // Closest valid subcommand, empty if none is close
+}
+
+func (e *ErrUnknownSubcommand) Error() string {
+	if len(knownSubcommands[e.Command]) == 0 {
+		return fmt.Sprintf("%s does not take a subcommand: %s", e.Command, e.Subcommand)
+	}
+	return fmt.Sprintf("unknown %s subcommand: %s", e.Command, e.Subcommand)
+}
+
+// ErrUnknownFlag is returned by parseArgs for an unrecognized flag.
+type ErrUnknownFlag struct {
+	Flag       string
+	Suggestion string // Closest known flag, empty if none is close
+}
+
+func (e *ErrUnknownFlag) Error() string {
+	return fmt.Sprintf("unknown flag: %s", e.Flag)
+}
+
+// suggest returns the candidate closest to token by edit distance, or "" if
+// none is within a third of the token's length (but at least 2 edits, so
+// transposed letters still match).
+func suggest(token string, candidates []string) string {
+	maxDist := max(len(token)/3, 2)
+
+	best, bestDist := "", maxDist+1
+	for _, c := range candidates {
+		if d := levenshtein(token, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, levenshtein("clean", "clean"))
+	assert.Equal(t, 1, levenshtein("clen", "clean"))
+	assert.Equal(t, 2, levenshtein("lsit", "list"))
+	assert.Equal(t, 5, levenshtein("", "cache"))
+}
+
+func TestSuggest(t *testing.T) {
+	assert.Equal(t, "clean", suggest("clena", knownCommands))
+	assert.Equal(t, "list", suggest("lst", knownCommands))
+	assert.Equal(t, "--dry-run", suggest("--dryrun", knownFlags))
+	assert.Empty(t, suggest("frobnicate", knownCommands))
+}
+
+func TestParseArgs_TypedErrors(t *testing.T) {
+	_, err := parseArgs([]string{"claen"})
+	var cmdErr *ErrUnknownCommand
+	require.True(t, errors.As(err, &cmdErr))
+	assert.Equal(t, "claen", cmdErr.Command)
+	assert.Equal(t, "clean", cmdErr.Suggestion)
+
+	_, err = parseArgs([]string{"clean", "--verbos"})
+	var flagErr *ErrUnknownFlag
+	require.True(t, errors.As(err, &flagErr))
+	assert.Equal(t, "--verbos", flagErr.Flag)
+	assert.Equal(t, "--verbose", flagErr.Suggestion)
+}
+
+func TestKnownFlagsAreAccepted(t *testing.T) {
+	for _, flag := range knownFlags {
+		_, err := parseArgs([]string{flag})
+		var flagErr *ErrUnknownFlag
+		assert.False(t, errors.As(err, &flagErr), flag)
+	}
+}
+
+func TestRunCLI_UnknownCommandSuggestion(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := runCLI([]string{"lsit"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "Error: unknown command: lsit")
+	assert.Contains(t, stderr.String(), "Did you mean 'list'?")
+}
+
+func TestParseArgs_UnknownSubcommand(t *testing.T) {
+	tests := []struct {
+		args       []string
+		command    string
+		suggestion string
+		message    string
+	}{
+		{[]string{"clean", "project"}, "clean", "projects", "unknown clean subcommand: project"},
+		{[]string{"list", "orphan"}, "list", "orphans", "unknown list subcommand: orphan"},
+		{[]string{"cache", "projects"}, "cache", "", "unknown cache subcommand: projects"},
+		{[]string{"prune", "projects", "--older-than", "1d"}, "prune", "", "prune does not take a subcommand: projects"},
+	}
+
+	for _, tc := range tests {
+		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
+			_, err := parseArgs(tc.args)
+			var subErr *ErrUnknownSubcommand
+			require.True(t, errors.As(err, &subErr), err)
+			assert.Equal(t, tc.command, subErr.Command)
+			assert.Equal(t, tc.suggestion, subErr.Suggestion)
+			assert.EqualError(t, err, tc.message)
+		})
+	}
+}
+
+func TestRunCLI_UnknownSubcommandSuggestion(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := runCLI([]string{"clean", "project"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "Did you mean 'cccc clean projects'?")
+
+	stderr.Reset()
+	code = runCLI([]string{"cache", "wipe"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "Valid cache subcommands: clear")
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+	"github.com/mkoepf/claude-code-config-cleaner/internal/cleaner"
+)
+
+// jsonSchemaVersion is the version of the --json output contract.
+// Bump it whenever a field is removed or changes meaning.
+const jsonSchemaVersion = 1
+
+// now returns the current time; replaced in tests.
+var now = time.Now
+
+// jsonEnvelope wraps every --json payload so consumers can detect format changes.
+type jsonEnvelope struct {
+	Schema      int    `json:"schema"`
+	GeneratedAt string `json:"generated_at"`
+	Items       any    `json:"items"`
+	Summary     any    `json:"summary,omitempty"` // Aggregates of items, if the command has any
+}
+
+// writeJSON writes items wrapped in a schema-versioned envelope.
+func writeJSON(w io.Writer, items any) error {
+	return writeJSONWithSummary(w, items, nil)
+}
+
+// writeJSONWithSummary is like writeJSON but also includes summary in the
+// envelope.
+func writeJSONWithSummary(w io.Writer, items, summary any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jsonEnvelope{
+		Schema:      jsonSchemaVersion,
+		GeneratedAt: now().UTC().Format(time.RFC3339),
+		Items:       items,
+		Summary:     summary,
+	})
+}
+
+// streamProjects writes each project as a JSON object on its own line as
+// soon as it is scanned, without holding the full project list in memory.
+func streamProjects(ctx context.Context, args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
+	enc := json.NewEncoder(stdout)
+	err := scanProjectsFunc(ctx, args, paths, stderr, func(p claude.Project) error {
+		status := projectStatus(args, p)
+		if args.StaleOnly && !status.IsStale() {
+			return nil
+		}
+		return enc.Encode(newProjectJSON(p, status))
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, "Error scanning projects:", err)
+		return 1
+	}
+	return 0
+}
+
+// projectJSON is the --json representation of a project.
+type projectJSON struct {
+	EncodedName string   `json:"encoded_name"`
+	ActualPath  string   `json:"actual_path"`
+	SessionIDs  []string `json:"session_ids"`
+	FileCount   int      `json:"file_count"`
+	TotalSize   int64    `json:"total_size_bytes"`
+	LastUsed    string   `json:"last_used,omitempty"`
+	Status      string   `json:"status"`
+}
+
+func newProjectJSON(p claude.Project, status claude.ProjectStatus) projectJSON {
+	out := projectJSON{
+		EncodedName: p.EncodedName,
+		ActualPath:  p.ActualPath,
+		SessionIDs:  p.SessionIDs,
+		FileCount:   p.FileCount,
+		TotalSize:   p.TotalSize,
+		Status:      status.String(),
+	}
+	if out.SessionIDs == nil {
+		out.SessionIDs = []string{}
+	}
+	if !p.LastUsed.IsZero() {
+		out.LastUsed = p.LastUsed.UTC().Format(time.RFC3339)
+	}
+	return out
+}
+
+// orphanJSON is the --json representation of an orphan item.
+type orphanJSON struct {
+	Type   cleaner.OrphanType `json:"type"`
+	Path   string             `json:"path"`
+	Size   int64              `json:"size_bytes"`
+	Reason string             `json:"reason,omitempty"` // Only with --explain-orphan
+}
+
+// orphanSummaryJSON is the --json representation of the orphans of one type.
+type orphanSummaryJSON struct {
+	Count int   `json:"count"`
+	Size  int64 `json:"size_bytes"`
+}
+
+// newOrphanSummaryJSON returns the orphan summary keyed by orphan type.
+func newOrphanSummaryJSON(summary []cleaner.OrphanSummary) map[cleaner.OrphanType]orphanSummaryJSON {
+	out := make(map[cleaner.OrphanType]orphanSummaryJSON, len(summary))
+	for _, s := range summary {
+		out[s.Type] = orphanSummaryJSON{Count: s.Count, Size: s.Size}
+	}
+	return out
+}
+
+// lintJSON is the --json representation of a config lint finding.
+type lintJSON struct {
+	Path     string               `json:"path"`
+	Severity cleaner.LintSeverity `json:"severity"`
+	List     string               `json:"list"`
+	Entry    string               `json:"entry"`
+	Message  string               `json:"message"`
+}
+
+// dedupJSON is the --json representation of a config deduplication result.
+type dedupJSON struct {
+	LocalPath          string   `json:"local_path"`
+	DuplicateAllow     []string `json:"duplicate_allow"`
+	DuplicateDeny      []string `json:"duplicate_deny"`
+	DuplicateAsk       []string `json:"duplicate_ask"`
+	SelfDuplicateAllow []string `json:"self_duplicate_allow"`
+	SelfDuplicateDeny  []string `json:"self_duplicate_deny"`
+	SelfDuplicateAsk   []string `json:"self_duplicate_ask"`
+	SuggestDelete      bool     `json:"suggest_delete"`
+}
+
+func newDedupJSON(r cleaner.DedupResult) dedupJSON {
+	return dedupJSON{
+		LocalPath:          r.LocalPath,
+		DuplicateAllow:     nonNil(r.DuplicateAllow),
+		DuplicateDeny:      nonNil(r.DuplicateDeny),
+		DuplicateAsk:       nonNil(r.DuplicateAsk),
+		SelfDuplicateAllow: nonNil(r.SelfDuplicateAllow),
+		SelfDuplicateDeny:  nonNil(r.SelfDuplicateDeny),
+		SelfDuplicateAsk:   nonNil(r.SelfDuplicateAsk),
+		SuggestDelete:      r.SuggestDelete,
+	}
+}
+
+// dedupSummaryJSON is the --json summary of the config deduplication results.
+type dedupSummaryJSON struct {
+	Configs          int `json:"configs"`
+	DuplicateEntries int `json:"duplicate_entries"`
+	ConfigsToDelete  int `json:"configs_to_delete"`
+}
+
+func newDedupSummaryJSON(s cleaner.DedupSummary) dedupSummaryJSON {
+	return dedupSummaryJSON{
+		Configs:          s.Configs,
+		DuplicateEntries: s.Entries,
+		ConfigsToDelete:  s.Deleted,
+	}
+}
+
+// duplicateSessionJSON is the --json representation of a duplicate session.
+type duplicateSessionJSON struct {
+	SessionID string   `json:"session_id"`
+	Projects  []string `json:"projects"`
+}
+
+// corruptSessionJSON is the --json representation of a corrupt session file.
+type corruptSessionJSON struct {
+	Path    string `json:"path"`
+	Project string `json:"project"`
+	Line    int    `json:"line"`
+	Error   string `json:"error"`
+	Size    int64  `json:"size_bytes"`
+}
+
+// sessionFileJSON is the --json representation of a session file listed by
+// list sessions.
+type sessionFileJSON struct {
+	Path        string `json:"path"`
+	Project     string `json:"project"`
+	ProjectPath string `json:"project_path"`
+	Size        int64  `json:"size_bytes"`
+	Modified    string `json:"modified"`
+}
+
+// nonNil returns s, or an empty slice if s is nil, so JSON shows [] not null.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
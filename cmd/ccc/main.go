@@ -1,30 +1,131 @@
 package main
 
 import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
 	"github.com/mkoepf/claude-code-config-cleaner/internal/cleaner"
 	"github.com/mkoepf/claude-code-config-cleaner/internal/ui"
 )
 
-// Version is set at build time via ldflags.
-var Version = "dev"
+// Build information, set at build time via ldflags:
+//
+//	-X main.Version=... -X main.Commit=... -X main.Date=...
+var (
+	Version = "dev"
+	Commit  = "none"
+	Date    = "unknown"
+)
+
+// exitNoTTY is the exit code used when a confirmation is required but stdin
+// is not a terminal and --yes was not given.
+const exitNoTTY = 2
 
 // Args represents parsed command-line arguments.
 type Args struct {
-	Command    string // "clean", "list", ""
-	Subcommand string // "projects", "orphans", "config", ""
-	DryRun     bool
-	Yes        bool
-	StaleOnly  bool
-	Verbose    bool
-	Help       bool
-	Version    bool
+	Command     string // "clean", "list", "cache", "config", "prune", "report", "trash", "audit", "watch", ""
+	Subcommand  string // "projects", "orphans", "config", "duplicates", "corrupt", "sessions", ""
+	DryRun      bool
+	Yes         bool
+	StaleOnly   bool
+	Verbose     bool
+	Help        bool
+	Version     bool
+	Format      string // Output format for list projects: "" (default), "table" or "csv"
+	Recursive   bool   // Search project trees for nested local configs
+	Explain     bool   // Print why each project is or isn't stale (implies DryRun)
+	GroupBy     bool   // List duplicate config entries with the local configs containing them
+	GroupByDisk bool   // Add the reclaimable space per filesystem to the report
+
+	ExplainOrphan      bool           // Print why each item is considered orphaned (implies DryRun)
+	IncludeUnavailable bool           // Treat projects on unavailable filesystems as stale
+	KeepNoCWD          bool           // Keep projects without a cwd instead of treating them as stale
+	IncludeClaudeHome  bool           // Also clean projects whose cwd is inside the Claude home
+	AuditFormat        ui.AuditFormat // Audit log format: "text" (default) or "jsonl"
+	Since              time.Time      // Only show audit entries logged at or after this time
+	AuditAction        ui.Action      // Only show audit entries with this action ("" = all)
+	Interval           time.Duration  // Time between scans of watch (0 = 1h)
+	WatchClean         bool           // Clean what watch finds on each scan
+	AuditLog           string         // Audit log path ("" = ~/.claude/cccc-audit.log)
+	ClaudeHomes        []string       // Claude config directories to operate on, one after another ("" = ~/.claude)
+	Trash              bool           // Move cleaned projects and orphans to the trash instead of deleting them
+	KeepNewestSession  bool           // Archive the newest session of each cleaned stale project
+	Quiet              bool           // Suppress progress output, and all other output with --yes
+	JSON               bool           // Emit list results as schema-versioned JSON
+	JSONStream         bool           // Emit one JSON object per project as it is scanned
+	Project            string         // Restrict orphan cleanup to this project path
+	Only               string         // Only show listed projects matching this substring or glob
+	PathsOnly          bool           // Print only the paths of listed items, one per line
+	MachineTotals      bool           // Append a "# totals key=value ..." line to list projects
+	Diff               bool           // Show unified diffs of config changes
+	YesToModify        bool           // Skip confirmation unless something is deleted
+	YesFromEnv         bool           // Yes was not given but defaulted by CCC_ASSUME_YES=1
+	Timeout            time.Duration  // Abort filesystem scans after this long (0 = no limit)
+	Concurrency        int            // Project directories scanned in parallel (0 = number of CPUs)
+	MaxDelete          int            // Abort a cleanup of more items than this (0 = no limit)
+	FailFast           bool           // Stop a clean at the first item that cannot be removed
+	Force              bool           // Ignore --max-delete
+	Input              string         // Clean the projects listed in this file ("-" = stdin) instead of the stale ones
+	SkipUnknown        bool           // Skip unknown --input entries with a warning instead of refusing
+	ShowAuditLines     bool           // Print the audit entries a dry run would write
+	Select             bool           // Pick the stale projects to clean from a checkbox list
+	MeasureDisk        bool           // Print the free disk space before and after cleaning
+	IncludeGlobalLocal bool           // Also deduplicate ~/.claude/settings.local.json
+	NoCache            bool           // Rescan all projects instead of using the scan cache
+	TodoPattern        *regexp.Regexp // Additional recognized todo filename format
+	IncludeUnknown     bool           // Also clean todo files that match no known format
+	ExcludeEmpty       bool           // Skip zero-size orphans (--include-empty=false)
+	OrphanKind         string         // Restrict orphan commands to one kind: todos, file-history, sessions, env, logs, locks
+	LogsOlderThan      time.Duration  // Also treat log files older than this as orphans (0 = never)
+	IncludeLocks       bool           // Also treat lock and pid files of processes that are gone as orphans
+	AbsoluteTime       bool           // Show dates instead of relative times in list output
+	SummaryOnly        bool           // Show only counts and sizes in previews, not every path
+	SortPreview        bool           // List the largest changes first in previews
+	NoKept             bool           // Hide the kept items in previews
+	Top                int            // Number of session files listed by list sessions (0 = 20)
+	OlderThan          time.Duration  // Only act on items last used longer ago than this
+	NewerThan          time.Duration  // Only act on items last used more recently than this
+	AgeFrom            string         // Age source for --older-than/--newer-than: "timestamp" (default) or "mtime"
+	KeepLatest         int            // Always keep this many most recent sessions per project when pruning
+	Agent              string         // Restrict orphan todos to those written by this agent ID
+	Output             string         // Write previews and list output to this file
+	AssumeMissing      []string       // Project paths to treat as stale even if they exist
+	VerifyMarkers      []string       // Existing project paths without any of these files are treated as stale
+	ConfirmAssumed     bool           // Allow --yes together with --assume-missing
+	Normalize          bool           // Match config entries ignoring whitespace differences
+	IgnoreCase         bool           // Also ignore case when matching config entries
+	SavePlan           string         // Save the config dedup plan of a dry run to this file
+	ApplyPlan          string         // Apply the config dedup plan saved in this file instead of scanning
+
+	scanWarned   bool      // Unreadable project directories have been reported
+	scanTimedOut bool      // A scan was aborted by --timeout
+	trashBatch   string    // Trash batch directory of this run if --trash is set
+	terminal     io.Writer // Stdout without the --output file, for prompts
+}
+
+// orphanKinds maps the orphan kind arguments to the orphan types they select.
+var orphanKinds = map[string][]cleaner.OrphanType{
+	"todos":        {cleaner.OrphanTypeTodo, cleaner.OrphanTypeUnknownTodo, cleaner.OrphanTypeDuplicateTodo},
+	"file-history": {cleaner.OrphanTypeFileHistory},
+	"sessions":     {cleaner.OrphanTypeEmptySession},
+	"env":          {cleaner.OrphanTypeSessionEnv},
+	"logs":         {cleaner.OrphanTypeLog},
+	"locks":        {cleaner.OrphanTypeLock},
 }
 
 func main() {
@@ -37,11 +138,17 @@ func runCLI(osArgs []string, stdin io.Reader, stdout, stderr io.Writer) int {
 	args, err := parseArgs(osArgs)
 	if err != nil {
 		fmt.Fprintln(stderr, "Error:", err)
+		printSuggestion(err, stderr)
 		return 1
 	}
+	if args.YesFromEnv && ui.IsInteractive(stdin) {
+		// The variable cannot be undone by a flag, so a variable left set
+		// in a shell must not skip prompts silently.
+		fmt.Fprintf(stderr, "Note: %s=1 is set; confirmation prompts are skipped. Unset it to be asked again.\n", assumeYesEnv)
+	}
 
 	if args.Version {
-		fmt.Fprintf(stdout, "cccc version %s\n", Version)
+		fmt.Fprintln(stdout, versionString())
 		return 0
 	}
 
@@ -50,21 +157,221 @@ func runCLI(osArgs []string, stdin io.Reader, stdout, stderr io.Writer) int {
 		return 0
 	}
 
+	if args.Command == "" {
+		printHelp(stdout)
+		return 0
+	}
+
+	// Check the audit log location before anything is changed, so that
+	// changes are not made without their audit trail
+	if args.AuditLog != "" {
+		if err := checkWritableDir(filepath.Dir(args.AuditLog)); err != nil {
+			fmt.Fprintf(stderr, "Error: invalid --audit-log %q: %v\n", args.AuditLog, err)
+			return 1
+		}
+	}
+
 	// Discover Claude paths
-	paths, err := claude.DiscoverPaths("")
+	homes, err := discoverHomes(args)
 	if err != nil {
 		fmt.Fprintln(stderr, "Error discovering Claude paths:", err)
 		return 1
 	}
 
+	if unattended(args) {
+		stdout = io.Discard
+	}
+
+	if args.Output != "" {
+		out, err := openOutput(args.Output)
+		if err != nil {
+			fmt.Fprintln(stderr, "Error opening output file:", err)
+			return 1
+		}
+		defer func() {
+			if err := out.Close(); err != nil {
+				fmt.Fprintln(stderr, "Error writing output file:", err)
+			}
+		}()
+		args.terminal = stdout
+		stdout = outputWriter(args, stdout, out)
+	}
+
+	ctx := context.Background()
+	var code, failed int
+	for i, paths := range homes {
+		if len(homes) > 1 {
+			if i > 0 {
+				fmt.Fprintln(stdout)
+			}
+			fmt.Fprintf(stdout, "== Claude home %s ==\n", paths.Root)
+		}
+
+		if c := runCommand(ctx, args, paths, stdin, stdout, stderr); c != 0 {
+			code = c
+			failed++
+		}
+		if args.scanTimedOut {
+			break
+		}
+	}
+
+	if len(homes) > 1 {
+		fmt.Fprintf(stdout, "\nFinished %d Claude homes (%d failed)\n", len(homes), failed)
+	}
+
+	if code != 0 && args.scanTimedOut {
+		fmt.Fprintf(stderr, "Aborted: timed out after %s (--timeout)\n", args.Timeout)
+	}
+	return code
+}
+
+// discoverHomes returns the paths of each --claude-home, or of the default
+// ~/.claude if none is given. Explicitly given homes must exist, so that a
+// typo does not silently do nothing.
+func discoverHomes(args *Args) ([]*claude.Paths, error) {
+	if len(args.ClaudeHomes) == 0 {
+		paths, err := claude.DiscoverPaths("")
+		if err != nil {
+			return nil, err
+		}
+		return []*claude.Paths{paths}, nil
+	}
+
+	var homes []*claude.Paths
+	for _, home := range args.ClaudeHomes {
+		if _, err := os.Stat(home); err != nil {
+			return nil, err
+		}
+		paths, err := claude.DiscoverPaths(home)
+		if err != nil {
+			return nil, err
+		}
+		homes = append(homes, paths)
+	}
+	return homes, nil
+}
+
+// runCommand runs the command of args against one Claude home.
+func runCommand(ctx context.Context, args *Args, paths *claude.Paths, stdin io.Reader, stdout, stderr io.Writer) int {
+	// State of a previous home's run must not leak into this one
+	args.scanWarned = false
+	args.trashBatch = ""
+	if args.Trash {
+		args.trashBatch = cleaner.TrashBatchDir(cleaner.DefaultTrashRoot(paths.Root), now())
+	}
+
+	var freeBefore uint64
+	measure := args.MeasureDisk
+	if measure {
+		var err error
+		if freeBefore, err = cleaner.FreeSpace(paths.Root); err != nil {
+			fmt.Fprintln(stderr, "Warning: cannot measure free disk space:", err)
+			measure = false
+		}
+	}
+
+	var code int
 	switch args.Command {
 	case "clean":
-		return handleClean(args, paths, stdin, stdout, stderr)
+		code = handleClean(ctx, args, paths, stdin, stdout, stderr)
 	case "list":
-		return handleList(args, paths, stdout, stderr)
+		code = handleList(ctx, args, paths, stdout, stderr)
+	case "cache":
+		code = handleCache(args, paths, stdout, stderr)
+	case "prune":
+		code = handlePrune(ctx, args, paths, stdin, stdout, stderr)
+	case "report":
+		code = handleReport(ctx, args, paths, stdout, stderr)
+	case "config":
+		code = handleConfig(ctx, args, paths, stdin, stdout, stderr)
+	case "trash":
+		code = handleTrash(args, paths, stdin, stdout, stderr)
+	case "audit":
+		code = handleAudit(args, paths, stdout, stderr)
+	case "watch":
+		code = handleWatch(ctx, args, paths, stdin, stdout, stderr)
+	}
+
+	if args.trashBatch != "" {
+		if _, err := os.Stat(args.trashBatch); err == nil {
+			fmt.Fprintf(stdout, "Moved cleaned items to %s (use 'cccc trash empty' to delete them permanently)\n", args.trashBatch)
+		}
+	}
+	if measure {
+		printDiskFree(stdout, stderr, paths.Root, freeBefore)
+	}
+	return code
+}
+
+// printDiskFree prints how the free space of the disk the Claude home is on
+// changed since before, with --measure-disk. This is the space actually
+// reclaimed, which can differ from the size of the removed files due to
+// block sizes, sparse files and other programs writing to the disk.
+func printDiskFree(stdout, stderr io.Writer, root string, before uint64) {
+	after, err := cleaner.FreeSpace(root)
+	if err != nil {
+		fmt.Fprintln(stderr, "Warning: cannot measure free disk space:", err)
+		return
+	}
+
+	b, a := int64(before), int64(after) // #nosec G115 -- free space fits an int64
+	change := fmt.Sprintf("reclaimed %s", ui.FormatSize(a-b))
+	if a < b {
+		change = fmt.Sprintf("decreased by %s, other programs wrote to the disk", ui.FormatSize(b-a))
+	}
+	fmt.Fprintf(stdout, "Disk free: %s → %s (%s)\n", ui.FormatSize(b), ui.FormatSize(a), change)
+}
+
+// unattended reports whether a command that changes files runs with --quiet
+// and --yes, e.g. from cron. It needs no prompts and prints nothing on
+// success; errors and warnings (e.g. an audit log that cannot be written)
+// still go to stderr.
+func unattended(args *Args) bool {
+	if !args.Quiet || !args.Yes || args.DryRun {
+		return false
+	}
+	switch args.Command {
+	case "clean", "prune", "config", "cache":
+		return true
+	case "trash":
+		return args.Subcommand == "empty"
+	}
+	return false
+}
+
+// assumeYesEnv is the environment variable that, when set to "1", defaults
+// --yes to on, e.g. for CI jobs that cannot pass flags through. It is
+// dangerous: every cleanup then runs without confirmation.
+const assumeYesEnv = "CCC_ASSUME_YES"
+
+// printSuggestion prints a "did you mean" hint for unknown commands and flags.
+func printSuggestion(err error, w io.Writer) {
+	var cmdErr *ErrUnknownCommand
+	var subErr *ErrUnknownSubcommand
+	var flagErr *ErrUnknownFlag
+	switch {
+	case errors.As(err, &cmdErr) && cmdErr.Suggestion != "":
+		fmt.Fprintf(w, "Did you mean '%s'?\n", cmdErr.Suggestion)
+	case errors.As(err, &subErr) && subErr.Suggestion != "":
+		fmt.Fprintf(w, "Did you mean 'cccc %s %s'?\n", subErr.Command, subErr.Suggestion)
+	case errors.As(err, &subErr) && len(knownSubcommands[subErr.Command]) > 0:
+		fmt.Fprintf(w, "Valid %s subcommands: %s\n", subErr.Command, strings.Join(knownSubcommands[subErr.Command], ", "))
+	case errors.As(err, &flagErr) && flagErr.Suggestion != "":
+		fmt.Fprintf(w, "Did you mean '%s'?\n", flagErr.Suggestion)
 	default:
-		printHelp(stdout)
-		return 0
+		return
+	}
+	fmt.Fprintln(w, "Run 'cccc --help' for usage.")
+}
+
+// unknownSubcommand returns the error for a subcommand the command does not
+// accept, with the closest valid subcommand as suggestion.
+func unknownSubcommand(command, subcommand string) error {
+	return &ErrUnknownSubcommand{
+		Command:    command,
+		Subcommand: subcommand,
+		Suggestion: suggest(subcommand, knownSubcommands[command]),
 	}
 }
 
@@ -81,41 +388,615 @@ func parseArgs(osArgs []string) (*Args, error) {
 	for i < len(osArgs) {
 		arg := osArgs[i]
 
+		// Support --flag=value in addition to --flag value. An inline value
+		// must be used by the flag; boolean flags do not take one, so that
+		// e.g. --yes=false is refused instead of confirming everything.
+		inline, hasInline, usedInline := "", false, false
+		if strings.HasPrefix(arg, "--") {
+			if idx := strings.Index(arg, "="); idx != -1 {
+				arg, inline, hasInline = arg[:idx], arg[idx+1:], true
+			}
+		}
+		value := func() (string, error) {
+			if hasInline {
+				usedInline = true
+				return inline, nil
+			}
+			if i+1 >= len(osArgs) {
+				return "", fmt.Errorf("flag %s requires a value", arg)
+			}
+			i++
+			return osArgs[i], nil
+		}
+
 		switch arg {
 		case "-h", "--help", "help":
 			args.Help = true
 			return args, nil
-		case "--version":
+		case "-V", "--version":
 			args.Version = true
 			return args, nil
 		case "--dry-run":
 			args.DryRun = true
 		case "-y", "--yes":
 			args.Yes = true
+		case "--yes-to-modify":
+			args.YesToModify = true
+		case "--timeout":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid timeout %q (expected a positive duration like 30s or 2m)", v)
+			}
+			args.Timeout = d
+		case "--concurrency":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid --concurrency %q (expected a number >= 1)", v)
+			}
+			args.Concurrency = n
+		case "--max-delete":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid --max-delete %q (expected a number >= 1)", v)
+			}
+			args.MaxDelete = n
+		case "--force":
+			args.Force = true
+		case "--skip-unknown":
+			args.SkipUnknown = true
+		case "--fail-fast":
+			args.FailFast = true
+		case "--older-than":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			d, err := parseAge(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid age %q (expected a positive duration like 90d or 48h)", v)
+			}
+			args.OlderThan = d
+		case "--logs-older-than":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			d, err := parseAge(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid age %q (expected a positive duration like 90d or 48h)", v)
+			}
+			args.LogsOlderThan = d
+		case "--newer-than":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			d, err := parseAge(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid age %q (expected a positive duration like 1d or 6h)", v)
+			}
+			args.NewerThan = d
+		case "--age-from":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			switch v {
+			case "timestamp":
+				args.AgeFrom = ""
+			case "mtime":
+				args.AgeFrom = v
+			default:
+				return nil, fmt.Errorf("unknown age source: %s (expected timestamp or mtime)", v)
+			}
+		case "--keep-latest":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid --keep-latest %q (expected a non-negative number)", v)
+			}
+			args.KeepLatest = n
 		case "--stale-only":
 			args.StaleOnly = true
+		case "--explain":
+			args.Explain = true
+			args.DryRun = true
+		case "--explain-orphan":
+			args.ExplainOrphan = true
+			args.DryRun = true
+		case "--recursive":
+			args.Recursive = true
+		case "--include-unavailable":
+			args.IncludeUnavailable = true
+		case "--include-locks":
+			args.IncludeLocks = true
+		case "--keep-no-cwd":
+			args.KeepNoCWD = true
+		case "--include-claude-home":
+			args.IncludeClaudeHome = true
+		case "--include-global-local":
+			args.IncludeGlobalLocal = true
+		case "--no-cache":
+			args.NoCache = true
+		case "--absolute-time":
+			args.AbsoluteTime = true
+		case "--summary-only":
+			args.SummaryOnly = true
+		case "--sort-preview":
+			args.SortPreview = true
+		case "--no-kept":
+			args.NoKept = true
+		case "--include-unknown":
+			args.IncludeUnknown = true
+		case "--include-empty":
+			include := true
+			if hasInline {
+				usedInline = true
+				b, err := strconv.ParseBool(inline)
+				if err != nil {
+					return nil, fmt.Errorf("invalid --include-empty %q (expected true or false)", inline)
+				}
+				include = b
+			}
+			args.ExcludeEmpty = !include
+		case "--todo-pattern":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			re, err := cleaner.ParseTodoPattern(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid todo pattern: %w", err)
+			}
+			args.TodoPattern = re
+		case "--audit-format":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			switch ui.AuditFormat(v) {
+			case ui.AuditFormatText, ui.AuditFormatJSONL:
+				args.AuditFormat = ui.AuditFormat(v)
+			default:
+				return nil, fmt.Errorf("unknown audit format: %s", v)
+			}
+		case "--audit-log":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			abs, err := filepath.Abs(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %w", v, err)
+			}
+			args.AuditLog = abs
+		case "--interval":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid interval %q (expected a positive duration like 30m or 1h)", v)
+			}
+			args.Interval = d
+		case "--clean":
+			args.WatchClean = true
+		case "--since":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			t, err := parseSince(v)
+			if err != nil {
+				return nil, err
+			}
+			args.Since = t
+		case "--action":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			switch v {
+			case "delete", "modify":
+				args.AuditAction = ui.Action(strings.ToUpper(v))
+			default:
+				return nil, fmt.Errorf("unknown action: %s (expected delete or modify)", v)
+			}
 		case "-v", "--verbose":
 			args.Verbose = true
-		case "clean", "list":
+		case "-q", "--quiet":
+			args.Quiet = true
+		case "--json":
+			args.JSON = true
+		case "--json-stream":
+			args.JSONStream = true
+		case "--trash":
+			args.Trash = true
+		case "--keep-newest-session":
+			args.KeepNewestSession = true
+		case "--group-by-entry":
+			args.GroupBy = true
+		case "--group-by-disk":
+			args.GroupByDisk = true
+		case "--diff":
+			args.Diff = true
+		case "--normalize":
+			args.Normalize = true
+		case "--ignore-case":
+			args.IgnoreCase = true
+		case "--output", "-o":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			args.Output = v
+		case "--save-plan":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			args.SavePlan = v
+		case "--apply-plan":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			args.ApplyPlan = v
+		case "--show-audit-lines":
+			args.ShowAuditLines = true
+		case "--select":
+			args.Select = true
+		case "--measure-disk":
+			args.MeasureDisk = true
+		case "--input":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			args.Input = v
+		case "--assume-missing":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			abs, err := filepath.Abs(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %w", v, err)
+			}
+			args.AssumeMissing = append(args.AssumeMissing, abs)
+		case "--claude-home":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			for _, home := range strings.Split(v, ",") {
+				if home = strings.TrimSpace(home); home != "" {
+					args.ClaudeHomes = append(args.ClaudeHomes, home)
+				}
+			}
+		case "--verify-marker":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			args.VerifyMarkers = append(args.VerifyMarkers, v)
+		case "--confirm-assume-missing":
+			args.ConfirmAssumed = true
+		case "--agent":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			args.Agent = v
+		case "--paths-only":
+			args.PathsOnly = true
+		case "--machine-totals":
+			args.MachineTotals = true
+		case "--top":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid --top %q (expected a positive number)", v)
+			}
+			args.Top = n
+		case "--only":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := filepath.Match(v, ""); err != nil {
+				return nil, fmt.Errorf("invalid --only pattern %q: %w", v, err)
+			}
+			args.Only = v
+		case "--project":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			args.Project = v
+		case "--format":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			switch v {
+			case "default":
+				args.Format = ""
+			case "table", "csv":
+				args.Format = v
+			default:
+				return nil, fmt.Errorf("unknown format: %s", v)
+			}
+		case "clean", "list", "cache", "prune", "report", "trash", "audit", "watch":
+			if args.Command == "" {
+				args.Command = arg
+			} else {
+				args.Subcommand = arg
+			}
+		case "config":
 			if args.Command == "" {
 				args.Command = arg
 			} else {
 				args.Subcommand = arg
 			}
-		case "projects", "orphans", "config":
+		case "projects", "orphans", "duplicates", "corrupt", "clear", "consolidate", "lint", "empty":
 			args.Subcommand = arg
+		case "todos", "file-history", "sessions", "env", "logs", "locks":
+			if arg == "sessions" && args.Command == "list" && args.Subcommand == "" {
+				args.Subcommand = arg
+				break
+			}
+			if args.Subcommand != "orphans" {
+				return nil, fmt.Errorf("%s is only valid after orphans", arg)
+			}
+			args.OrphanKind = arg
 		default:
 			if strings.HasPrefix(arg, "-") {
-				return nil, fmt.Errorf("unknown flag: %s", arg)
+				return nil, &ErrUnknownFlag{Flag: arg, Suggestion: suggest(arg, knownFlags)}
 			}
-			return nil, fmt.Errorf("unknown command: %s", arg)
+			if args.Command != "" && args.Subcommand == "" {
+				return nil, unknownSubcommand(args.Command, arg)
+			}
+			return nil, &ErrUnknownCommand{Command: arg, Suggestion: suggest(arg, knownCommands)}
+		}
+		if hasInline && !usedInline {
+			return nil, fmt.Errorf("flag %s does not take a value", arg)
 		}
 		i++
 	}
 
+	// CCC_ASSUME_YES=1 acts as --yes, so it is applied before validation
+	// and every check that depends on --yes sees it. Dry runs need no
+	// confirmation and --select is interactive by design.
+	if os.Getenv(assumeYesEnv) == "1" && !args.Yes && !args.DryRun && !args.Select {
+		args.Yes, args.YesFromEnv = true, true
+	}
+
+	if args.Command != "" && args.Subcommand != "" && !slices.Contains(knownSubcommands[args.Command], args.Subcommand) {
+		return nil, unknownSubcommand(args.Command, args.Subcommand)
+	}
+
+	if args.OrphanKind == "logs" && args.LogsOlderThan == 0 {
+		return nil, errors.New("orphans logs requires --logs-older-than")
+	}
+
+	if args.OrphanKind == "locks" && !args.IncludeLocks {
+		return nil, errors.New("orphans locks requires --include-locks")
+	}
+
+	if args.Agent != "" && args.OrphanKind != "todos" {
+		return nil, errors.New("--agent is only supported by orphans todos")
+	}
+
+	if len(args.AssumeMissing) > 0 && args.Yes && !args.ConfirmAssumed {
+		return nil, errors.New("--assume-missing deletes data of existing paths; combine it with --yes (or " + assumeYesEnv + "=1) only together with --confirm-assume-missing")
+	}
+
+	if args.Explain && (args.JSON || args.JSONStream || args.Format != "") {
+		return nil, errors.New("--explain is only supported by the default output format")
+	}
+
+	if args.ExplainOrphan && ((args.Command != "list" && args.Command != "clean") ||
+		(args.Subcommand != "orphans" && (args.Command != "clean" || args.Subcommand != ""))) {
+		return nil, errors.New("--explain-orphan is only supported by list orphans, clean orphans and clean")
+	}
+
+	if args.GroupBy && (args.Command != "list" || args.Subcommand != "config" || args.JSON) {
+		return nil, errors.New("--group-by-entry is only supported by list config without --json")
+	}
+
+	if args.GroupByDisk && args.Command != "report" {
+		return nil, errors.New("--group-by-disk is only supported by report")
+	}
+
+	if args.Only != "" && (args.Command != "list" || (args.Subcommand != "projects" && args.Subcommand != "")) {
+		return nil, errors.New("--only is only supported by list projects")
+	}
+
+	if args.PathsOnly && (args.Command != "list" || (args.Subcommand != "projects" && args.Subcommand != "orphans" && args.Subcommand != "")) {
+		return nil, errors.New("--paths-only is only supported by list projects and list orphans")
+	}
+
+	if args.PathsOnly && (args.JSON || args.JSONStream || args.Format != "" || args.Explain || args.ExplainOrphan) {
+		return nil, errors.New("--paths-only cannot be combined with --json, --json-stream, --format, --explain or --explain-orphan")
+	}
+
+	if args.MachineTotals && (args.Command != "list" || (args.Subcommand != "projects" && args.Subcommand != "")) {
+		return nil, errors.New("--machine-totals is only supported by list projects")
+	}
+
+	if args.MachineTotals && (args.JSON || args.JSONStream || args.Format == "csv" || args.PathsOnly) {
+		return nil, errors.New("--machine-totals cannot be combined with --json, --json-stream, --format csv or --paths-only")
+	}
+
+	if args.Top > 0 && (args.Command != "list" || args.Subcommand != "sessions") {
+		return nil, errors.New("--top is only supported by list sessions")
+	}
+
+	if (args.Interval > 0 || args.WatchClean) && args.Command != "watch" {
+		return nil, errors.New("--interval and --clean are only supported by watch")
+	}
+
+	if args.WatchClean && !args.Yes {
+		return nil, errors.New("watch --clean requires --yes, as nobody is there to confirm each cleanup")
+	}
+
+	if (!args.Since.IsZero() || args.AuditAction != "") && args.Command != "audit" {
+		return nil, errors.New("--since and --action are only supported by audit")
+	}
+
+	if (args.MaxDelete > 0 || args.Force) && args.Command != "clean" {
+		return nil, errors.New("--max-delete and --force are only supported by clean")
+	}
+
+	if args.FailFast && args.Command != "clean" && args.Command != "watch" {
+		return nil, errors.New("--fail-fast is only supported by clean and watch --clean")
+	}
+
+	if args.Trash && args.Command != "clean" {
+		return nil, errors.New("--trash is only supported by clean")
+	}
+
+	if args.KeepNewestSession && (args.Command != "clean" || (args.Subcommand != "" && args.Subcommand != "projects")) {
+		return nil, errors.New("--keep-newest-session is only supported by clean projects")
+	}
+
+	if args.ShowAuditLines && !args.DryRun {
+		return nil, errors.New("--show-audit-lines requires --dry-run")
+	}
+
+	if args.ShowAuditLines && !slices.Contains([]string{"clean", "prune", "config", "trash"}, args.Command) {
+		return nil, errors.New("--show-audit-lines is only supported by clean, prune, config and trash empty")
+	}
+
+	if args.Input != "" && (args.Command != "clean" || args.Subcommand != "projects") {
+		return nil, errors.New("--input is only supported by clean projects")
+	}
+
+	if args.MeasureDisk && !slices.Contains([]string{"clean", "prune", "trash"}, args.Command) {
+		return nil, errors.New("--measure-disk is only supported by clean, prune and trash empty")
+	}
+
+	if args.MeasureDisk && args.DryRun {
+		return nil, errors.New("--measure-disk cannot be combined with --dry-run, which frees no space")
+	}
+
+	if args.Select && (args.Command != "clean" || args.Subcommand != "projects") {
+		return nil, errors.New("--select is only supported by clean projects")
+	}
+
+	if args.Select && (args.Yes || args.Input != "") {
+		return nil, errors.New("--select cannot be combined with --yes or --input")
+	}
+
+	if args.SkipUnknown && args.Input == "" {
+		return nil, errors.New("--skip-unknown requires --input")
+	}
+
+	if args.Input != "" && args.Explain {
+		return nil, errors.New("--input and --explain cannot be combined")
+	}
+
+	if args.Input == "-" && !args.Yes && !args.DryRun {
+		return nil, errors.New("--input - reads the projects from stdin, so it requires --yes or --dry-run")
+	}
+
+	if (args.SavePlan != "" || args.ApplyPlan != "") && (args.Command != "clean" || args.Subcommand != "config") {
+		return nil, errors.New("--save-plan and --apply-plan are only supported by clean config")
+	}
+
+	if args.SavePlan != "" && !args.DryRun {
+		return nil, errors.New("--save-plan requires --dry-run")
+	}
+
+	if args.SavePlan != "" && args.ApplyPlan != "" {
+		return nil, errors.New("--save-plan and --apply-plan cannot be combined")
+	}
+
+	if len(args.ClaudeHomes) > 1 && (args.JSON || args.JSONStream || args.Format == "csv" || args.PathsOnly || args.SavePlan != "") {
+		return nil, errors.New("several --claude-home directories cannot be combined with --json, --json-stream, --format csv, --paths-only or --save-plan")
+	}
+
+	if len(args.ClaudeHomes) > 1 && args.Command == "watch" {
+		return nil, errors.New("watch supports only one --claude-home")
+	}
+
+	if len(args.ClaudeHomes) > 1 && args.Input == "-" {
+		return nil, errors.New("--input - supports only one --claude-home")
+	}
+
+	if args.IgnoreCase && !args.Normalize {
+		return nil, errors.New("--ignore-case requires --normalize")
+	}
+
+	if args.NewerThan > 0 && args.OlderThan > 0 && args.NewerThan <= args.OlderThan {
+		return nil, errors.New("--newer-than must be longer than --older-than, otherwise nothing can match")
+	}
+
+	if args.Command == "prune" && args.OlderThan == 0 && args.NewerThan == 0 {
+		return nil, errors.New("prune requires --older-than or --newer-than")
+	}
+
 	return args, nil
 }
 
+// parseSince parses a --since value, either a date (midnight local time) or
+// an RFC 3339 timestamp.
+func parseSince(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (expected a date like 2025-01-01)", s)
+}
+
+// logCutoff returns the modification time before which log files are
+// orphans, or the zero time if --logs-older-than is not set.
+func logCutoff(args *Args) time.Time {
+	if args.LogsOlderThan == 0 {
+		return time.Time{}
+	}
+	return now().Add(-args.LogsOlderThan)
+}
+
+// parseAge parses a duration that may also be given in days, e.g. "90d".
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// versionString returns the version line including commit and build date.
+func versionString() string {
+	return fmt.Sprintf("cccc version %s (commit %s, built %s)", Version, Commit, Date)
+}
+
 // printHelp prints the usage information.
 func printHelp(w io.Writer) {
 	fmt.Fprintf(w, "cccc version %s\n", Version)
@@ -126,386 +1007,2354 @@ func printHelp(w io.Writer) {
 	fmt.Fprintln(w, "  cccc clean                          Clean all (default: projects + orphans + config)")
 	fmt.Fprintln(w, "  cccc clean projects [--dry-run]     Remove stale project session data")
 	fmt.Fprintln(w, "  cccc clean orphans [--dry-run]      Remove orphaned data")
+	fmt.Fprintln(w, "  cccc clean orphans KIND             Remove one kind only: todos, file-history, sessions, env, logs, locks")
 	fmt.Fprintln(w, "  cccc clean config [--dry-run]       Deduplicate local configs against global settings")
+	fmt.Fprintln(w, "  cccc clean config --dry-run --save-plan FILE  Save the reviewed dedup plan to FILE")
+	fmt.Fprintln(w, "  cccc clean config --apply-plan FILE Apply a saved dedup plan without re-scanning")
 	fmt.Fprintln(w, "  cccc list                           List projects (default)")
 	fmt.Fprintln(w, "  cccc list projects [--stale-only]   List all projects with their status")
 	fmt.Fprintln(w, "  cccc list orphans                   List orphaned data without removing")
 	fmt.Fprintln(w, "  cccc list config [--verbose]        List duplicate config entries without removing")
+	fmt.Fprintln(w, "  cccc list duplicates                List session IDs shared by multiple projects")
+	fmt.Fprintln(w, "  cccc list corrupt                   List session files that fail to parse")
+	fmt.Fprintln(w, "  cccc list sessions [--top 20]       List the largest session files across all projects")
+	fmt.Fprintln(w, "  cccc cache clear                    Remove the project scan cache")
+	fmt.Fprintln(w, "  cccc trash list                     List the batches moved to the trash with --trash")
+	fmt.Fprintln(w, "  cccc trash empty [--older-than AGE] Permanently delete trashed batches (older than AGE)")
+	fmt.Fprintln(w, "  cccc config consolidate [--dry-run] Move entries shared by all local configs into global settings")
+	fmt.Fprintln(w, "  cccc config lint [--json]           Flag duplicate, contradictory and malformed permission entries")
+	fmt.Fprintln(w, "  cccc watch [--interval 1h] [--clean --yes]  Report new stale projects and orphans periodically")
+	fmt.Fprintln(w, "  cccc audit [--since DATE] [--action delete|modify]  Show past cleanups from the audit log")
+	fmt.Fprintln(w, "  cccc report [--output plan.md]      Write the cleanup plan as markdown without changing anything")
+	fmt.Fprintln(w, "  cccc prune --older-than AGE         Remove session files that started before AGE (e.g. 90d)")
 	fmt.Fprintln(w, "")
 	fmt.Fprintln(w, "Flags:")
 	fmt.Fprintln(w, "  --dry-run      Show what would be cleaned without making changes")
 	fmt.Fprintln(w, "  --yes, -y      Skip confirmation prompts")
+	fmt.Fprintln(w, "  --yes-to-modify")
+	fmt.Fprintln(w, "                 Skip confirmation for modifications, but prompt before deletions")
 	fmt.Fprintln(w, "  --verbose, -v  Show detailed output (e.g., list duplicate entries)")
+	fmt.Fprintln(w, "  --quiet, -q    Suppress progress output during cleanup; with --yes print nothing but errors")
+	fmt.Fprintln(w, "                 and warnings (on stderr), e.g. for cron. The audit log is still written")
 	fmt.Fprintln(w, "  --stale-only   Show only stale projects (with list projects)")
+	fmt.Fprintln(w, "  --format FMT   Output format for list projects: default, table, csv")
+	fmt.Fprintln(w, "  --absolute-time")
+	fmt.Fprintln(w, "                 Show last-used dates instead of relative times (with list projects)")
+	fmt.Fprintln(w, "  --summary-only Show only counts and total size per action instead of every path")
+	fmt.Fprintln(w, "                 (previews list the first 1000 changes and summarize the rest unless --verbose is given)")
+	fmt.Fprintln(w, "  --sort-preview List the largest items first in previews instead of in discovery order")
+	fmt.Fprintln(w, "  --no-kept      Hide the projects that are kept from previews, only list what is removed")
+	fmt.Fprintln(w, "  --output, -o FILE")
+	fmt.Fprintln(w, "                 Write previews and list output to FILE (also shown on screen when cleaning;")
+	fmt.Fprintln(w, "                 confirmation prompts are shown on screen only)")
+	fmt.Fprintln(w, "  --json         Output list results as JSON ({schema, generated_at, items})")
+	fmt.Fprintln(w, "  --json-stream  Output one JSON object per line while scanning (with list projects)")
+	fmt.Fprintln(w, "  --agent ID     Only include todos written by this agent (with orphans todos)")
+	fmt.Fprintln(w, "  --only PATTERN Only list projects whose path contains PATTERN or matches it as a glob (with list projects)")
+	fmt.Fprintln(w, "  --project PATH Only clean orphans of this project (clean orphans)")
+	fmt.Fprintln(w, "  --diff         Show a unified diff of each config change (with config)")
+	fmt.Fprintln(w, "  --normalize    Match config entries ignoring whitespace differences (with config)")
+	fmt.Fprintln(w, "  --ignore-case  Also ignore case when matching config entries (with --normalize)")
+	fmt.Fprintln(w, "  --save-plan FILE")
+	fmt.Fprintln(w, "                 Save the dedup plan of a dry run to FILE (with clean config --dry-run)")
+	fmt.Fprintln(w, "  --apply-plan FILE")
+	fmt.Fprintln(w, "                 Apply the plan saved in FILE, skipping configs that changed since (with clean config)")
+	fmt.Fprintln(w, "  --recursive    Also find nested .claude/settings.local.json files (with config)")
+	fmt.Fprintln(w, "  --include-global-local")
+	fmt.Fprintln(w, "                 Also deduplicate ~/.claude/settings.local.json (with config)")
+	fmt.Fprintln(w, "  --assume-missing PATH")
+	fmt.Fprintln(w, "                 Treat the project at PATH as stale even if it exists (repeatable, with clean projects)")
+	fmt.Fprintln(w, "  --verify-marker NAME")
+	fmt.Fprintln(w, "                 Treat existing project paths without NAME (e.g. .git) as stale (repeatable)")
+	fmt.Fprintln(w, "  --confirm-assume-missing")
+	fmt.Fprintln(w, "                 Allow --yes together with --assume-missing")
+	fmt.Fprintln(w, "  --trash        Move cleaned projects and orphans to ~/.claude/cccc-trash instead of deleting them (with clean)")
+	fmt.Fprintln(w, "  --keep-newest-session")
+	fmt.Fprintln(w, "                 Move the newest session of each stale project to ~/.claude/cccc-archive (with clean projects)")
+	fmt.Fprintln(w, "  --group-by-entry")
+	fmt.Fprintln(w, "                 List each duplicate config entry with the local configs containing it (with list config)")
+	fmt.Fprintln(w, "  --group-by-disk")
+	fmt.Fprintln(w, "                 Add the space freed per drive (mount point) to the report (with report)")
+	fmt.Fprintln(w, "  --explain      Print why each project is or isn't stale (implies --dry-run)")
+	fmt.Fprintln(w, "  --explain-orphan")
+	fmt.Fprintln(w, "                 Print why each item is considered orphaned (implies --dry-run)")
+	fmt.Fprintln(w, "  --include-unavailable")
+	fmt.Fprintln(w, "                 Also clean projects on unmounted drives (with clean projects)")
+	fmt.Fprintln(w, "  --keep-no-cwd  Keep projects whose sessions record no cwd instead of cleaning them as stale;")
+	fmt.Fprintln(w, "                 they are listed as UNKNOWN")
+	fmt.Fprintln(w, "  --todo-pattern RE")
+	fmt.Fprintln(w, "                 Also recognize todo files matching RE; its (?P<session>...) or first group is the session ID")
+	fmt.Fprintln(w, "                 and an optional (?P<agent>...) group the agent ID, to detect duplicate todo files")
+	fmt.Fprintln(w, "  --logs-older-than AGE")
+	fmt.Fprintln(w, "                 Also treat files in ~/.claude/logs last modified more than AGE ago as orphans")
+	fmt.Fprintln(w, "  --include-locks")
+	fmt.Fprintln(w, "                 Also treat *.lock and *.pid files of processes that are no longer running as orphans")
+	fmt.Fprintln(w, "  --include-empty=false")
+	fmt.Fprintln(w, "                 Skip empty session files and session-env directories, which free no space")
+	fmt.Fprintln(w, "  --include-unknown")
+	fmt.Fprintln(w, "                 Also clean todo files that match no known format (with clean orphans)")
+	fmt.Fprintln(w, "  --older-than AGE")
+	fmt.Fprintln(w, "                 Only act on sessions and projects last used more than AGE ago, e.g. 90d or 48h")
+	fmt.Fprintln(w, "  --newer-than AGE")
+	fmt.Fprintln(w, "                 Only act on sessions and projects last used less than AGE ago; with --older-than forms a window")
+	fmt.Fprintln(w, "  --age-from SRC")
+	fmt.Fprintln(w, "                 Base --older-than/--newer-than on the session timestamp (default) or file mtime")
+	fmt.Fprintln(w, "  --keep-latest N")
+	fmt.Fprintln(w, "                 Always keep the N most recent sessions of each project (with prune)")
+	fmt.Fprintln(w, "  --no-cache     Rescan all projects instead of reusing cached results")
+	fmt.Fprintln(w, "  --timeout DUR  Abort if a scan takes longer than DUR (e.g. 30s, 2m); time spent at prompts")
+	fmt.Fprintln(w, "                 and removing files does not count")
+	fmt.Fprintln(w, "  --concurrency N")
+	fmt.Fprintln(w, "                 Scan up to N project directories in parallel (default: number of CPUs, 1 = sequential);")
+	fmt.Fprintln(w, "                 --timeout covers the whole scan, so lower N may need a longer --timeout")
+	fmt.Fprintln(w, "  --paths-only   Print only the paths, one per line (with list projects, list orphans)")
+	fmt.Fprintln(w, "  --top N        Number of session files listed by list sessions (default: 20)")
+	fmt.Fprintln(w, "  --machine-totals")
+	fmt.Fprintln(w, "                 End list projects with \"# totals projects=N stale=N unavailable=N bytes=N\" for scripts")
+	fmt.Fprintln(w, "  --include-claude-home")
+	fmt.Fprintln(w, "                 Also clean projects whose cwd is inside the Claude home (skipped by default)")
+	fmt.Fprintln(w, "  --max-delete N Abort a clean before deleting anything if it would remove more than N items")
+	fmt.Fprintln(w, "  --force        Ignore --max-delete")
+	fmt.Fprintln(w, "  --select       Pick the stale projects to clean from a checkbox list (with clean projects)")
+	fmt.Fprintln(w, "  --measure-disk Print the disk's free space before and after cleaning (Linux, macOS, FreeBSD)")
+	fmt.Fprintln(w, "  --show-audit-lines")
+	fmt.Fprintln(w, "                 With --dry-run, also print the audit log entries a real run would write")
+	fmt.Fprintln(w, "  --input FILE   Clean the projects listed in FILE (- for stdin), one path or encoded name per line,")
+	fmt.Fprintln(w, "                 whether or not they are stale (with clean projects)")
+	fmt.Fprintln(w, "  --skip-unknown Skip --input entries that are not known projects instead of refusing")
+	fmt.Fprintln(w, "  --fail-fast    Stop a clean at the first item that cannot be removed (default: continue and report")
+	fmt.Fprintln(w, "                 all failures at the end); either way the exit code is 1 if anything failed")
+	fmt.Fprintln(w, "  --claude-home DIRS")
+	fmt.Fprintln(w, "                 Operate on these Claude config dirs instead of ~/.claude, one after another")
+	fmt.Fprintln(w, "                 (comma-separated or repeatable); each home keeps its own audit log")
+	fmt.Fprintln(w, "  --audit-log PATH")
+	fmt.Fprintln(w, "                 Write the audit log to PATH instead of ~/.claude/cccc-audit.log")
+	fmt.Fprintln(w, "  --audit-format FMT")
+	fmt.Fprintln(w, "                 Audit log format: text (default), jsonl")
+	fmt.Fprintln(w, "  --interval DUR Time between scans of watch (default: 1h)")
+	fmt.Fprintln(w, "  --clean        Clean what watch finds on each scan (requires --yes); a failed cleanup is")
+	fmt.Fprintln(w, "                 reported and watching goes on, unless --fail-fast is given")
+	fmt.Fprintln(w, "  --since DATE   Only show audit entries logged on or after DATE (with audit)")
+	fmt.Fprintln(w, "  --action ACT   Only show audit entries of ACT: delete, modify (with audit)")
 	fmt.Fprintln(w, "  --help, -h     Show this help message")
-	fmt.Fprintln(w, "  --version      Show version information")
+	fmt.Fprintln(w, "  --version, -V  Show version information")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Environment:")
+	fmt.Fprintln(w, "  CCC_ASSUME_YES=1")
+	fmt.Fprintln(w, "                 Act as if --yes was given (except with --dry-run and --select). Dangerous:")
+	fmt.Fprintln(w, "                 everything is deleted without confirmation; meant for CI only")
+}
+
+// openOutput creates (or truncates) the --output file.
+func openOutput(path string) (*os.File, error) {
+	return os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644) // #nosec G304 -- path is given by the user
+}
+
+// outputWriter returns the writer for the output of a command run with
+// --output. List commands and dry runs write only to the file. Commands that
+// change files also keep writing to the terminal, so the preview stays
+// visible next to the confirmation prompt.
+func outputWriter(args *Args, stdout io.Writer, out io.Writer) io.Writer {
+	changesFiles := (args.Command == "clean" || args.Command == "prune") && !args.DryRun
+	if changesFiles {
+		return io.MultiWriter(stdout, out)
+	}
+	return out
+}
+
+// promptOut returns the writer for confirmation prompts and the --select
+// list: stdout, but without the --output file, which is a record of the
+// output and not of the dialog (nor of the list's terminal escape codes).
+func promptOut(args *Args, stdout io.Writer) io.Writer {
+	if args.terminal != nil {
+		return args.terminal
+	}
+	return stdout
 }
 
 // handleClean handles the "clean" command and subcommands.
-func handleClean(args *Args, paths *claude.Paths, stdin io.Reader, stdout, stderr io.Writer) int {
+func handleClean(ctx context.Context, args *Args, paths *claude.Paths, stdin io.Reader, stdout, stderr io.Writer) int {
+	if args.Project != "" && args.Subcommand != "orphans" {
+		fmt.Fprintln(stderr, "--project is only supported by clean orphans")
+		return 1
+	}
+
 	switch args.Subcommand {
 	case "projects":
-		return cleanProjects(args, paths, stdin, stdout, stderr)
+		return cleanProjects(ctx, args, paths, stdin, stdout, stderr, nil)
 	case "orphans":
-		return cleanOrphans(args, paths, stdin, stdout, stderr)
+		return cleanOrphans(ctx, args, paths, stdin, stdout, stderr, nil)
 	case "config":
-		return cleanConfig(args, paths, stdin, stdout, stderr)
+		return cleanConfig(ctx, args, paths, stdin, stdout, stderr, nil)
 	case "":
-		// Clean all
-		code := cleanProjects(args, paths, stdin, stdout, stderr)
-		if code != 0 {
-			return code
+		// Clean all. A phase that fails does not keep the others from
+		// running, unless --fail-fast is set or there is no TTY to confirm.
+		if exceedsMaxDeleteAll(ctx, args, paths, stdin, stderr) {
+			return 1
 		}
-		code = cleanOrphans(args, paths, stdin, stdout, stderr)
-		if code != 0 {
-			return code
+		run := &cleanRun{}
+		code := 0
+		for _, phase := range cleanPhases {
+			c := phase(ctx, args, paths, stdin, stdout, stderr, run)
+			if c == 0 {
+				continue
+			}
+			if code == 0 {
+				code = c
+			}
+			if args.FailFast || c == exitNoTTY || ctx.Err() != nil {
+				return code
+			}
+		}
+		if args.DryRun {
+			fmt.Fprintf(stdout, "\nWould free %s across %d items\n", ui.FormatSize(run.Size), run.Items)
+		} else {
+			fmt.Fprintf(stdout, "\nTotal freed: %s across projects, orphans, config (%d items)\n", ui.FormatSize(run.Freed), run.Cleaned)
 		}
-		return cleanConfig(args, paths, stdin, stdout, stderr)
+		return code
 	default:
 		fmt.Fprintf(stderr, "Unknown clean subcommand: %s\n", args.Subcommand)
 		return 1
 	}
 }
 
-// handleList handles the "list" command and subcommands.
-func handleList(args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
-	switch args.Subcommand {
-	case "projects", "":
-		return listProjects(args, paths, stdout, stderr)
-	case "orphans":
-		return listOrphans(paths, stdout, stderr)
-	case "config":
-		return listConfig(args, paths, stdout, stderr)
-	default:
+// cleanPhases are the cleanups a plain "clean" runs, in order.
+var cleanPhases = []func(context.Context, *Args, *claude.Paths, io.Reader, io.Writer, io.Writer, *cleanRun) int{
+	cleanProjects, cleanOrphans, cleanConfig,
+}
+
+// exceedsMaxDeleteAll reports whether all phases of a plain "clean" together
+// change more items than allowed by --max-delete, printing a warning if so.
+// The phases are counted by a silent dry run before anything is deleted, as
+// each phase on its own may stay below the limit.
+func exceedsMaxDeleteAll(ctx context.Context, args *Args, paths *claude.Paths, stdin io.Reader, stderr io.Writer) bool {
+	if args.MaxDelete == 0 || args.Force || args.DryRun {
+		return false
+	}
+
+	dryRun := *args
+	dryRun.DryRun = true
+	run := &cleanRun{}
+	for _, phase := range cleanPhases {
+		if phase(ctx, &dryRun, paths, stdin, io.Discard, io.Discard, run) != 0 {
+			// The real run reports the error
+			return false
+		}
+	}
+	if run.Items <= args.MaxDelete {
+		return false
+	}
+	fmt.Fprintf(stderr, "Aborting: %d items to clean across projects, orphans and config exceed --max-delete %d; nothing was changed.\n", run.Items, args.MaxDelete)
+	fmt.Fprintln(stderr, "Re-run with a higher --max-delete, or with --force if this is intended.")
+	return true
+}
+
+// resolveProjectDir returns the session directory of the project identified
+// by path, which may be the project's actual path or its encoded name.
+func resolveProjectDir(paths *claude.Paths, projects []claude.Project, path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		absPath = path
+	}
+
+	for _, p := range projects {
+		if p.EncodedName == path || (p.ActualPath != "" && filepath.Clean(p.ActualPath) == absPath) {
+			return filepath.Join(paths.Projects, p.EncodedName), nil
+		}
+	}
+
+	return "", fmt.Errorf("no project found for %s", path)
+}
+
+// cleanRun carries state between the subcommands of a combined clean.
+// All methods are no-ops on a nil *cleanRun, which individual subcommands use.
+type cleanRun struct {
+	Size    int64            // Dry-run total of reclaimable bytes
+	Items   int              // Dry-run total of changes
+	Freed   int64            // Bytes actually freed
+	Cleaned int              // Items actually removed or modified
+	Removed []claude.Project // Stale projects removed (or, in a dry run, to be removed)
+}
+
+// cleanErrors collects the items a cleanup failed to remove. By default a
+// cleanup continues past failures and reports them at the end; with
+// --fail-fast it stops at the first one.
+type cleanErrors struct {
+	failFast bool
+	errs     []error
+}
+
+// add reports the failure to clean the item of the given kind at path and
+// returns whether the cleanup should stop.
+func (c *cleanErrors) add(stderr io.Writer, kind, path string, err error) (stop bool) {
+	fmt.Fprintf(stderr, "Error cleaning %s %s: %v\n", kind, path, err)
+	c.errs = append(c.errs, fmt.Errorf("%s: %w", path, err))
+	return c.failFast
+}
+
+// exitCode reports the number of failures, if any, and returns the exit
+// code of the cleanup.
+func (c *cleanErrors) exitCode(stderr io.Writer, kind string) int {
+	if len(c.errs) == 0 {
+		return 0
+	}
+	if c.failFast {
+		fmt.Fprintf(stderr, "Stopped at the first %s that could not be cleaned (--fail-fast)\n", kind)
+	} else {
+		fmt.Fprintf(stderr, "Failed to clean %d %ss\n", len(c.errs), kind)
+	}
+	return 1
+}
+
+// add records the changes of a preview in the running totals.
+func (r *cleanRun) add(preview *ui.Preview) {
+	if r == nil {
+		return
+	}
+	r.Size += preview.TotalSize()
+	r.Items += len(preview.Changes)
+}
+
+// clean records items that were actually cleaned and the bytes they freed.
+func (r *cleanRun) clean(items int, freed int64) {
+	if r == nil {
+		return
+	}
+	r.Cleaned += items
+	r.Freed += freed
+}
+
+// removeProject records a stale project removed earlier in the run.
+func (r *cleanRun) removeProject(p claude.Project) {
+	if r == nil {
+		return
+	}
+	r.Removed = append(r.Removed, p)
+}
+
+// removedProjects returns the stale projects removed earlier in the run.
+func (r *cleanRun) removedProjects() []claude.Project {
+	if r == nil {
+		return nil
+	}
+	return r.Removed
+}
+
+// newProgress returns a progress reporter on stderr, or nil if --quiet is set.
+// Progress goes to stderr so it never mixes with machine-readable stdout.
+func newProgress(args *Args, stderr io.Writer, total int) *ui.Progress {
+	if args.Quiet {
+		return nil
+	}
+	return ui.NewProgress(stderr, total)
+}
+
+// openAuditLogger opens the audit log in the requested format.
+// It warns and returns nil if the log cannot be created.
+func openAuditLogger(args *Args, paths *claude.Paths, stderr io.Writer) *ui.AuditLogger {
+	format := args.AuditFormat
+	if format == "" {
+		format = ui.AuditFormatText
+	}
+
+	path := args.AuditLog
+	if path == "" {
+		path = ui.DefaultAuditLogPath(paths.Root)
+	}
+
+	auditLogger, err := ui.NewAuditLoggerWithFormat(path, format)
+	if err != nil {
+		fmt.Fprintln(stderr, "Warning: could not create audit log:", err)
+		return nil
+	}
+	return auditLogger
+}
+
+// checkWritableDir verifies that files can be created in dir. A missing dir
+// is fine as long as its nearest existing ancestor is a writable directory,
+// since it will be created.
+func checkWritableDir(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+
+	f, err := os.CreateTemp(dir, ".cccc-write-test-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable", dir)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// logRemoval writes the audit entry of a deleted item, noting the trash
+// location if it was moved to the trash instead.
+func logRemoval(auditLogger *ui.AuditLogger, path string, size int64, trashedTo string) {
+	if details := removalDetails(size, trashedTo); details != "" {
+		_ = auditLogger.LogWithDetails(ui.ActionDelete, path, details)
+		return
+	}
+	_ = auditLogger.Log(ui.ActionDelete, path, size)
+}
+
+// removalDetails returns the audit details of a removed item, which it only
+// has if it was moved to the trash.
+func removalDetails(size int64, trashedTo string) string {
+	if trashedTo == "" {
+		return ""
+	}
+	return fmt.Sprintf("moved to trash %s (%s)", trashedTo, ui.FormatSize(size))
+}
+
+// removalAuditEntry returns the audit entry logged as path for removing src
+// in a dry run. With --trash, src is assumed to keep its name in the trash
+// batch.
+func removalAuditEntry(args *Args, path, src string, size int64) string {
+	trashedTo := ""
+	if args.trashBatch != "" {
+		trashedTo = filepath.Join(args.trashBatch, filepath.Base(src))
+	}
+	return ui.FormatAuditEntry(ui.ActionDelete, path, size, removalDetails(size, trashedTo))
+}
+
+// dedupAuditAction returns the action an applied dedup result is logged
+// with: local configs left empty are deleted.
+func dedupAuditAction(r *cleaner.DedupResult) ui.Action {
+	if r.SuggestDelete {
+		return ui.ActionDelete
+	}
+	return ui.ActionModify
+}
+
+// printAuditEntries prints the audit entries a real run would write, with
+// --show-audit-lines. Apart from the timestamp, they are written exactly
+// like the text audit log, so the two can be diffed.
+func printAuditEntries(w io.Writer, args *Args, entries []string) {
+	if !args.ShowAuditLines {
+		return
+	}
+	fmt.Fprintln(w, "Audit log entries:")
+	for _, e := range entries {
+		fmt.Fprintln(w, e)
+	}
+}
+
+// autoApprove returns the confirmation policy selected by --yes and --yes-to-modify.
+func autoApprove(args *Args) ui.AutoApprove {
+	switch {
+	case args.Yes, args.Select:
+		// With --select, picking the items was the confirmation
+		return ui.AutoApproveAll
+	case args.YesToModify:
+		return ui.AutoApproveModify
+	default:
+		return ui.AutoApproveNone
+	}
+}
+
+// previewPageSize is the number of changes a preview lists before it
+// summarizes the rest, unless --verbose is given.
+const previewPageSize = 1000
+
+// configurePreview applies the display flags --summary-only, --verbose,
+// --no-kept and --sort-preview to preview.
+func configurePreview(args *Args, preview *ui.Preview) {
+	preview.SummaryOnly = args.SummaryOnly
+	preview.HideKept = args.NoKept
+	if !args.Verbose {
+		preview.PageSize = previewPageSize
+	}
+	if args.SortPreview {
+		preview.SortBySize()
+	}
+}
+
+// exceedsMaxDelete reports whether preview changes more items than allowed
+// by --max-delete, printing a warning if so.
+func exceedsMaxDelete(args *Args, preview *ui.Preview, stderr io.Writer) bool {
+	if args.MaxDelete == 0 || args.Force || len(preview.Changes) <= args.MaxDelete {
+		return false
+	}
+	fmt.Fprintf(stderr, "Aborting: %d items to clean exceed --max-delete %d; nothing was changed.\n", len(preview.Changes), args.MaxDelete)
+	fmt.Fprintln(stderr, "Re-run with a higher --max-delete, or with --force if this is intended.")
+	return true
+}
+
+// confirmErrorCode reports a confirmation error and returns the exit code.
+func confirmErrorCode(err error, stderr io.Writer) int {
+	if errors.Is(err, ui.ErrNoTTY) {
+		fmt.Fprintln(stderr, "No TTY and --yes not given; aborting. No changes made.")
+		return exitNoTTY
+	}
+	fmt.Fprintln(stderr, "Error:", err)
+	return 1
+}
+
+// handleList handles the "list" command and subcommands.
+func handleList(ctx context.Context, args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
+	if args.JSONStream && args.Subcommand != "projects" && args.Subcommand != "" {
+		fmt.Fprintln(stderr, "--json-stream is only supported by list projects")
+		return 1
+	}
+
+	switch args.Subcommand {
+	case "projects", "":
+		return listProjects(ctx, args, paths, stdout, stderr)
+	case "orphans":
+		return listOrphans(ctx, args, paths, stdout, stderr)
+	case "config":
+		return listConfig(ctx, args, paths, stdout, stderr)
+	case "duplicates":
+		return listDuplicates(ctx, args, paths, stdout, stderr)
+	case "corrupt":
+		return listCorrupt(ctx, args, paths, stdout, stderr)
+	case "sessions":
+		return listSessions(ctx, args, paths, stdout, stderr)
+	default:
 		fmt.Fprintf(stderr, "Unknown list subcommand: %s\n", args.Subcommand)
 		return 1
 	}
 }
 
-// cleanProjects finds and removes stale project session data.
-func cleanProjects(args *Args, paths *claude.Paths, stdin io.Reader, stdout, stderr io.Writer) int {
-	projects, err := claude.ScanProjects(paths.Projects)
+// handlePrune removes session files within the --older-than/--newer-than
+// window, keeping the --keep-latest most recent sessions of each project.
+func handlePrune(ctx context.Context, args *Args, paths *claude.Paths, stdin io.Reader, stdout, stderr io.Writer) int {
+	projects, err := scanProjects(ctx, args, paths, stderr)
 	if err != nil {
 		fmt.Fprintln(stderr, "Error scanning projects:", err)
 		return 1
 	}
 
-	stale := cleaner.FindStaleProjects(projects)
-	if len(stale) == 0 {
-		fmt.Fprintln(stdout, "No stale projects found.")
+	age := cleaner.SessionStarted
+	if args.AgeFrom == "mtime" {
+		age = cleaner.SessionModified
+	}
+	// The age window is applied below, so only the keep window filters here
+	sessions, err := cleaner.FindOldSessions(paths.Projects, projects, time.Time{}, args.KeepLatest, age)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error finding old sessions:", err)
+		return 1
+	}
+	sessions = cleaner.FilterByAge(sessions, now(), args.OlderThan, args.NewerThan, age)
+
+	if len(sessions) == 0 {
+		fmt.Fprintln(stdout, "No old sessions found.")
 		return 0
 	}
 
-	// Build kept list (non-stale)
-	var kept []claude.Project
-	staleSet := make(map[string]bool)
-	for _, p := range stale {
-		staleSet[p.EncodedName] = true
+	preview := cleaner.BuildOldSessionPreview(sessions)
+	configurePreview(args, preview)
+
+	if args.DryRun {
+		fmt.Fprintln(stdout, "[DRY RUN]")
+		_ = preview.Display(stdout)
+		var entries []string
+		for _, s := range sessions {
+			entries = append(entries, ui.FormatAuditEntry(ui.ActionDelete, s.Path, s.Size, ""))
+		}
+		printAuditEntries(stdout, args, entries)
+		return 0
+	}
+
+	confirmed, err := ui.ConfirmChangesWithAutoApprove(preview, stdin, stdout, promptOut(args, stdout), autoApprove(args))
+	if err != nil {
+		return confirmErrorCode(err, stderr)
+	}
+	if !confirmed {
+		return 0
 	}
+
+	auditLogger := openAuditLogger(args, paths, stderr)
+	if auditLogger != nil {
+		defer auditLogger.Close()
+	}
+
+	progress := newProgress(args, stderr, len(sessions))
+	results, _ := cleaner.CleanOldSessions(sessions, false, func(n int, s cleaner.OldSession) {
+		progress.Step(n, s.Path)
+	})
+	progress.Done()
+
+	var totalSaved int64
+	var pruned, failed int
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(stderr, "Error pruning session %s: %v\n", r.Path, r.Err)
+			failed++
+			continue
+		}
+		pruned++
+		totalSaved += r.Size
+		if auditLogger != nil {
+			_ = auditLogger.Log(ui.ActionDelete, r.Path, r.Size)
+		}
+	}
+
+	fmt.Fprintf(stdout, "Pruned %d sessions, freed %s\n", pruned, ui.FormatSize(totalSaved))
+	if failed > 0 {
+		fmt.Fprintf(stderr, "Failed to prune %d sessions\n", failed)
+		return 1
+	}
+	return 0
+}
+
+// handleReport writes a markdown plan of what a combined clean would do.
+func handleReport(ctx context.Context, args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
+	projects, err := scanProjects(ctx, args, paths, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error scanning projects:", err)
+		return 1
+	}
+
+	stale, kept, _ := selectStaleProjects(args, paths, projects, stderr)
+
+	// Like a combined clean, count data of the stale projects as orphaned
+	validSessionIDs := cleaner.SessionIDsExcludingStale(projects, stale)
+	opts := &cleaner.OrphanOptions{TodoPattern: args.TodoPattern, ExcludeEmpty: args.ExcludeEmpty, LogCutoff: logCutoff(args), IncludeLocks: args.IncludeLocks}
+	scanCtx, scanDone := scanContext(ctx, args)
+	orphans, err := cleaner.FindOrphansWithOptions(scanCtx, paths, validSessionIDs, opts)
+	scanDone(err)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error finding orphans:", err)
+		return 1
+	}
+	orphans, _ = dropUnknownTodos(args, orphans)
+
+	warnMissingSettings(paths, stderr)
+	global, err := claude.LoadSettings(paths.Settings)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error loading global settings:", err)
+		return 1
+	}
+	results := dedupConfigs(args, global, findLocalConfigs(args, paths, projects), stderr)
+
+	previews := []*ui.Preview{
+		cleaner.BuildStalePreview(stale, kept),
+		cleaner.BuildOrphanPreview(orphans),
+		cleaner.BuildDedupPreview(results),
+	}
+	err = ui.RenderMarkdownReport(stdout, now(), previews...)
+	if err == nil && args.GroupByDisk {
+		// The stale preview lists projects by cwd, not where their data is
+		changes := cleaner.StaleProjectData(paths.Projects, stale)
+		for _, p := range previews[1:] {
+			changes = append(changes, p.Changes...)
+		}
+		err = ui.RenderMarkdownDiskUsage(stdout, cleaner.GroupByDisk(changes))
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "Error writing report:", err)
+		return 1
+	}
+	return 0
+}
+
+// handleTrash handles the trash command.
+func handleTrash(args *Args, paths *claude.Paths, stdin io.Reader, stdout, stderr io.Writer) int {
+	switch args.Subcommand {
+	case "list":
+		return listTrash(args, paths, stdout, stderr)
+	case "empty":
+		return emptyTrash(args, paths, stdin, stdout, stderr)
+	case "":
+		fmt.Fprintln(stderr, "Usage: cccc trash [list|empty]")
+		return 1
+	default:
+		fmt.Fprintf(stderr, "Unknown trash subcommand: %s\n", args.Subcommand)
+		return 1
+	}
+}
+
+// findTrashBatches returns the trash batches selected by --older-than and
+// --newer-than.
+func findTrashBatches(args *Args, paths *claude.Paths) ([]cleaner.TrashBatch, error) {
+	batches, err := cleaner.FindTrashBatches(cleaner.DefaultTrashRoot(paths.Root))
+	if err != nil {
+		return nil, err
+	}
+	return cleaner.FilterByAge(batches, now(), args.OlderThan, args.NewerThan,
+		func(b cleaner.TrashBatch) time.Time { return b.Time }), nil
+}
+
+// printTrashEmpty reports that no trash batches were found.
+func printTrashEmpty(args *Args, stdout io.Writer) {
+	if args.OlderThan > 0 || args.NewerThan > 0 {
+		fmt.Fprintln(stdout, "No trashed batches in the given age range.")
+		return
+	}
+	fmt.Fprintln(stdout, "Trash is empty.")
+}
+
+// listTrash lists the batches moved to the trash by --trash.
+func listTrash(args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
+	batches, err := findTrashBatches(args, paths)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error reading trash:", err)
+		return 1
+	}
+
+	if len(batches) == 0 {
+		printTrashEmpty(args, stdout)
+		return 0
+	}
+
+	var totalSize int64
+	fmt.Fprintln(stdout, "Trashed batches:")
+	for _, b := range batches {
+		totalSize += b.Size
+		fmt.Fprintf(stdout, "  %s  %8s  %s\n", b.Time.Local().Format("2006-01-02 15:04:05"), ui.FormatSize(b.Size), b.Path)
+	}
+
+	fmt.Fprintf(stdout, "\nTotal: %d batches (%s)\n", len(batches), ui.FormatSize(totalSize))
+	return 0
+}
+
+// emptyTrash permanently removes the items moved to the trash by --trash,
+// limited to the batches selected by --older-than and --newer-than.
+func emptyTrash(args *Args, paths *claude.Paths, stdin io.Reader, stdout, stderr io.Writer) int {
+	batches, err := findTrashBatches(args, paths)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error reading trash:", err)
+		return 1
+	}
+
+	if len(batches) == 0 {
+		printTrashEmpty(args, stdout)
+		return 0
+	}
+
+	preview := cleaner.BuildTrashPreview(batches)
+	configurePreview(args, preview)
+
+	if args.DryRun {
+		fmt.Fprintln(stdout, "[DRY RUN]")
+		_ = preview.Display(stdout)
+		var entries []string
+		for _, b := range batches {
+			entries = append(entries, ui.FormatAuditEntry(ui.ActionDelete, b.Path, b.Size, ""))
+		}
+		printAuditEntries(stdout, args, entries)
+		return 0
+	}
+
+	confirmed, err := ui.ConfirmChangesWithAutoApprove(preview, stdin, stdout, promptOut(args, stdout), autoApprove(args))
+	if err != nil {
+		return confirmErrorCode(err, stderr)
+	}
+	if !confirmed {
+		return 0
+	}
+
+	auditLogger := openAuditLogger(args, paths, stderr)
+	if auditLogger != nil {
+		defer auditLogger.Close()
+	}
+
+	removed, err := cleaner.EmptyTrash(batches)
+	var freed int64
+	for _, b := range removed {
+		freed += b.Size
+		if auditLogger != nil {
+			_ = auditLogger.Log(ui.ActionDelete, b.Path, b.Size)
+		}
+	}
+
+	fmt.Fprintf(stdout, "Emptied trash, freed %s\n", ui.FormatSize(freed))
+	if err != nil {
+		fmt.Fprintln(stderr, "Error emptying trash:", err)
+		return 1
+	}
+	return 0
+}
+
+// handleAudit prints the audit log entries selected by --since and --action,
+// followed by totals.
+func handleAudit(args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
+	path := args.AuditLog
+	if path == "" {
+		path = ui.DefaultAuditLogPath(paths.Root)
+	}
+
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Fprintf(stdout, "No audit log found at %s.\n", path)
+			return 0
+		}
+		fmt.Fprintln(stderr, "Error reading audit log:", err)
+		return 1
+	}
+	defer f.Close()
+
+	entries, err := ui.ParseAuditLog(f)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error reading audit log:", err)
+		return 1
+	}
+
+	var actions []ui.Action
+	if args.AuditAction != "" {
+		actions = append(actions, args.AuditAction)
+	}
+	entries = ui.FilterAuditEntries(entries, args.Since, actions)
+	if len(entries) == 0 {
+		fmt.Fprintln(stdout, "No audit entries found.")
+		return 0
+	}
+
+	var freed int64
+	counts := make(map[ui.Action]int)
+	for _, e := range entries {
+		counts[e.Action]++
+		if e.Action == ui.ActionDelete {
+			freed += e.Size
+		}
+
+		line := fmt.Sprintf("%s  %-6s  %s", e.Time.Local().Format("2006-01-02 15:04:05"), e.Action, e.Path)
+		switch {
+		case e.Details != "":
+			line += ": " + e.Details
+		case e.Action == ui.ActionDelete:
+			line += fmt.Sprintf(" (%s)", ui.FormatSize(e.Size))
+		}
+		fmt.Fprintln(stdout, line)
+	}
+
+	var byAction []string
+	for _, action := range slices.Sorted(maps.Keys(counts)) {
+		byAction = append(byAction, fmt.Sprintf("%d %s", counts[action], action))
+	}
+	fmt.Fprintf(stdout, "\nTotal: %d entries (%s), freed %s\n", len(entries), strings.Join(byAction, ", "), ui.FormatSize(freed))
+	return 0
+}
+
+// handleConfig handles the config command.
+func handleConfig(ctx context.Context, args *Args, paths *claude.Paths, stdin io.Reader, stdout, stderr io.Writer) int {
+	switch args.Subcommand {
+	case "consolidate":
+		return consolidateConfig(ctx, args, paths, stdin, stdout, stderr)
+	case "lint":
+		return lintConfig(ctx, args, paths, stdout, stderr)
+	case "":
+		fmt.Fprintln(stderr, "Usage: cccc config consolidate|lint")
+		return 1
+	default:
+		fmt.Fprintf(stderr, "Unknown config subcommand: %s\n", args.Subcommand)
+		return 1
+	}
+}
+
+// lintConfig reports problematic permission entries in the global settings
+// and every local config. It fails if any finding is an error.
+func lintConfig(ctx context.Context, args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
+	warnMissingSettings(paths, stderr)
+	projects, err := scanProjects(ctx, args, paths, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error scanning projects:", err)
+		return 1
+	}
+
+	items := make([]lintJSON, 0)
+	var files, errs, warnings int
+	for _, path := range append([]string{paths.Settings}, findLocalConfigs(args, paths, projects)...) {
+		settings, err := claude.LoadSettings(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "Warning: could not load %s: %v\n", path, err)
+			continue
+		}
+
+		findings := cleaner.LintSettings(settings)
+		if len(findings) == 0 {
+			continue
+		}
+		files++
+		if !args.JSON {
+			fmt.Fprintln(stdout, path)
+		}
+		for _, f := range findings {
+			if f.Severity == cleaner.LintError {
+				errs++
+			} else {
+				warnings++
+			}
+			if args.JSON {
+				items = append(items, lintJSON{Path: path, Severity: f.Severity, List: f.List, Entry: f.Entry, Message: f.Message})
+				continue
+			}
+			fmt.Fprintf(stdout, "  %-7s %s %q: %s\n", f.Severity, f.List, f.Entry, f.Message)
+		}
+	}
+
+	if args.JSON {
+		if code := writeJSONOrFail(stdout, stderr, items); code != 0 {
+			return code
+		}
+	} else if files == 0 {
+		fmt.Fprintln(stdout, "No problems found.")
+	} else {
+		fmt.Fprintf(stdout, "\nFound %d errors and %d warnings in %d files\n", errs, warnings, files)
+	}
+
+	if errs > 0 {
+		return 1
+	}
+	return 0
+}
+
+// consolidateConfig moves permission entries present in every local config
+// but missing globally into the global settings and strips them from the
+// local configs.
+func consolidateConfig(ctx context.Context, args *Args, paths *claude.Paths, stdin io.Reader, stdout, stderr io.Writer) int {
+	warnMissingSettings(paths, stderr)
+	global, err := claude.LoadSettings(paths.Settings)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error loading global settings:", err)
+		return 1
+	}
+
+	projects, err := scanProjects(ctx, args, paths, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error scanning projects:", err)
+		return 1
+	}
+
+	var localPaths []string
+	var locals []*claude.Settings
+	for _, configPath := range findLocalConfigs(args, paths, projects) {
+		local, err := claude.LoadSettings(configPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "Warning: could not load %s: %v\n", configPath, err)
+			continue
+		}
+		localPaths = append(localPaths, configPath)
+		locals = append(locals, local)
+	}
+
+	if len(locals) < cleaner.MinConsolidateConfigs {
+		fmt.Fprintf(stdout, "Found %d local configs; at least %d are needed to consolidate.\n", len(locals), cleaner.MinConsolidateConfigs)
+		return 0
+	}
+
+	common := cleaner.FindCommonLocalEntries(global, locals)
+	if common.IsEmpty() {
+		fmt.Fprintln(stdout, "No entries are shared by all local configs.")
+		return 0
+	}
+
+	results := cleaner.BuildConsolidation(common, localPaths, locals)
+	preview := cleaner.BuildConsolidationPreview(paths.Settings, common, results)
+	configurePreview(args, preview)
+
+	if args.DryRun {
+		fmt.Fprintln(stdout, "[DRY RUN]")
+		_ = preview.Display(stdout)
+		entries := []string{ui.FormatAuditEntry(ui.ActionModify, paths.Settings, 0, preview.Changes[0].Description)}
+		for _, r := range results {
+			entries = append(entries, ui.FormatAuditEntry(dedupAuditAction(&r), r.LocalPath, 0, r.FormatAuditDetails()))
+		}
+		printAuditEntries(stdout, args, entries)
+		return 0
+	}
+
+	confirmed, err := ui.ConfirmChangesWithAutoApprove(preview, stdin, stdout, promptOut(args, stdout), autoApprove(args))
+	if err != nil {
+		return confirmErrorCode(err, stderr)
+	}
+	if !confirmed {
+		return 0
+	}
+
+	auditLogger := openAuditLogger(args, paths, stderr)
+	if auditLogger != nil {
+		defer auditLogger.Close()
+	}
+
+	// Only strip local entries once they are safely in the global settings
+	if err := cleaner.AddPermissionEntries(paths.Settings, common); err != nil {
+		fmt.Fprintf(stderr, "Error updating global settings %s: %v\n", paths.Settings, err)
+		return 1
+	}
+	if auditLogger != nil {
+		_ = auditLogger.LogWithDetails(ui.ActionModify, paths.Settings, preview.Changes[0].Description)
+	}
+
+	var failed int
+	for _, r := range results {
+		if err := cleaner.ApplyDedup(&r, false); err != nil {
+			fmt.Fprintf(stderr, "Error updating %s: %v\n", r.LocalPath, err)
+			failed++
+			continue
+		}
+		if auditLogger != nil {
+			_ = auditLogger.LogWithDetails(dedupAuditAction(&r), r.LocalPath, r.FormatAuditDetails())
+		}
+	}
+
+	moved := len(common.Permissions.Allow) + len(common.Permissions.Deny) + len(common.Permissions.Ask)
+	fmt.Fprintf(stdout, "Moved %d permission entries into global settings, updated %d local configs\n", moved, len(results)-failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// handleCache handles the cache command.
+func handleCache(args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
+	switch args.Subcommand {
+	case "clear":
+		if err := claude.ClearProjectCache(claude.DefaultCachePath(paths.Root)); err != nil {
+			fmt.Fprintln(stderr, "Error clearing cache:", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, "Cache cleared.")
+		return 0
+	case "":
+		fmt.Fprintln(stderr, "Usage: cccc cache clear")
+		return 1
+	default:
+		fmt.Fprintf(stderr, "Unknown cache subcommand: %s\n", args.Subcommand)
+		return 1
+	}
+}
+
+// scanProjects scans the projects directory, reusing cached metadata for
+// unchanged project directories unless --no-cache is given.
+func scanProjects(ctx context.Context, args *Args, paths *claude.Paths, stderr io.Writer) ([]claude.Project, error) {
+	var projects []claude.Project
+	err := scanProjectsFunc(ctx, args, paths, stderr, func(p claude.Project) error {
+		projects = append(projects, p)
+		return nil
+	})
+	return projects, err
+}
+
+// scanProjectsFunc is like scanProjects but calls fn for each project as it
+// is scanned.
+func scanProjectsFunc(ctx context.Context, args *Args, paths *claude.Paths, stderr io.Writer, fn func(claude.Project) error) error {
+	var cache *claude.ProjectCache
+	if !args.NoCache {
+		cache = claude.LoadProjectCache(claude.DefaultCachePath(paths.Root))
+	}
+
+	scanCtx, scanDone := scanContext(ctx, args)
+	_, warnings, err := claude.ScanProjectsWithOptions(scanCtx, paths.Projects, claude.ScanOptions{
+		Cache:       cache,
+		Concurrency: args.Concurrency,
+		Each:        fn,
+	})
+	scanDone(err)
+	reportScanWarnings(args, stderr, warnings)
+	if err != nil {
+		return err
+	}
+
+	if cache != nil {
+		// The cache is only an optimization; failing to write it is not an error
+		_ = cache.Save()
+	}
+	return nil
+}
+
+// scanContext limits a single filesystem scan to --timeout. The timeout
+// deliberately does not cover prompts and removals between the scans, so
+// taking time to confirm does not abort a cleanup halfway. scanDone must be
+// called with the scan's error once it has finished.
+func scanContext(ctx context.Context, args *Args) (scanCtx context.Context, scanDone func(error)) {
+	if args.Timeout == 0 {
+		return ctx, func(error) {}
+	}
+	scanCtx, cancel := context.WithTimeout(ctx, args.Timeout)
+	return scanCtx, func(err error) {
+		if err != nil && errors.Is(scanCtx.Err(), context.DeadlineExceeded) {
+			args.scanTimedOut = true
+		}
+		cancel()
+	}
+}
+
+// reportScanWarnings tells the user about project directories that could
+// not be read, so they do not silently vanish from the results. The summary
+// is printed once per run, as a combined clean scans several times.
+func reportScanWarnings(args *Args, stderr io.Writer, warnings []claude.ScanWarning) {
+	if len(warnings) == 0 || args.scanWarned {
+		return
+	}
+	args.scanWarned = true
+
+	fmt.Fprintf(stderr, "Warning: skipped %d project directories due to read errors", len(warnings))
+	if !args.Verbose {
+		fmt.Fprintln(stderr, " (use --verbose to list them)")
+		return
+	}
+	fmt.Fprintln(stderr, ":")
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "  %s: %v\n", w.Path, w.Err)
+	}
+}
+
+// cleanProjects finds and removes stale project session data.
+func cleanProjects(ctx context.Context, args *Args, paths *claude.Paths, stdin io.Reader, stdout, stderr io.Writer, run *cleanRun) int {
+	projects, err := scanProjects(ctx, args, paths, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error scanning projects:", err)
+		return 1
+	}
+
+	if args.Input != "" {
+		return cleanInputProjects(args, paths, projects, stdin, stdout, stderr, run)
+	}
+
+	stale, kept, skipped := selectStaleProjects(args, paths, projects, stderr)
+	if args.Explain {
+		printExplanations(stdout, args, paths, stale, kept)
+	}
+	if skipped > 0 {
+		fmt.Fprintf(stdout, "Skipping %d projects on unavailable filesystems (use --include-unavailable to clean them).\n", skipped)
+	}
+	if len(stale) == 0 {
+		fmt.Fprintln(stdout, "No stale projects found.")
+		return 0
+	}
+
+	if args.Select {
+		var unselected []claude.Project
+		stale, unselected, err = selectProjects(stale, stdin, promptOut(args, stdout))
+		if err != nil {
+			if errors.Is(err, ui.ErrSelectionAborted) {
+				fmt.Fprintln(stdout, "Aborted. No changes made.")
+				return 0
+			}
+			return confirmErrorCode(err, stderr)
+		}
+		if len(stale) == 0 {
+			fmt.Fprintln(stdout, "No projects selected.")
+			return 0
+		}
+		kept = append(kept, unselected...)
+	}
+
+	preview := cleaner.BuildStalePreview(stale, kept)
+	noteOrphanedData(ctx, args, paths, projects, stale, preview, stderr)
+	return removeProjects(args, paths, stale, preview, stdin, stdout, stderr, run)
+}
+
+// noteOrphanedData notes below each stale project of preview how many todos
+// and how much file history of its sessions cleaning it orphans, so the
+// follow-up cleanup is visible in the same view. The notes are informational;
+// if the data cannot be read, a warning is printed and they are left out.
+func noteOrphanedData(ctx context.Context, args *Args, paths *claude.Paths, projects, stale []claude.Project, preview *ui.Preview, stderr io.Writer) {
+	scanCtx, scanDone := scanContext(ctx, args)
+	data, err := cleaner.FindOrphanedByCleaning(scanCtx, paths, projects, stale, args.TodoPattern)
+	scanDone(err)
+	if err != nil {
+		fmt.Fprintln(stderr, "Warning: cannot check the todos and file history of stale projects:", err)
+		return
+	}
+
+	// A full clean removes the orphans in the same run
+	hint := ""
+	if args.Subcommand == "projects" {
+		hint = " (remove them with 'cccc clean orphans')"
+	}
+	for i, p := range stale {
+		if d := data[p.EncodedName]; !d.IsEmpty() {
+			preview.Changes[i].Note = "cleaning this orphans " + formatSessionData(d) + hint
+		}
+	}
+}
+
+// formatSessionData formats todo and file-history data, e.g. "4 todos and
+// 120.0 MB of file history".
+func formatSessionData(d cleaner.SessionData) string {
+	var parts []string
+	switch d.Todos {
+	case 0:
+	case 1:
+		parts = append(parts, "1 todo")
+	default:
+		parts = append(parts, fmt.Sprintf("%d todos", d.Todos))
+	}
+	if d.FileHistory > 0 {
+		parts = append(parts, ui.FormatSize(d.FileHistorySize)+" of file history")
+	}
+	return strings.Join(parts, " and ")
+}
+
+// selectProjects lets the user pick which of the stale projects to clean,
+// with --select, and returns the picked and the other projects.
+func selectProjects(stale []claude.Project, stdin io.Reader, stdout io.Writer) (selected, unselected []claude.Project, err error) {
+	changes := cleaner.BuildStalePreview(stale, nil).Changes
+	for i, p := range stale {
+		changes[i].Path = projectDisplayPath(p)
+	}
+
+	picked, err := ui.SelectChanges(changes, stdin, stdout)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, p := range stale {
+		if slices.Contains(picked, i) {
+			selected = append(selected, p)
+		} else {
+			unselected = append(unselected, p)
+		}
+	}
+	return selected, unselected, nil
+}
+
+// cleanInputProjects removes the projects listed in the --input file,
+// regardless of whether they are stale. Each line is a project path (its
+// cwd) or encoded directory name. Unknown entries are refused, or skipped
+// with a warning if --skip-unknown is set.
+func cleanInputProjects(args *Args, paths *claude.Paths, projects []claude.Project, stdin io.Reader, stdout, stderr io.Writer, run *cleanRun) int {
+	lines, err := readInputLines(args.Input, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error reading --input:", err)
+		return 1
+	}
+
+	selected, unknown := selectInputProjects(projects, lines)
+	if len(unknown) > 0 {
+		if !args.SkipUnknown {
+			fmt.Fprintf(stderr, "Error: %d entries of --input are not known projects:\n", len(unknown))
+			for _, u := range unknown {
+				fmt.Fprintf(stderr, "  %s\n", u)
+			}
+			fmt.Fprintln(stderr, "Nothing was removed. Re-run with --skip-unknown to skip them.")
+			return 1
+		}
+		for _, u := range unknown {
+			fmt.Fprintf(stderr, "Warning: skipping %s: not a known project\n", u)
+		}
+	}
+	if len(selected) == 0 {
+		fmt.Fprintln(stdout, "No projects to clean.")
+		return 0
+	}
+
+	preview := cleaner.BuildStalePreview(selected, nil)
+	preview.Title = "Selected Project Cleanup"
+	return removeProjects(args, paths, selected, preview, stdin, stdout, stderr, run)
+}
+
+// readInputLines returns the non-blank lines of the file name, or of stdin
+// if name is "-".
+func readInputLines(name string, stdin io.Reader) ([]string, error) {
+	r := stdin
+	if name != "-" {
+		f, err := os.Open(filepath.Clean(name))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// selectInputProjects returns the projects whose cwd or encoded name is
+// listed in lines, in the order of projects, and the lines that match no
+// project.
+func selectInputProjects(projects []claude.Project, lines []string) (selected []claude.Project, unknown []string) {
+	wanted := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		matched := false
+		for _, p := range projects {
+			if line == p.EncodedName || (p.ActualPath != "" && claude.NormalizePath(line) == p.ActualPath) {
+				wanted[p.EncodedName] = true
+				matched = true
+			}
+		}
+		if !matched {
+			unknown = append(unknown, line)
+		}
+	}
+
+	for _, p := range projects {
+		if wanted[p.EncodedName] {
+			selected = append(selected, p)
+		}
+	}
+	return selected, unknown
+}
+
+// selectedDespiteCWD reports whether p is removed although its cwd may
+// exist, because it was named with --input or --assume-missing or lacks the
+// --verify-marker files. Other projects are re-checked right before removal.
+func selectedDespiteCWD(args *Args, p claude.Project) bool {
+	if args.Input != "" || len(cleaner.FindAssumedMissingProjects([]claude.Project{p}, args.AssumeMissing)) > 0 {
+		return true
+	}
+	return len(args.VerifyMarkers) > 0 && p.ActualPath != "" && !cleaner.HasMarker(p.ActualPath, args.VerifyMarkers)
+}
+
+// removeProjects previews, confirms and removes the projects, which are
+// either the stale ones or those selected with --input.
+func removeProjects(args *Args, paths *claude.Paths, stale []claude.Project, preview *ui.Preview, stdin io.Reader, stdout, stderr io.Writer, run *cleanRun) int {
+	configurePreview(args, preview)
+	archiveDir := ""
+	if args.KeepNewestSession {
+		archiveDir = cleaner.DefaultArchiveRoot(paths.Root)
+		for i := range preview.Changes {
+			preview.Changes[i].Description += ", newest session is kept in " + archiveDir
+		}
+	}
+	opts := cleaner.StaleOptions{TrashDir: args.trashBatch, ArchiveDir: archiveDir}
+
+	if args.DryRun {
+		fmt.Fprintln(stdout, "[DRY RUN]")
+		_ = preview.Display(stdout)
+		if args.ShowAuditLines {
+			var entries []string
+			for _, p := range stale {
+				result, err := cleaner.CleanStaleProjectWithOptions(paths.Projects, p, true, opts)
+				if err != nil {
+					fmt.Fprintf(stderr, "Warning: %s: %v\n", projectDisplayPath(p), err)
+					continue
+				}
+				entries = append(entries, removalAuditEntry(args, p.ActualPath, filepath.Join(paths.Projects, p.EncodedName), result.SizeSaved))
+			}
+			printAuditEntries(stdout, args, entries)
+		}
+		run.add(preview)
+		for _, p := range stale {
+			run.removeProject(p)
+		}
+		return 0
+	}
+
+	if exceedsMaxDelete(args, preview, stderr) {
+		return 1
+	}
+
+	confirmed, err := ui.ConfirmChangesWithAutoApprove(preview, stdin, stdout, promptOut(args, stdout), autoApprove(args))
+	if err != nil {
+		return confirmErrorCode(err, stderr)
+	}
+	if !confirmed {
+		return 0
+	}
+
+	// Create audit logger
+	auditLogger := openAuditLogger(args, paths, stderr)
+	if auditLogger != nil {
+		defer auditLogger.Close()
+	}
+
+	// Perform cleanup
+	progress := newProgress(args, stderr, len(stale))
+	failures := &cleanErrors{failFast: args.FailFast}
+	var totalSaved int64
+	var cleaned, archived int
+	for i, p := range stale {
+		progress.Step(i+1, p.ActualPath)
+		opts.AllowActive = selectedDespiteCWD(args, p)
+		result, err := cleaner.CleanStaleProjectWithOptions(paths.Projects, p, false, opts)
+		if errors.Is(err, cleaner.ErrProjectActive) {
+			fmt.Fprintf(stderr, "Warning: skipping %s: %v\n", projectDisplayPath(p), err)
+			if auditLogger != nil {
+				_ = auditLogger.LogWithDetails(ui.ActionSkip, p.ActualPath, err.Error())
+			}
+			continue
+		}
+		if err != nil {
+			if failures.add(stderr, "project", projectDisplayPath(p), err) {
+				break
+			}
+			continue
+		}
+		cleaned++
+		totalSaved += result.SizeSaved
+		if result.ArchivedTo != "" {
+			archived++
+		}
+		run.removeProject(p)
+		run.clean(1, result.SizeSaved)
+
+		if auditLogger != nil {
+			logRemoval(auditLogger, p.ActualPath, result.SizeSaved, result.TrashedTo)
+		}
+	}
+	progress.Done()
+
+	fmt.Fprintf(stdout, "Cleaned %d stale projects, freed %s\n", cleaned, ui.FormatSize(totalSaved))
+	if archived > 0 {
+		fmt.Fprintf(stdout, "Kept the newest session of %d projects in %s\n", archived, archiveDir)
+	}
+	return failures.exitCode(stderr, "project")
+}
+
+// selectStaleProjects splits the projects into those clean projects removes
+// and those it keeps, and returns how many unavailable projects are skipped
+// because --include-unavailable is not set.
+func selectStaleProjects(args *Args, paths *claude.Paths, projects []claude.Project, stderr io.Writer) (stale, kept []claude.Project, skipped int) {
+	// Project directories without session files are not projects to clean:
+	// empty ones are cleaned as orphans, others may hold data and are kept
+	var withSessions []claude.Project
+	for _, p := range projects {
+		if cleaner.HasSessionFiles(filepath.Join(paths.Projects, p.EncodedName)) {
+			withSessions = append(withSessions, p)
+		}
+	}
+	projects = withSessions
+
+	stale = cleaner.FindStaleProjects(projects)
+	if args.KeepNoCWD {
+		stale = slices.DeleteFunc(stale, func(p claude.Project) bool { return p.ActualPath == "" })
+	}
+	unavailable := cleaner.FindUnavailableProjects(projects)
+	if args.IncludeUnavailable {
+		stale = append(stale, unavailable...)
+	} else {
+		skipped = len(unavailable)
+	}
+	stale = appendAssumedMissing(stale, cleaner.FindAssumedMissingProjects(projects, args.AssumeMissing))
+	if len(args.VerifyMarkers) > 0 {
+		unmarked := cleaner.FindUnmarkedProjects(projects, args.VerifyMarkers)
+		for _, p := range unmarked {
+			fmt.Fprintf(stderr, "Warning: %s exists but contains none of %s; treating it as stale\n",
+				p.ActualPath, strings.Join(args.VerifyMarkers, ", "))
+		}
+		stale = appendAssumedMissing(stale, unmarked)
+	}
+	stale = cleaner.FilterByAge(stale, now(), args.OlderThan, args.NewerThan,
+		func(p claude.Project) time.Time {
+			if args.AgeFrom == "mtime" {
+				return p.LastModified
+			}
+			return p.LastUsed
+		})
+	if !args.IncludeClaudeHome {
+		stale = dropProjectsInsideClaudeHome(paths, stale, stderr)
+	}
+
+	// Build kept list (non-stale)
+	staleSet := make(map[string]bool)
+	for _, p := range stale {
+		staleSet[p.EncodedName] = true
+	}
+	for _, p := range projects {
+		if !staleSet[p.EncodedName] {
+			kept = append(kept, p)
+		}
+	}
+
+	return stale, kept, skipped
+}
+
+// dropProjectsInsideClaudeHome removes the projects whose cwd is inside the
+// Claude home from stale, warning about each. Their sessions were started
+// from within the tree cccc operates on, so cleaning them is left to an
+// explicit --include-claude-home.
+func dropProjectsInsideClaudeHome(paths *claude.Paths, stale []claude.Project, stderr io.Writer) []claude.Project {
+	inside := cleaner.FindProjectsInside(stale, paths.Root)
+	if len(inside) == 0 {
+		return stale
+	}
+
+	insideSet := make(map[string]bool, len(inside))
+	for _, p := range inside {
+		insideSet[p.EncodedName] = true
+		fmt.Fprintf(stderr, "Warning: skipping %s: its cwd is inside the Claude home %s (use --include-claude-home to clean it)\n", p.ActualPath, paths.Root)
+	}
+
+	var kept []claude.Project
+	for _, p := range stale {
+		if !insideSet[p.EncodedName] {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// printExplanations prints the reason for each project selected as stale or
+// kept by selectStaleProjects.
+func printExplanations(w io.Writer, args *Args, paths *claude.Paths, stale, kept []claude.Project) {
+	fmt.Fprintln(w, "Decisions:")
+	for _, p := range stale {
+		status, reason := p.ExplainStatus()
+		switch {
+		case status == claude.StatusActive && len(args.VerifyMarkers) > 0 && !cleaner.HasMarker(p.ActualPath, args.VerifyMarkers):
+			reason = fmt.Sprintf("stale: cwd exists but contains none of %s (--verify-marker)", strings.Join(args.VerifyMarkers, ", "))
+		case status == claude.StatusActive:
+			reason = "stale: assumed missing (--assume-missing)"
+		case status == claude.StatusUnavailable:
+			reason += " (included by --include-unavailable)"
+		}
+		fmt.Fprintf(w, "  %s\n        %s\n", projectDisplayPath(p), reason)
+	}
+	for _, p := range kept {
+		status, reason := p.ExplainStatus()
+		switch {
+		case status != claude.StatusActive && !args.IncludeClaudeHome && cleaner.IsInside(p.ActualPath, paths.Root):
+			reason = "kept: cwd is inside the Claude home (use --include-claude-home)"
+		case status == claude.StatusNoCWD && args.KeepNoCWD:
+			reason = "kept: no cwd found in any session (--keep-no-cwd)"
+		case status.IsStale():
+			reason = "kept: last used outside the --older-than/--newer-than window"
+		}
+		fmt.Fprintf(w, "  %s\n        %s\n", projectDisplayPath(p), reason)
+	}
+	fmt.Fprintln(w)
+}
+
+// appendAssumedMissing adds the projects forced stale with --assume-missing
+// or --verify-marker that are not stale already.
+func appendAssumedMissing(stale, assumed []claude.Project) []claude.Project {
+	for _, p := range assumed {
+		if !slices.ContainsFunc(stale, func(s claude.Project) bool { return s.EncodedName == p.EncodedName }) {
+			stale = append(stale, p)
+		}
+	}
+	return stale
+}
+
+// cleanOrphans finds and removes orphaned data.
+func cleanOrphans(ctx context.Context, args *Args, paths *claude.Paths, stdin io.Reader, stdout, stderr io.Writer, run *cleanRun) int {
+	// Get valid session IDs from projects
+	projects, err := scanProjects(ctx, args, paths, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error scanning projects:", err)
+		return 1
+	}
+
+	// Sessions of projects removed earlier in a combined clean no longer
+	// count as valid, so their todos and file history are cleaned in the same
+	// run (in a dry run the projects still exist on disk)
+	validSessionIDs := cleaner.SessionIDsExcludingStale(projects, run.removedProjects())
+
+	var scope *cleaner.OrphanScope
+	if args.Project != "" {
+		projectDir, err := resolveProjectDir(paths, projects, args.Project)
+		if err != nil {
+			fmt.Fprintln(stderr, "Error:", err)
+			return 1
+		}
+		scope, err = cleaner.NewProjectScope(projectDir)
+		if err != nil {
+			fmt.Fprintln(stderr, "Error reading project:", err)
+			return 1
+		}
+	}
+
+	opts := &cleaner.OrphanOptions{Scope: scope, TodoPattern: args.TodoPattern, Types: orphanKinds[args.OrphanKind], AgentID: args.Agent, Details: args.Verbose, ExcludeEmpty: args.ExcludeEmpty, LogCutoff: logCutoff(args), IncludeLocks: args.IncludeLocks}
+	scanCtx, scanDone := scanContext(ctx, args)
+	orphans, err := cleaner.FindOrphansWithOptions(scanCtx, paths, validSessionIDs, opts)
+	scanDone(err)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error finding orphans:", err)
+		return 1
+	}
+
+	orphans, unknown := dropUnknownTodos(args, orphans)
+	if unknown > 0 {
+		fmt.Fprintf(stdout, "Skipping %d unrecognized todo files (use --include-unknown to clean them).\n", unknown)
+	}
+	if args.ExplainOrphan && len(orphans) > 0 {
+		printOrphanExplanations(stdout, orphans)
+	}
+
+	if len(orphans) == 0 {
+		fmt.Fprintln(stdout, "No orphaned data found.")
+		return 0
+	}
+
+	preview := cleaner.BuildOrphanPreview(orphans)
+	configurePreview(args, preview)
+
+	if args.DryRun {
+		fmt.Fprintln(stdout, "[DRY RUN]")
+		_ = preview.Display(stdout)
+		var entries []string
+		for _, o := range orphans {
+			entries = append(entries, removalAuditEntry(args, o.Path, o.Path, o.SizeSaved))
+		}
+		printAuditEntries(stdout, args, entries)
+		run.add(preview)
+		return 0
+	}
+
+	if exceedsMaxDelete(args, preview, stderr) {
+		return 1
+	}
+
+	confirmed, err := ui.ConfirmChangesWithAutoApprove(preview, stdin, stdout, promptOut(args, stdout), autoApprove(args))
+	if err != nil {
+		return confirmErrorCode(err, stderr)
+	}
+	if !confirmed {
+		return 0
+	}
+
+	// Create audit logger
+	auditLogger := openAuditLogger(args, paths, stderr)
+	if auditLogger != nil {
+		defer auditLogger.Close()
+	}
+
+	// Perform cleanup, continuing past individual failures unless --fail-fast
+	progress := newProgress(args, stderr, len(orphans))
+	results, _ := cleaner.CleanOrphansWithOptions(orphans, cleaner.CleanOrphanOptions{
+		TrashDir: args.trashBatch,
+		FailFast: args.FailFast,
+		Progress: func(n int, o cleaner.OrphanResult) { progress.Step(n, o.Path) },
+	})
+	progress.Done()
+
+	failures := &cleanErrors{failFast: args.FailFast}
+	var totalSaved int64
+	var cleaned int
+	for _, r := range results {
+		if r.Err != nil {
+			failures.add(stderr, "orphan", r.Path, r.Err)
+			continue
+		}
+		cleaned++
+		totalSaved += r.SizeSaved
+		if auditLogger != nil {
+			logRemoval(auditLogger, r.Path, r.SizeSaved, r.TrashedTo)
+		}
+	}
+
+	run.clean(cleaned, totalSaved)
+	fmt.Fprintf(stdout, "Cleaned %d orphaned items, freed %s\n", cleaned, ui.FormatSize(totalSaved))
+	return failures.exitCode(stderr, "orphan")
+}
+
+// printOrphanExplanations prints why each orphan is considered orphaned.
+func printOrphanExplanations(w io.Writer, orphans []cleaner.OrphanResult) {
+	fmt.Fprintln(w, "Reasons:")
+	for _, o := range orphans {
+		fmt.Fprintf(w, "  %s\n        %s\n", o.Path, o.Reason)
+	}
+	fmt.Fprintln(w)
+}
+
+// dropUnknownTodos removes unrecognized todo files from orphans unless
+// --include-unknown is set, and returns how many were removed.
+func dropUnknownTodos(args *Args, orphans []cleaner.OrphanResult) ([]cleaner.OrphanResult, int) {
+	if args.IncludeUnknown {
+		return orphans, 0
+	}
+
+	var attributed []cleaner.OrphanResult
+	unknown := 0
+	for _, o := range orphans {
+		if o.Type == cleaner.OrphanTypeUnknownTodo {
+			unknown++
+			continue
+		}
+		attributed = append(attributed, o)
+	}
+	return attributed, unknown
+}
+
+// cleanConfig deduplicates local configs against global settings.
+func cleanConfig(ctx context.Context, args *Args, paths *claude.Paths, stdin io.Reader, stdout, stderr io.Writer, run *cleanRun) int {
+	var results []cleaner.DedupResult
+	if args.ApplyPlan != "" {
+		plan, err := cleaner.LoadDedupPlan(args.ApplyPlan)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error loading plan %s: %v\n", args.ApplyPlan, err)
+			return 1
+		}
+		results = withoutDrift(plan, stderr)
+		if len(results) == 0 {
+			fmt.Fprintln(stdout, "Nothing left to apply from the plan.")
+			return 0
+		}
+	} else {
+		var code int
+		results, code = findConfigDuplicates(ctx, args, paths, stdout, stderr)
+		if len(results) == 0 {
+			return code
+		}
+	}
+
+	// Use verbose preview if requested
+	var preview *ui.Preview
+	if args.Verbose {
+		preview = cleaner.BuildDedupPreviewVerbose(results, paths.Settings)
+	} else {
+		preview = cleaner.BuildDedupPreview(results)
+	}
+	configurePreview(args, preview)
+
+	if args.DryRun {
+		fmt.Fprintln(stdout, "[DRY RUN]")
+		_ = preview.Display(stdout)
+		if args.Diff {
+			printDedupDiffs(stdout, stderr, results)
+		}
+		var entries []string
+		for _, r := range results {
+			entries = append(entries, ui.FormatAuditEntry(dedupAuditAction(&r), r.LocalPath, 0, r.FormatAuditDetails()))
+		}
+		printAuditEntries(stdout, args, entries)
+		run.add(preview)
+		if args.SavePlan != "" {
+			if err := cleaner.SaveDedupPlan(args.SavePlan, results, now()); err != nil {
+				fmt.Fprintln(stderr, "Error saving plan:", err)
+				return 1
+			}
+			fmt.Fprintf(stdout, "Saved plan to %s (apply it with 'cccc clean config --apply-plan %s')\n", args.SavePlan, args.SavePlan)
+		}
+		return 0
+	}
+
+	if args.Diff {
+		printDedupDiffs(stdout, stderr, results)
+	}
+
+	if exceedsMaxDelete(args, preview, stderr) {
+		return 1
+	}
+
+	confirmed, err := ui.ConfirmChangesWithAutoApprove(preview, stdin, stdout, promptOut(args, stdout), autoApprove(args))
+	if err != nil {
+		return confirmErrorCode(err, stderr)
+	}
+	if !confirmed {
+		return 0
+	}
+
+	// Create audit logger
+	auditLogger := openAuditLogger(args, paths, stderr)
+	if auditLogger != nil {
+		defer auditLogger.Close()
+	}
+
+	// Apply deduplication
+	failures := &cleanErrors{failFast: args.FailFast}
+	var applied []cleaner.DedupResult
+	for _, r := range results {
+		before, after := r.Sizes()
+		if err := cleaner.ApplyDedup(&r, false); err != nil {
+			if failures.add(stderr, "config", r.LocalPath, err) {
+				break
+			}
+			continue
+		}
+		applied = append(applied, r)
+		run.clean(1, before-after)
+		if auditLogger != nil {
+			_ = auditLogger.LogWithDetails(dedupAuditAction(&r), r.LocalPath, r.FormatAuditDetails())
+		}
+	}
+
+	summary := cleaner.SummarizeDedup(applied)
+	fmt.Fprintf(stdout, "Deduplicated %d config files, removed %d duplicate entries", summary.Configs, summary.Entries)
+	if summary.Deleted > 0 {
+		fmt.Fprintf(stdout, " (%s deleted)", ui.PluralFiles(summary.Deleted))
+	}
+	fmt.Fprintln(stdout)
+	return failures.exitCode(stderr, "config")
+}
+
+// findConfigDuplicates scans the local configs of all projects for entries
+// duplicated in the global settings. If there are none, it returns the exit
+// code, having printed why.
+func findConfigDuplicates(ctx context.Context, args *Args, paths *claude.Paths, stdout, stderr io.Writer) ([]cleaner.DedupResult, int) {
+	// Load global settings
+	warnMissingSettings(paths, stderr)
+	global, err := claude.LoadSettings(paths.Settings)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error loading global settings:", err)
+		return nil, 1
+	}
+
+	// Get project paths from scanned projects for fast config lookup
+	projects, err := scanProjects(ctx, args, paths, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error scanning projects:", err)
+		return nil, 1
+	}
+
+	localConfigs := findLocalConfigs(args, paths, projects)
+
+	if len(localConfigs) == 0 {
+		fmt.Fprintln(stdout, "No local configs found.")
+		return nil, 0
+	}
+
+	results := dedupConfigs(args, global, localConfigs, stderr)
+
+	if len(results) == 0 {
+		fmt.Fprintln(stdout, "No duplicate configs found.")
+	}
+	return results, 0
+}
+
+// withoutDrift returns the planned results whose local configs are still as
+// they were when the plan was saved, warning about the others.
+func withoutDrift(plan *cleaner.DedupPlan, stderr io.Writer) []cleaner.DedupResult {
+	var results []cleaner.DedupResult
+	for _, r := range plan.Results {
+		if err := plan.CheckDrift(&r); err != nil {
+			fmt.Fprintf(stderr, "Warning: skipping %s: changed since the plan was saved: %v\n", r.LocalPath, err)
+			continue
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// warnMissingSettings warns if no global settings file was found, in which
+// case local configs are compared against empty settings.
+func warnMissingSettings(paths *claude.Paths, stderr io.Writer) {
+	if !paths.SettingsFound {
+		fmt.Fprintf(stderr, "Warning: no global settings found (expected %s)\n", paths.Settings)
+	}
+}
+
+// dedupConfigs analyzes each local config against the global settings and
+// returns those with duplicate entries or that can be deleted entirely.
+func dedupConfigs(args *Args, global *claude.Settings, localConfigs []string, stderr io.Writer) []cleaner.DedupResult {
+	opts := &cleaner.DedupOptions{Normalize: args.Normalize, IgnoreCase: args.IgnoreCase}
+
+	var results []cleaner.DedupResult
+	for _, configPath := range localConfigs {
+		local, err := claude.LoadSettings(configPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "Warning: could not load %s: %v\n", configPath, err)
+			continue
+		}
+
+		result := cleaner.DeduplicateConfigWithOptions(configPath, global, local, opts)
+		if result.HasDuplicates() || result.SuggestDelete {
+			results = append(results, *result)
+		}
+	}
+	return results
+}
+
+// findLocalConfigs returns the local config files of the scanned projects.
+func findLocalConfigs(args *Args, paths *claude.Paths, projects []claude.Project) []string {
+	// Extract unique project paths
+	var projectPaths []string
+	for _, p := range projects {
+		if p.ActualPath != "" {
+			projectPaths = append(projectPaths, p.ActualPath)
+		}
+	}
+
+	// Exclude ~/.claude/settings.local.json (if home dir is a project, it shouldn't be treated as a local config)
+	// unless --include-global-local asks for it to be deduplicated as well
+	homeLocalSettings := filepath.Join(paths.Root, "settings.local.json")
+	excludePath := homeLocalSettings
+	if args.IncludeGlobalLocal {
+		excludePath = ""
+	}
+
+	var configs []string
+	if args.Recursive {
+		configs = cleaner.FindLocalConfigsRecursive(projectPaths, excludePath, cleaner.DefaultConfigSearchDepth)
+	} else {
+		// Find local configs only in known project directories (fast)
+		configs = cleaner.FindLocalConfigsFromProjects(projectPaths, excludePath)
+	}
+
+	if args.IncludeGlobalLocal {
+		if _, err := os.Stat(homeLocalSettings); err == nil && !containsPath(configs, homeLocalSettings) {
+			configs = append(configs, homeLocalSettings)
+		}
+	}
+
+	return configs
+}
+
+// containsPath reports whether paths contains path after cleaning both.
+func containsPath(paths []string, path string) bool {
+	path = filepath.Clean(path)
+	for _, p := range paths {
+		if filepath.Clean(p) == path {
+			return true
+		}
+	}
+	return false
+}
+
+// listProjects lists all projects and their status.
+func listProjects(ctx context.Context, args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
+	if args.JSONStream {
+		return streamProjects(ctx, args, paths, stdout, stderr)
+	}
+
+	projects, err := scanProjects(ctx, args, paths, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error scanning projects:", err)
+		return 1
+	}
+
+	if len(projects) == 0 && args.Format != "csv" && !args.JSON && !args.PathsOnly {
+		fmt.Fprintln(stdout, "No projects found.")
+		if args.MachineTotals {
+			fmt.Fprintln(stdout, "# totals projects=0 stale=0 unavailable=0 bytes=0")
+		}
+		return 0
+	}
+
+	statuses := make(map[string]claude.ProjectStatus, len(projects))
+	var staleCount, unavailableCount int
+	var totalSize int64
+	for _, p := range projects {
+		status := projectStatus(args, p)
+		statuses[p.EncodedName] = status
+		totalSize += p.TotalSize
+		switch {
+		case status.IsStale():
+			staleCount++
+		case status == claude.StatusUnavailable:
+			unavailableCount++
+		}
+	}
+
+	var shown []claude.Project
 	for _, p := range projects {
-		if !staleSet[p.EncodedName] {
-			kept = append(kept, p)
+		// Skip non-stale if --stale-only
+		if args.StaleOnly && !statuses[p.EncodedName].IsStale() {
+			continue
 		}
+		if args.Only != "" && !matchesOnly(args.Only, p) {
+			continue
+		}
+		shown = append(shown, p)
 	}
 
-	preview := cleaner.BuildStalePreview(stale, kept)
+	if args.JSON {
+		items := make([]projectJSON, 0, len(shown))
+		for _, p := range shown {
+			items = append(items, newProjectJSON(p, statuses[p.EncodedName]))
+		}
+		return writeJSONOrFail(stdout, stderr, items)
+	}
 
-	if args.DryRun {
-		fmt.Fprintln(stdout, "[DRY RUN]")
-		_ = preview.Display(stdout)
+	if args.PathsOnly {
+		for _, p := range shown {
+			if p.ActualPath != "" {
+				fmt.Fprintln(stdout, p.ActualPath)
+			}
+		}
 		return 0
 	}
 
-	confirmed, err := ui.ConfirmChanges(preview, stdin, stdout, args.Yes)
-	if err != nil {
-		fmt.Fprintln(stderr, "Error:", err)
-		return 1
-	}
-	if !confirmed {
+	switch args.Format {
+	case "csv":
+		if err := writeProjectsCSV(stdout, shown, statuses); err != nil {
+			fmt.Fprintln(stderr, "Error writing CSV:", err)
+			return 1
+		}
+		// CSV output is meant for spreadsheets; skip the human summary
 		return 0
+	case "table":
+		printProjectsTable(stdout, shown, statuses, args.AbsoluteTime)
+	default:
+		var reasons map[string]string
+		if args.Explain {
+			reasons = make(map[string]string, len(shown))
+			for _, p := range shown {
+				_, reasons[p.EncodedName] = p.ExplainStatus()
+				if statuses[p.EncodedName] == claude.StatusUnknown {
+					reasons[p.EncodedName] = "kept: no cwd found in any session (--keep-no-cwd)"
+				}
+			}
+		}
+		printProjectsList(stdout, shown, statuses, reasons, args.AbsoluteTime)
 	}
 
-	// Create audit logger
-	auditLogger, err := ui.NewAuditLogger(ui.DefaultAuditLogPath(paths.Root))
-	if err != nil {
-		fmt.Fprintln(stderr, "Warning: could not create audit log:", err)
+	if args.Only != "" {
+		fmt.Fprintf(stdout, "\nShowing %d projects matching %q\n", len(shown), args.Only)
+	}
+	if unavailableCount > 0 {
+		fmt.Fprintf(stdout, "\nTotal: %d projects (%d stale, %d unavailable)\n", len(projects), staleCount, unavailableCount)
 	} else {
-		defer auditLogger.Close()
+		fmt.Fprintf(stdout, "\nTotal: %d projects (%d stale)\n", len(projects), staleCount)
 	}
+	if args.MachineTotals {
+		fmt.Fprintf(stdout, "# totals projects=%d stale=%d unavailable=%d bytes=%d\n", len(projects), staleCount, unavailableCount, totalSize)
+	}
+	return 0
+}
 
-	// Perform cleanup
-	var totalSaved int64
-	for _, p := range stale {
-		result, err := cleaner.CleanStaleProject(paths.Projects, p, false)
-		if err != nil {
-			fmt.Fprintf(stderr, "Error cleaning project %s: %v\n", p.ActualPath, err)
+// projectStatus returns the status of p to list, which is StatusUnknown
+// instead of StatusNoCWD with --keep-no-cwd.
+func projectStatus(args *Args, p claude.Project) claude.ProjectStatus {
+	status := p.Status()
+	if status == claude.StatusNoCWD && args.KeepNoCWD {
+		return claude.StatusUnknown
+	}
+	return status
+}
+
+// matchesOnly reports whether the project's path or encoded name matches the
+// --only pattern: a glob if it contains glob metacharacters, a substring
+// otherwise.
+func matchesOnly(pattern string, p claude.Project) bool {
+	for _, name := range []string{p.ActualPath, p.EncodedName} {
+		if name == "" {
 			continue
 		}
-		totalSaved += result.SizeSaved
-
-		if auditLogger != nil {
-			_ = auditLogger.Log(ui.ActionDelete, p.ActualPath, result.SizeSaved)
+		if strings.ContainsAny(pattern, "*?[") {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				return true
+			}
+		} else if strings.Contains(name, pattern) {
+			return true
 		}
 	}
-
-	fmt.Fprintf(stdout, "Cleaned %d stale projects, freed %s\n", len(stale), ui.FormatSize(totalSaved))
-	return 0
+	return false
 }
 
-// cleanOrphans finds and removes orphaned data.
-func cleanOrphans(args *Args, paths *claude.Paths, stdin io.Reader, stdout, stderr io.Writer) int {
-	// Get valid session IDs from projects
-	projects, err := claude.ScanProjects(paths.Projects)
-	if err != nil {
-		fmt.Fprintln(stderr, "Error scanning projects:", err)
+// writeJSONOrFail writes items as JSON and returns the exit code.
+func writeJSONOrFail(stdout, stderr io.Writer, items any) int {
+	if err := writeJSON(stdout, items); err != nil {
+		fmt.Fprintln(stderr, "Error writing JSON:", err)
 		return 1
 	}
+	return 0
+}
 
-	var validSessionIDs []string
+// printProjectsList renders projects in the default two-line-per-project
+// format, plus a line with the reason for projects in reasons (may be nil).
+func printProjectsList(w io.Writer, projects []claude.Project, statuses map[string]claude.ProjectStatus, reasons map[string]string, absoluteTime bool) {
+	fmt.Fprintln(w, "Projects:")
 	for _, p := range projects {
-		validSessionIDs = append(validSessionIDs, p.SessionIDs...)
-	}
+		status := statuses[p.EncodedName]
 
-	orphans, err := cleaner.FindOrphans(paths, validSessionIDs)
-	if err != nil {
-		fmt.Fprintln(stderr, "Error finding orphans:", err)
-		return 1
+		fmt.Fprintf(w, "  [%s] %s\n", status, projectDisplayPath(p))
+		fmt.Fprintf(w, "        %d files, %s, last used: %s\n",
+			p.FileCount, ui.FormatSize(p.TotalSize), formatLastUsed(p.LastUsed, absoluteTime))
+		if reason, ok := reasons[p.EncodedName]; ok {
+			fmt.Fprintf(w, "        %s\n", reason)
+		}
 	}
+}
 
-	if len(orphans) == 0 {
-		fmt.Fprintln(stdout, "No orphaned data found.")
-		return 0
+// projectDisplayPath returns the project path for listings, guessed from the
+// directory name if no session recorded a cwd.
+func projectDisplayPath(p claude.Project) string {
+	if p.ActualPath == "" {
+		return fmt.Sprintf("%s (guessed from directory name)", claude.DecodeProjectName(p.EncodedName))
 	}
+	return p.ActualPath
+}
 
-	preview := cleaner.BuildOrphanPreview(orphans)
-
-	if args.DryRun {
-		fmt.Fprintln(stdout, "[DRY RUN]")
-		_ = preview.Display(stdout)
-		return 0
+// formatLastUsed formats a project's last use as a relative time, or as a
+// date if absolute is set. Projects without valid sessions show "never".
+func formatLastUsed(t time.Time, absolute bool) string {
+	if absolute && !t.IsZero() {
+		return t.Format("2006-01-02")
 	}
+	return ui.FormatRelativeTime(t)
+}
 
-	confirmed, err := ui.ConfirmChanges(preview, stdin, stdout, args.Yes)
-	if err != nil {
-		fmt.Fprintln(stderr, "Error:", err)
-		return 1
-	}
-	if !confirmed {
-		return 0
+// printProjectsTable renders projects as an aligned table, one row per project.
+func printProjectsTable(w io.Writer, projects []claude.Project, statuses map[string]claude.ProjectStatus, absoluteTime bool) {
+	// Leave room for the STATUS, FILES, SIZE and LAST USED columns
+	maxPath := terminalWidth() - 45
+	if maxPath < 20 {
+		maxPath = 20
 	}
 
-	// Create audit logger
-	auditLogger, err := ui.NewAuditLogger(ui.DefaultAuditLogPath(paths.Root))
-	if err != nil {
-		fmt.Fprintln(stderr, "Warning: could not create audit log:", err)
-	} else {
-		defer auditLogger.Close()
-	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tPATH\tFILES\tSIZE\tLAST USED")
+	for _, p := range projects {
+		status := statuses[p.EncodedName]
 
-	// Perform cleanup
-	results, err := cleaner.CleanOrphans(orphans, false)
-	if err != nil {
-		fmt.Fprintln(stderr, "Error cleaning orphans:", err)
-		return 1
+		path := p.ActualPath
+		if path == "" {
+			path = claude.DecodeProjectName(p.EncodedName) + " (guessed)"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n",
+			status, truncatePath(path, maxPath), p.FileCount, ui.FormatSize(p.TotalSize), formatLastUsed(p.LastUsed, absoluteTime))
 	}
+	_ = tw.Flush()
+}
 
-	var totalSaved int64
-	for _, r := range results {
-		totalSaved += r.SizeSaved
-		if auditLogger != nil {
-			_ = auditLogger.Log(ui.ActionDelete, r.Path, r.SizeSaved)
+// writeProjectsCSV writes projects as CSV with a header row.
+func writeProjectsCSV(w io.Writer, projects []claude.Project, statuses map[string]claude.ProjectStatus) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"encoded_name", "actual_path", "file_count", "total_size_bytes", "last_used", "stale"}); err != nil {
+		return err
+	}
+	for _, p := range projects {
+		lastUsed := ""
+		if !p.LastUsed.IsZero() {
+			lastUsed = p.LastUsed.UTC().Format(time.RFC3339)
+		}
+		record := []string{
+			p.EncodedName,
+			p.ActualPath,
+			strconv.Itoa(p.FileCount),
+			strconv.FormatInt(p.TotalSize, 10),
+			lastUsed,
+			strconv.FormatBool(statuses[p.EncodedName].IsStale()),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
 		}
 	}
+	cw.Flush()
+	return cw.Error()
+}
 
-	fmt.Fprintf(stdout, "Cleaned %d orphaned items, freed %s\n", len(results), ui.FormatSize(totalSaved))
-	return 0
+// truncatePath shortens a path to at most maxLen runes, keeping its tail.
+func truncatePath(path string, maxLen int) string {
+	runes := []rune(path)
+	if len(runes) <= maxLen {
+		return path
+	}
+	return "..." + string(runes[len(runes)-(maxLen-3):])
 }
 
-// cleanConfig deduplicates local configs against global settings.
-func cleanConfig(args *Args, paths *claude.Paths, stdin io.Reader, stdout, stderr io.Writer) int {
-	// Load global settings
-	global, err := claude.LoadSettings(paths.Settings)
-	if err != nil {
-		fmt.Fprintln(stderr, "Error loading global settings:", err)
-		return 1
+// terminalWidth returns the terminal width from $COLUMNS, defaulting to 80.
+func terminalWidth() int {
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
 	}
+	return 80
+}
 
-	// Get project paths from scanned projects for fast config lookup
-	projects, err := claude.ScanProjects(paths.Projects)
+// listOrphans lists orphaned data without removing it.
+func listOrphans(ctx context.Context, args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
+	// Get valid session IDs from projects
+	projects, err := scanProjects(ctx, args, paths, stderr)
 	if err != nil {
 		fmt.Fprintln(stderr, "Error scanning projects:", err)
 		return 1
 	}
 
-	// Extract unique project paths
-	var projectPaths []string
+	var validSessionIDs []string
 	for _, p := range projects {
-		if p.ActualPath != "" {
-			projectPaths = append(projectPaths, p.ActualPath)
-		}
+		validSessionIDs = append(validSessionIDs, p.SessionIDs...)
 	}
 
-	// Find local configs only in known project directories (fast)
-	// Exclude ~/.claude/settings.local.json (if home dir is a project, it shouldn't be treated as a local config)
-	homeLocalSettings := filepath.Join(paths.Root, "settings.local.json")
-	localConfigs := cleaner.FindLocalConfigsFromProjects(projectPaths, homeLocalSettings)
-
-	if len(localConfigs) == 0 {
-		fmt.Fprintln(stdout, "No local configs found.")
-		return 0
+	opts := &cleaner.OrphanOptions{TodoPattern: args.TodoPattern, Types: orphanKinds[args.OrphanKind], AgentID: args.Agent, Details: args.Verbose, ExcludeEmpty: args.ExcludeEmpty, LogCutoff: logCutoff(args), IncludeLocks: args.IncludeLocks}
+	scanCtx, scanDone := scanContext(ctx, args)
+	orphans, err := cleaner.FindOrphansWithOptions(scanCtx, paths, validSessionIDs, opts)
+	scanDone(err)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error finding orphans:", err)
+		return 1
 	}
 
-	// Analyze each local config
-	var results []cleaner.DedupResult
-	for _, configPath := range localConfigs {
-		local, err := claude.LoadSettings(configPath)
-		if err != nil {
-			fmt.Fprintf(stderr, "Warning: could not load %s: %v\n", configPath, err)
-			continue
+	if args.JSON {
+		items := make([]orphanJSON, 0, len(orphans))
+		for _, o := range orphans {
+			item := orphanJSON{Type: o.Type, Path: o.Path, Size: o.SizeSaved}
+			if args.ExplainOrphan {
+				item.Reason = o.Reason
+			}
+			items = append(items, item)
 		}
-
-		result := cleaner.DeduplicateConfig(configPath, global, local)
-		if result.HasDuplicates() || result.SuggestDelete {
-			results = append(results, *result)
+		if err := writeJSONWithSummary(stdout, items, newOrphanSummaryJSON(cleaner.SummarizeOrphans(orphans))); err != nil {
+			fmt.Fprintln(stderr, "Error writing JSON:", err)
+			return 1
 		}
-	}
-
-	if len(results) == 0 {
-		fmt.Fprintln(stdout, "No duplicate configs found.")
 		return 0
 	}
 
-	// Use verbose preview if requested
-	var preview *ui.Preview
-	if args.Verbose {
-		preview = cleaner.BuildDedupPreviewVerbose(results, paths.Settings)
-	} else {
-		preview = cleaner.BuildDedupPreview(results)
-	}
-
-	if args.DryRun {
-		fmt.Fprintln(stdout, "[DRY RUN]")
-		_ = preview.Display(stdout)
+	if args.PathsOnly {
+		for _, o := range orphans {
+			fmt.Fprintln(stdout, o.Path)
+		}
 		return 0
 	}
 
-	confirmed, err := ui.ConfirmChanges(preview, stdin, stdout, args.Yes)
-	if err != nil {
-		fmt.Fprintln(stderr, "Error:", err)
-		return 1
-	}
-	if !confirmed {
+	if len(orphans) == 0 {
+		fmt.Fprintln(stdout, "No orphaned data found.")
 		return 0
 	}
 
-	// Create audit logger
-	auditLogger, err := ui.NewAuditLogger(ui.DefaultAuditLogPath(paths.Root))
-	if err != nil {
-		fmt.Fprintln(stderr, "Warning: could not create audit log:", err)
-	} else {
-		defer auditLogger.Close()
+	if args.ExplainOrphan {
+		printOrphanExplanations(stdout, orphans)
 	}
 
-	// Apply deduplication
-	for _, r := range results {
-		if err := cleaner.ApplyDedup(&r, false); err != nil {
-			fmt.Fprintf(stderr, "Error deduplicating %s: %v\n", r.LocalPath, err)
-			continue
-		}
-		if auditLogger != nil {
-			action := ui.ActionModify
-			if r.SuggestDelete {
-				action = ui.ActionDelete
-			}
-			_ = auditLogger.LogWithDetails(action, r.LocalPath, r.FormatAuditDetails())
-		}
+	preview := cleaner.BuildOrphanPreview(orphans)
+	configurePreview(args, preview)
+	_ = preview.Display(stdout)
+
+	fmt.Fprintln(stdout, "\nBy type:")
+	for _, s := range cleaner.SummarizeOrphans(orphans) {
+		fmt.Fprintf(stdout, "  %-18s %5d items  %8s\n", s.Type, s.Count, ui.FormatSize(s.Size))
 	}
 
-	fmt.Fprintf(stdout, "Deduplicated %d config files\n", len(results))
 	return 0
 }
 
-// listProjects lists all projects and their status.
-func listProjects(args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
-	projects, err := claude.ScanProjects(paths.Projects)
+// listDuplicates lists session IDs that appear in more than one project.
+func listDuplicates(ctx context.Context, args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
+	projects, err := scanProjects(ctx, args, paths, stderr)
 	if err != nil {
 		fmt.Fprintln(stderr, "Error scanning projects:", err)
 		return 1
 	}
 
-	if len(projects) == 0 {
-		fmt.Fprintln(stdout, "No projects found.")
+	duplicates := cleaner.FindDuplicateSessions(projects)
+
+	if args.JSON {
+		items := make([]duplicateSessionJSON, 0, len(duplicates))
+		for _, d := range duplicates {
+			items = append(items, duplicateSessionJSON{SessionID: d.SessionID, Projects: d.Projects})
+		}
+		return writeJSONOrFail(stdout, stderr, items)
+	}
+
+	if len(duplicates) == 0 {
+		fmt.Fprintln(stdout, "No duplicate sessions found.")
 		return 0
 	}
 
-	stale := cleaner.FindStaleProjects(projects)
-	staleSet := make(map[string]bool)
-	for _, p := range stale {
-		staleSet[p.EncodedName] = true
+	fmt.Fprintln(stdout, "Duplicate sessions:")
+	for _, d := range duplicates {
+		fmt.Fprintf(stdout, "  %s\n", d.SessionID)
+		for _, name := range d.Projects {
+			fmt.Fprintf(stdout, "        %s\n", name)
+		}
 	}
 
-	fmt.Fprintln(stdout, "Projects:")
-	for _, p := range projects {
-		isStale := staleSet[p.EncodedName]
+	fmt.Fprintf(stdout, "\nTotal: %d duplicate sessions\n", len(duplicates))
+	return 0
+}
 
-		// Skip non-stale if --stale-only
-		if args.StaleOnly && !isStale {
-			continue
-		}
+// listCorrupt lists session files that fail to parse.
+func listCorrupt(ctx context.Context, args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
+	corrupt, err := cleaner.FindCorruptSessions(paths.Projects)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error finding corrupt sessions:", err)
+		return 1
+	}
 
-		status := "OK"
-		if isStale {
-			status = "STALE"
+	if args.JSON {
+		items := make([]corruptSessionJSON, 0, len(corrupt))
+		for _, c := range corrupt {
+			items = append(items, corruptSessionJSON{
+				Path:    c.Path,
+				Project: c.Project,
+				Line:    c.Line,
+				Error:   c.Err.Error(),
+				Size:    c.Size,
+			})
 		}
+		return writeJSONOrFail(stdout, stderr, items)
+	}
 
-		path := p.ActualPath
-		if path == "" {
-			path = "(unknown path)"
-		}
+	if len(corrupt) == 0 {
+		fmt.Fprintln(stdout, "No corrupt session files found.")
+		return 0
+	}
 
-		fmt.Fprintf(stdout, "  [%s] %s\n", status, path)
-		fmt.Fprintf(stdout, "        %d files, %s, last used: %s\n",
-			p.FileCount, ui.FormatSize(p.TotalSize), p.LastUsed.Format("2006-01-02"))
+	var totalSize int64
+	fmt.Fprintln(stdout, "Corrupt session files:")
+	for _, c := range corrupt {
+		totalSize += c.Size
+		fmt.Fprintf(stdout, "  %s\n", c.Path)
+		fmt.Fprintf(stdout, "        line %d: %v (%s)\n", c.Line, c.Err, ui.FormatSize(c.Size))
 	}
 
-	fmt.Fprintf(stdout, "\nTotal: %d projects (%d stale)\n", len(projects), len(stale))
+	fmt.Fprintf(stdout, "\nTotal: %d corrupt session files (%s)\n", len(corrupt), ui.FormatSize(totalSize))
 	return 0
 }
 
-// listOrphans lists orphaned data without removing it.
-func listOrphans(paths *claude.Paths, stdout, stderr io.Writer) int {
-	// Get valid session IDs from projects
-	projects, err := claude.ScanProjects(paths.Projects)
+// defaultTopSessions is the number of session files list sessions shows
+// without --top.
+const defaultTopSessions = 20
+
+// listSessions lists the largest session files across all projects, largest
+// first, to find the transcripts that take up the space of active projects.
+func listSessions(ctx context.Context, args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
+	sessions, err := claude.ScanSessions(paths.Projects)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error scanning sessions:", err)
+		return 1
+	}
+	// The project scan (usually cached) provides the cwd of each project
+	projects, err := scanProjects(ctx, args, paths, stderr)
 	if err != nil {
 		fmt.Fprintln(stderr, "Error scanning projects:", err)
 		return 1
 	}
-
-	var validSessionIDs []string
+	byName := make(map[string]claude.Project, len(projects))
 	for _, p := range projects {
-		validSessionIDs = append(validSessionIDs, p.SessionIDs...)
+		byName[p.EncodedName] = p
+	}
+	projectPath := func(s claude.SessionFile) string {
+		if p, ok := byName[s.Project]; ok {
+			return projectDisplayPath(p)
+		}
+		return projectDisplayPath(claude.Project{EncodedName: s.Project})
 	}
 
-	orphans, err := cleaner.FindOrphans(paths, validSessionIDs)
-	if err != nil {
-		fmt.Fprintln(stderr, "Error finding orphans:", err)
-		return 1
+	var totalSize int64
+	for _, s := range sessions {
+		totalSize += s.Size
+	}
+	slices.SortStableFunc(sessions, func(a, b claude.SessionFile) int {
+		return cmp.Compare(b.Size, a.Size)
+	})
+	top := args.Top
+	if top == 0 {
+		top = defaultTopSessions
+	}
+	listed := sessions[:min(top, len(sessions))]
+
+	if args.JSON {
+		items := make([]sessionFileJSON, 0, len(listed))
+		for _, s := range listed {
+			items = append(items, sessionFileJSON{
+				Path:        s.Path,
+				Project:     s.Project,
+				ProjectPath: byName[s.Project].ActualPath,
+				Size:        s.Size,
+				Modified:    s.ModTime.UTC().Format(time.RFC3339),
+			})
+		}
+		return writeJSONOrFail(stdout, stderr, items)
 	}
 
-	if len(orphans) == 0 {
-		fmt.Fprintln(stdout, "No orphaned data found.")
+	if len(sessions) == 0 {
+		fmt.Fprintln(stdout, "No session files found.")
 		return 0
 	}
 
-	preview := cleaner.BuildOrphanPreview(orphans)
-	_ = preview.Display(stdout)
+	var listedSize int64
+	fmt.Fprintln(stdout, "Largest session files:")
+	for i, s := range listed {
+		listedSize += s.Size
+		fmt.Fprintf(stdout, "  %d. %s\n", i+1, s.Path)
+		fmt.Fprintf(stdout, "        %s, %s, modified %s\n", projectPath(s), ui.FormatSize(s.Size), formatLastUsed(s.ModTime, args.AbsoluteTime))
+	}
 
+	fmt.Fprintf(stdout, "\nTotal: %d of %d session files (%s of %s)\n", len(listed), len(sessions), ui.FormatSize(listedSize), ui.FormatSize(totalSize))
 	return 0
 }
 
 // listConfig lists duplicate config entries without removing them.
-func listConfig(args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
+func listConfig(ctx context.Context, args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
 	// Load global settings
+	warnMissingSettings(paths, stderr)
 	global, err := claude.LoadSettings(paths.Settings)
 	if err != nil {
 		fmt.Fprintln(stderr, "Error loading global settings:", err)
@@ -513,42 +3362,31 @@ func listConfig(args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
 	}
 
 	// Get project paths from scanned projects for fast config lookup
-	projects, err := claude.ScanProjects(paths.Projects)
+	projects, err := scanProjects(ctx, args, paths, stderr)
 	if err != nil {
 		fmt.Fprintln(stderr, "Error scanning projects:", err)
 		return 1
 	}
 
-	// Extract unique project paths
-	var projectPaths []string
-	for _, p := range projects {
-		if p.ActualPath != "" {
-			projectPaths = append(projectPaths, p.ActualPath)
-		}
-	}
-
-	// Find local configs only in known project directories (fast)
-	homeLocalSettings := filepath.Join(paths.Root, "settings.local.json")
-	localConfigs := cleaner.FindLocalConfigsFromProjects(projectPaths, homeLocalSettings)
+	localConfigs := findLocalConfigs(args, paths, projects)
 
-	if len(localConfigs) == 0 {
+	if len(localConfigs) == 0 && !args.JSON {
 		fmt.Fprintln(stdout, "No local configs found.")
 		return 0
 	}
 
-	// Analyze each local config
-	var results []cleaner.DedupResult
-	for _, configPath := range localConfigs {
-		local, err := claude.LoadSettings(configPath)
-		if err != nil {
-			fmt.Fprintf(stderr, "Warning: could not load %s: %v\n", configPath, err)
-			continue
-		}
+	results := dedupConfigs(args, global, localConfigs, stderr)
 
-		result := cleaner.DeduplicateConfig(configPath, global, local)
-		if result.HasDuplicates() || result.SuggestDelete {
-			results = append(results, *result)
+	if args.JSON {
+		items := make([]dedupJSON, 0, len(results))
+		for _, r := range results {
+			items = append(items, newDedupJSON(r))
 		}
+		if err := writeJSONWithSummary(stdout, items, newDedupSummaryJSON(cleaner.SummarizeDedup(results))); err != nil {
+			fmt.Fprintln(stderr, "Error writing JSON:", err)
+			return 1
+		}
+		return 0
 	}
 
 	if len(results) == 0 {
@@ -556,6 +3394,14 @@ func listConfig(args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
 		return 0
 	}
 
+	if args.GroupBy {
+		if err := cleaner.RenderDedupByEntry(stdout, cleaner.GroupDedupByEntry(results)); err != nil {
+			fmt.Fprintln(stderr, "Error writing output:", err)
+			return 1
+		}
+		return 0
+	}
+
 	// Use verbose preview if requested
 	var preview *ui.Preview
 	if args.Verbose {
@@ -563,8 +3409,25 @@ func listConfig(args *Args, paths *claude.Paths, stdout, stderr io.Writer) int {
 	} else {
 		preview = cleaner.BuildDedupPreview(results)
 	}
+	configurePreview(args, preview)
 
 	_ = preview.Display(stdout)
+	if args.Diff {
+		printDedupDiffs(stdout, stderr, results)
+	}
 
 	return 0
 }
+
+// printDedupDiffs prints the unified diff of each deduplication result.
+func printDedupDiffs(stdout, stderr io.Writer, results []cleaner.DedupResult) {
+	for _, r := range results {
+		diff, err := cleaner.RenderDedupDiff(&r)
+		if err != nil {
+			fmt.Fprintf(stderr, "Warning: could not diff %s: %v\n", r.LocalPath, err)
+			continue
+		}
+		fmt.Fprintln(stdout)
+		fmt.Fprint(stdout, diff)
+	}
+}
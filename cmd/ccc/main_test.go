@@ -2,12 +2,18 @@ package main
 
 import (
 	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+	"github.com/mkoepf/claude-code-config-cleaner/internal/ui"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -44,6 +50,27 @@ func TestParseArgs_VersionFlag(t *testing.T) {
 	assert.True(t, args.Version)
 }
 
+func TestParseArgs_ShortVersionFlag(t *testing.T) {
+	args, err := parseArgs([]string{"-V"})
+	require.NoError(t, err)
+	assert.True(t, args.Version)
+}
+
+func TestRunCLI_VersionShowsBuildInfo(t *testing.T) {
+	oldVersion, oldCommit, oldDate := Version, Commit, Date
+	defer func() { Version, Commit, Date = oldVersion, oldCommit, oldDate }()
+	Version, Commit, Date = "1.2.3", "abc1234", "2025-12-10T00:00:00Z"
+
+	var stdout, stderr bytes.Buffer
+	stdin := strings.NewReader("")
+
+	// --version must short-circuit even when combined with a command
+	code := runCLI([]string{"-V", "clean"}, stdin, &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "cccc version 1.2.3 (commit abc1234, built 2025-12-10T00:00:00Z)\n", stdout.String())
+}
+
 func TestRunCLI_VersionFlag(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	stdin := strings.NewReader("")
@@ -274,6 +301,39 @@ func TestRunCLI_CleanProjectsDryRun(t *testing.T) {
 	assert.DirExists(t, projectDir)
 }
 
+func TestRunCLI_CleanProjectsNotesOrphanedData(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectDir := filepath.Join(claudeDir, "projects", "-stale")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(filepath.Join(tmpDir, "gone")) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "sess1.jsonl"), []byte(sessionData), 0644))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "todos"), 0755))
+	for _, name := range []string{"sess1-agent-a.json", "sess1-agent-b.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(claudeDir, "todos", name), []byte(`{}`), 0644))
+	}
+	historyDir := filepath.Join(claudeDir, "file-history", "sess1")
+	require.NoError(t, os.MkdirAll(historyDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(historyDir, "file.txt"), make([]byte, 2048), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "projects", "--dry-run"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "Note: cleaning this orphans 2 todos and 2.0 KB of file history (remove them with 'cccc clean orphans')")
+
+	// A full clean removes the orphans itself, so it does not point to another command
+	stdout.Reset()
+	code = runCLI([]string{"clean", "--dry-run"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "Note: cleaning this orphans 2 todos and 2.0 KB of file history\n")
+}
+
 func TestRunCLI_CleanProjectsWithConfirmation(t *testing.T) {
 	tmpDir := t.TempDir()
 	claudeDir := filepath.Join(tmpDir, ".claude")
@@ -581,3 +641,2648 @@ func TestRunCLI_CleanConfigVerboseDryRun(t *testing.T) {
 	// Should show the global config path
 	assert.Contains(t, output, "settings.json")
 }
+
+func TestRunCLI_CleanAllDryRunShowsReclaimSummary(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+
+	// Create a stale project (cwd doesn't exist)
+	projectDir := filepath.Join(projectsDir, "-nonexistent-path")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	nonexistentPath := filepath.Join(tmpDir, "this-path-does-not-exist-anywhere")
+	sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(nonexistentPath) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	// Create an orphan todo
+	require.NoError(t, os.WriteFile(filepath.Join(todosDir, "orphan-agent-xyz.json"), []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	stdin := strings.NewReader("")
+
+	code := runCLI([]string{"clean", "--dry-run"}, stdin, &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	expectedSize := int64(len(sessionData) + len(`{}`))
+	assert.Contains(t, stdout.String(), "Would free "+ui.FormatSize(expectedSize)+" across 2 items")
+}
+
+func TestRunCLI_CleanSubcommandDryRunHasNoReclaimSummary(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+
+	projectDir := filepath.Join(projectsDir, "-nonexistent-path")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	nonexistentPath := filepath.Join(tmpDir, "this-path-does-not-exist-anywhere")
+	sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(nonexistentPath) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	stdin := strings.NewReader("")
+
+	code := runCLI([]string{"clean", "projects", "--dry-run"}, stdin, &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.NotContains(t, stdout.String(), "Would free")
+}
+
+func TestParseArgs_ListDuplicates(t *testing.T) {
+	args, err := parseArgs([]string{"list", "duplicates"})
+	require.NoError(t, err)
+	assert.Equal(t, "list", args.Command)
+	assert.Equal(t, "duplicates", args.Subcommand)
+}
+
+func TestRunCLI_ListDuplicates(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+
+	// Same session ID recorded under two project directories (e.g. after a cwd rename)
+	for _, name := range []string{"-old-name", "-new-name"} {
+		projectDir := filepath.Join(projectsDir, name)
+		require.NoError(t, os.MkdirAll(projectDir, 0755))
+		sessionData := `{"sessionId":"shared-sess","cwd":"` + filepath.ToSlash(tmpDir) + `","timestamp":"2025-01-01T00:00:00Z"}`
+		require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	stdin := strings.NewReader("")
+
+	code := runCLI([]string{"list", "duplicates"}, stdin, &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	output := stdout.String()
+	assert.Contains(t, output, "shared-sess")
+	assert.Contains(t, output, "-old-name")
+	assert.Contains(t, output, "-new-name")
+	assert.Contains(t, output, "Total: 1 duplicate sessions")
+}
+
+func TestParseArgs_FormatTable(t *testing.T) {
+	args, err := parseArgs([]string{"list", "projects", "--format", "table"})
+	require.NoError(t, err)
+	assert.Equal(t, "table", args.Format)
+
+	args, err = parseArgs([]string{"list", "projects", "--format=table"})
+	require.NoError(t, err)
+	assert.Equal(t, "table", args.Format)
+}
+
+func TestParseArgs_InlineValueOnBooleanFlag(t *testing.T) {
+	_, err := parseArgs([]string{"clean", "--yes=false"})
+	assert.ErrorContains(t, err, "flag --yes does not take a value")
+
+	_, err = parseArgs([]string{"clean", "--dry-run=false"})
+	assert.ErrorContains(t, err, "flag --dry-run does not take a value")
+}
+
+func TestParseArgs_FormatInvalid(t *testing.T) {
+	_, err := parseArgs([]string{"list", "projects", "--format", "xml"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown format")
+
+	_, err = parseArgs([]string{"list", "projects", "--format"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "requires a value")
+}
+
+func TestRunCLI_ListProjectsTable(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+
+	projectDir := filepath.Join(projectsDir, "-test-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	existingDir := filepath.Join(tmpDir, "existing-project")
+	require.NoError(t, os.MkdirAll(existingDir, 0755))
+	sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(existingDir) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	// Project with only an empty session has its path guessed from the name
+	unknownDir := filepath.Join(projectsDir, "-unknown-project")
+	require.NoError(t, os.MkdirAll(unknownDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(unknownDir, "empty.jsonl"), []byte{}, 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	stdin := strings.NewReader("")
+
+	code := runCLI([]string{"list", "projects", "--format", "table"}, stdin, &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	output := stdout.String()
+	assert.Contains(t, output, "STATUS")
+	assert.Contains(t, output, "LAST USED")
+	assert.Contains(t, output, "/unknown/project (guessed)")
+	assert.NotContains(t, output, "Projects:")
+}
+
+func TestTruncatePath(t *testing.T) {
+	assert.Equal(t, "/short", truncatePath("/short", 20))
+	assert.Equal(t, ".../c/project", truncatePath("/a/very/long/path/to/c/project", 13))
+}
+
+func TestRunCLI_CleanProjectsNoTTY(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+
+	projectDir := filepath.Join(projectsDir, "-nonexistent-path")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	nonexistentPath := filepath.Join(tmpDir, "this-path-does-not-exist-anywhere")
+	sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(nonexistentPath) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	// Simulate a cron job: stdin is a regular file, not a terminal
+	stdinPath := filepath.Join(tmpDir, "stdin")
+	require.NoError(t, os.WriteFile(stdinPath, nil, 0644))
+	stdin, err := os.Open(stdinPath)
+	require.NoError(t, err)
+	defer stdin.Close()
+
+	var stdout, stderr bytes.Buffer
+
+	code := runCLI([]string{"clean", "projects"}, stdin, &stdout, &stderr)
+
+	assert.Equal(t, exitNoTTY, code)
+	assert.Contains(t, stderr.String(), "No TTY and --yes not given")
+	assert.NotContains(t, stdout.String(), "No TTY")
+	assert.DirExists(t, projectDir)
+}
+
+func TestRunCLI_ListProjectsCSV(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+
+	projectDir := filepath.Join(projectsDir, "-test-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	// A comma in the path must be quoted in the CSV output
+	existingDir := filepath.Join(tmpDir, "my,project")
+	require.NoError(t, os.MkdirAll(existingDir, 0755))
+	sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(existingDir) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	stdin := strings.NewReader("")
+
+	code := runCLI([]string{"list", "projects", "--format", "csv"}, stdin, &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	records, err := csv.NewReader(&stdout).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, []string{"encoded_name", "actual_path", "file_count", "total_size_bytes", "last_used", "stale"}, records[0])
+	assert.Equal(t, []string{"-test-project", existingDir, "1", strconv.Itoa(len(sessionData)), "2025-01-01T00:00:00Z", "false"}, records[1])
+}
+
+func TestRunCLI_ListProjectsCSVEmpty(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, ".claude", "projects"), 0755))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	stdin := strings.NewReader("")
+
+	code := runCLI([]string{"list", "projects", "--format", "csv"}, stdin, &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "encoded_name,actual_path,file_count,total_size_bytes,last_used,stale\n", stdout.String())
+}
+
+func TestRunCLI_ListConfigRecursive(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+	require.NoError(t, os.MkdirAll(projectsDir, 0755))
+
+	globalSettings := `{"permissions":{"allow":["Bash(git:*)"]}}`
+	require.NoError(t, os.WriteFile(filepath.Join(claudeDir, "settings.json"), []byte(globalSettings), 0644))
+
+	// Only a nested package has a local config duplicating global
+	projectDir := filepath.Join(tmpDir, "monorepo")
+	pkgClaudeDir := filepath.Join(projectDir, "packages", "web", ".claude")
+	require.NoError(t, os.MkdirAll(pkgClaudeDir, 0755))
+	localSettings := `{"permissions":{"allow":["Bash(git:*)","Bash(npm:*)"]}}`
+	require.NoError(t, os.WriteFile(filepath.Join(pkgClaudeDir, "settings.local.json"), []byte(localSettings), 0644))
+
+	encodedProjectDir := filepath.Join(projectsDir, "-monorepo")
+	require.NoError(t, os.MkdirAll(encodedProjectDir, 0755))
+	sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(projectDir) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(encodedProjectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "config"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "No local configs found")
+
+	stdout.Reset()
+	code = runCLI([]string{"list", "config", "--recursive"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), filepath.Join("packages", "web", ".claude", "settings.local.json"))
+}
+
+func TestRunCLI_UnavailableProjectNotCleaned(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+
+	// Project on a network drive that is currently not mounted
+	projectDir := filepath.Join(projectsDir, "-Volumes-share-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	sessionData := `{"sessionId":"sess1","cwd":"/Volumes/ccc-test-unmounted-share/project","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "projects"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "[UNAVAILABLE]")
+	assert.Contains(t, stdout.String(), "(0 stale, 1 unavailable)")
+
+	stdout.Reset()
+	code = runCLI([]string{"clean", "projects", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "No stale projects found")
+	assert.DirExists(t, projectDir)
+
+	stdout.Reset()
+	code = runCLI([]string{"clean", "projects", "--yes", "--include-unavailable"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.NoDirExists(t, projectDir)
+}
+
+func TestParseArgs_AuditFormat(t *testing.T) {
+	args, err := parseArgs([]string{"clean", "--audit-format", "jsonl"})
+	require.NoError(t, err)
+	assert.Equal(t, ui.AuditFormatJSONL, args.AuditFormat)
+
+	_, err = parseArgs([]string{"clean", "--audit-format", "xml"})
+	assert.Error(t, err)
+}
+
+func TestRunCLI_CleanProjectsJSONLAudit(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+
+	projectDir := filepath.Join(projectsDir, "-nonexistent-path")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	nonexistentPath := filepath.Join(tmpDir, "this-path-does-not-exist-anywhere")
+	sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(nonexistentPath) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "projects", "--yes", "--audit-format", "jsonl"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code)
+
+	content, err := os.ReadFile(ui.DefaultAuditLogPath(claudeDir))
+	require.NoError(t, err)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(bytes.TrimSpace(content), &entry))
+	assert.Equal(t, "DELETE", entry["action"])
+	assert.Equal(t, nonexistentPath, entry["path"])
+	assert.Equal(t, float64(len(sessionData)), entry["size"])
+}
+
+func TestRunCLI_CleanProjectsProgress(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+
+	projectDir := filepath.Join(projectsDir, "-nonexistent-path")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	nonexistentPath := filepath.Join(tmpDir, "this-path-does-not-exist-anywhere")
+	sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(nonexistentPath) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "projects", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stderr.String(), "Cleaning 1/1: "+nonexistentPath)
+	assert.NotContains(t, stdout.String(), "Cleaning 1/1")
+}
+
+func TestRunCLI_CleanOrphansQuiet(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	orphanTodo := filepath.Join(todosDir, "orphan-agent-xyz.json")
+	require.NoError(t, os.WriteFile(orphanTodo, []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "orphans", "--yes", "--quiet"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.NoFileExists(t, orphanTodo)
+	assert.Empty(t, stdout.String())
+	assert.Empty(t, stderr.String())
+
+	// The audit log is still written
+	audit, err := os.ReadFile(filepath.Join(claudeDir, "cccc-audit.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(audit), orphanTodo)
+}
+
+func TestRunCLI_QuietWithoutYesKeepsPrompt(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	orphanTodo := filepath.Join(todosDir, "orphan-agent-xyz.json")
+	require.NoError(t, os.WriteFile(orphanTodo, []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "orphans", "--quiet"}, strings.NewReader("n\n"), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.FileExists(t, orphanTodo)
+	assert.Contains(t, stdout.String(), "Proceed? [y/N]")
+}
+
+func TestRunCLI_AssumeYesEnv(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	orphanTodo := filepath.Join(todosDir, "orphan-agent-xyz.json")
+	require.NoError(t, os.WriteFile(orphanTodo, []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	// Only "1" enables it
+	t.Setenv("CCC_ASSUME_YES", "true")
+	var stdout, stderr bytes.Buffer
+	runCLI([]string{"clean", "orphans"}, strings.NewReader("n\n"), &stdout, &stderr)
+	assert.FileExists(t, orphanTodo)
+	assert.Contains(t, stdout.String(), "Proceed? [y/N]")
+
+	t.Setenv("CCC_ASSUME_YES", "1")
+	stdout.Reset()
+	stderr.Reset()
+	code := runCLI([]string{"clean", "orphans"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.NoFileExists(t, orphanTodo)
+	assert.NotContains(t, stdout.String(), "Proceed?")
+	// Test readers count as interactive, so the variable is announced
+	assert.Contains(t, stderr.String(), "CCC_ASSUME_YES=1 is set")
+}
+
+func TestParseArgs_AssumeYesEnv(t *testing.T) {
+	t.Setenv("CCC_ASSUME_YES", "1")
+
+	tests := []struct {
+		name string
+		args []string
+		yes  bool
+	}{
+		{"applies", []string{"clean"}, true},
+		{"dry run", []string{"clean", "--dry-run"}, false},
+		{"select", []string{"clean", "projects", "--select"}, false},
+		{"input from stdin", []string{"clean", "projects", "--input", "-"}, true},
+		{"watch clean", []string{"watch", "--clean"}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			args, err := parseArgs(tc.args)
+			require.NoError(t, err)
+			assert.Equal(t, tc.yes, args.Yes)
+			assert.Equal(t, tc.yes, args.YesFromEnv)
+		})
+	}
+
+	_, err := parseArgs([]string{"clean", "projects", "--assume-missing", "/mnt/old"})
+	assert.ErrorContains(t, err, "--confirm-assume-missing")
+
+	args, err := parseArgs([]string{"clean", "--yes"})
+	require.NoError(t, err)
+	assert.False(t, args.YesFromEnv)
+}
+
+func TestRunCLI_ListCorrupt(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, ".claude", "projects", "-test-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	corruptFile := filepath.Join(projectDir, "corrupt.jsonl")
+	require.NoError(t, os.WriteFile(corruptFile, []byte("not json\n"), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "corrupt"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), corruptFile)
+	assert.Contains(t, stdout.String(), "line 1:")
+	assert.Contains(t, stdout.String(), "Total: 1 corrupt session files")
+}
+
+func TestParseArgs_ListSessions(t *testing.T) {
+	args, err := parseArgs([]string{"list", "sessions", "--top", "5"})
+	require.NoError(t, err)
+	assert.Equal(t, "sessions", args.Subcommand)
+	assert.Equal(t, 5, args.Top)
+
+	// After orphans, sessions is still the orphan kind
+	args, err = parseArgs([]string{"list", "orphans", "sessions"})
+	require.NoError(t, err)
+	assert.Equal(t, "orphans", args.Subcommand)
+	assert.Equal(t, "sessions", args.OrphanKind)
+
+	_, err = parseArgs([]string{"clean", "sessions"})
+	assert.ErrorContains(t, err, "only valid after orphans")
+
+	_, err = parseArgs([]string{"list", "sessions", "--top", "0"})
+	assert.ErrorContains(t, err, "invalid --top")
+
+	_, err = parseArgs([]string{"list", "projects", "--top", "5"})
+	assert.ErrorContains(t, err, "--top is only supported by list sessions")
+}
+
+func TestRunCLI_ListSessions(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectsDir := filepath.Join(tmpDir, ".claude", "projects")
+	projectDir := filepath.Join(projectsDir, "-work-app")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	line := `{"sessionId":"s1","cwd":"/work/app","timestamp":"2025-01-01T00:00:00Z"}` + "\n"
+	small := filepath.Join(projectDir, "small.jsonl")
+	large := filepath.Join(projectDir, "large.jsonl")
+	require.NoError(t, os.WriteFile(small, []byte(line), 0644))
+	require.NoError(t, os.WriteFile(large, []byte(strings.Repeat(line, 40)), 0644))
+	orphanDir := filepath.Join(projectsDir, "-gone-tool")
+	require.NoError(t, os.MkdirAll(orphanDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(orphanDir, "empty.jsonl"), nil, 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "sessions", "--top", "2"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	out := stdout.String()
+	assert.Contains(t, out, "  1. "+large+"\n        /work/app, 2.8 KB, modified ")
+	assert.Contains(t, out, "  2. "+small+"\n")
+	assert.NotContains(t, out, "empty.jsonl")
+	assert.Contains(t, out, "Total: 2 of 3 session files")
+
+	stdout.Reset()
+	code = runCLI([]string{"list", "sessions", "--top", "1", "--json"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	var envelope struct {
+		Items []sessionFileJSON `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &envelope))
+	require.Len(t, envelope.Items, 1)
+	assert.Equal(t, large, envelope.Items[0].Path)
+	assert.Equal(t, "-work-app", envelope.Items[0].Project)
+	assert.Equal(t, "/work/app", envelope.Items[0].ProjectPath)
+	assert.Equal(t, int64(40*len(line)), envelope.Items[0].Size)
+}
+
+func TestRunCLI_ListProjectsJSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, ".claude", "projects", "-test-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	sessionData := `{"sessionId":"sess1","cwd":"/nonexistent/project","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	origNow := now
+	now = func() time.Time { return time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = origNow }()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "projects", "--json"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	var envelope struct {
+		Schema      int           `json:"schema"`
+		GeneratedAt string        `json:"generated_at"`
+		Items       []projectJSON `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &envelope))
+	assert.Equal(t, jsonSchemaVersion, envelope.Schema)
+	assert.Equal(t, "2025-06-01T12:00:00Z", envelope.GeneratedAt)
+	require.Len(t, envelope.Items, 1)
+	assert.Equal(t, "-test-project", envelope.Items[0].EncodedName)
+	assert.Equal(t, "STALE", envelope.Items[0].Status)
+	assert.Equal(t, []string{"sess1"}, envelope.Items[0].SessionIDs)
+}
+
+func TestRunCLI_ListOrphansJSONEmpty(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, ".claude", "projects"), 0755))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "orphans", "--json"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	var envelope map[string]any
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &envelope))
+	assert.Equal(t, float64(jsonSchemaVersion), envelope["schema"])
+	assert.Equal(t, []any{}, envelope["items"])
+}
+
+func TestRunCLI_CleanOrphansProject(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+
+	// Target project has one live and one empty session
+	targetPath := filepath.Join(tmpDir, "target")
+	require.NoError(t, os.MkdirAll(targetPath, 0755))
+	targetDir := filepath.Join(projectsDir, "-target")
+	require.NoError(t, os.MkdirAll(targetDir, 0755))
+	sessionData := `{"sessionId":"live","cwd":"` + filepath.ToSlash(targetPath) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "live.jsonl"), []byte(sessionData), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "dead.jsonl"), []byte{}, 0644))
+
+	targetTodo := filepath.Join(todosDir, "dead-agent-a.json")
+	unrelatedTodo := filepath.Join(todosDir, "unrelated-agent-a.json")
+	require.NoError(t, os.WriteFile(targetTodo, []byte(`{}`), 0644))
+	require.NoError(t, os.WriteFile(unrelatedTodo, []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "orphans", "--project", targetPath, "--yes"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.NoFileExists(t, targetTodo)
+	assert.NoFileExists(t, filepath.Join(targetDir, "dead.jsonl"))
+	assert.FileExists(t, unrelatedTodo)
+	assert.FileExists(t, filepath.Join(targetDir, "live.jsonl"))
+}
+
+func TestRunCLI_CleanOrphansUnknownProject(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, ".claude", "projects"), 0755))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "orphans", "--project", "/nonexistent/project"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "no project found")
+}
+
+func TestRunCLI_ListConfigDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+	require.NoError(t, os.MkdirAll(projectsDir, 0755))
+
+	globalSettings := `{"permissions":{"allow":["Bash(git:*)"]}}`
+	require.NoError(t, os.WriteFile(filepath.Join(claudeDir, "settings.json"), []byte(globalSettings), 0644))
+
+	projectDir := filepath.Join(tmpDir, "project")
+	localPath := filepath.Join(projectDir, ".claude", "settings.local.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(localPath), 0755))
+	localSettings := "{\"permissions\":{\"allow\":[\n\"Bash(git:*)\",\n\"Bash(npm:*)\"\n]}}\n"
+	require.NoError(t, os.WriteFile(localPath, []byte(localSettings), 0644))
+
+	encodedProjectDir := filepath.Join(projectsDir, "-project")
+	require.NoError(t, os.MkdirAll(encodedProjectDir, 0755))
+	sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(projectDir) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(encodedProjectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "config", "--diff"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "--- a/"+localPath)
+	assert.Contains(t, stdout.String(), "-\"Bash(git:*)\",\n")
+
+	// Listing with --diff must not modify the file
+	data, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	assert.Equal(t, localSettings, string(data))
+}
+
+func TestRunCLI_YesToModify(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+	require.NoError(t, os.MkdirAll(projectsDir, 0755))
+
+	globalSettings := `{"permissions":{"allow":["Bash(git:*)"]}}`
+	require.NoError(t, os.WriteFile(filepath.Join(claudeDir, "settings.json"), []byte(globalSettings), 0644))
+
+	projectDir := filepath.Join(tmpDir, "project")
+	localPath := filepath.Join(projectDir, ".claude", "settings.local.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(localPath), 0755))
+	require.NoError(t, os.WriteFile(localPath, []byte(`{"permissions":{"allow":["Bash(git:*)","Bash(npm:*)"]}}`), 0644))
+
+	encodedProjectDir := filepath.Join(projectsDir, "-project")
+	require.NoError(t, os.MkdirAll(encodedProjectDir, 0755))
+	sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(projectDir) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(encodedProjectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	// A stale project whose deletion must still be confirmed
+	staleDir := filepath.Join(projectsDir, "-stale")
+	require.NoError(t, os.MkdirAll(staleDir, 0755))
+	staleData := `{"sessionId":"sess2","cwd":"/nonexistent/stale","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(staleDir, "session.jsonl"), []byte(staleData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "config", "--yes-to-modify"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.NotContains(t, stdout.String(), "Proceed?")
+	data, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	assert.Equal(t, `{"permissions":{"allow":["Bash(npm:*)"]}}`, string(data))
+
+	stdout.Reset()
+	code = runCLI([]string{"clean", "projects", "--yes-to-modify"}, strings.NewReader("n\n"), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "Proceed?")
+	assert.DirExists(t, staleDir)
+}
+
+func TestRunCLI_EmptyProjectDirCleanedAsOrphan(t *testing.T) {
+	tmpDir := t.TempDir()
+	emptyProject := filepath.Join(tmpDir, ".claude", "projects", "-leftover")
+	require.NoError(t, os.MkdirAll(emptyProject, 0755))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "projects", "--dry-run"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "No stale projects found.")
+
+	stdout.Reset()
+	code = runCLI([]string{"clean", "orphans", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Empty project directory")
+	assert.NoDirExists(t, emptyProject)
+}
+
+func TestRunCLI_ProjectDirWithoutSessionsKept(t *testing.T) {
+	tmpDir := t.TempDir()
+	leftover := filepath.Join(tmpDir, ".claude", "projects", "-leftover")
+	require.NoError(t, os.MkdirAll(filepath.Join(leftover, "subagents"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(leftover, "subagents", "agent.jsonl"), []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.FileExists(t, filepath.Join(leftover, "subagents", "agent.jsonl"))
+}
+
+func TestParseArgs_Timeout(t *testing.T) {
+	args, err := parseArgs([]string{"list", "--timeout", "30s"})
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, args.Timeout)
+
+	_, err = parseArgs([]string{"list", "--timeout", "soon"})
+	assert.Error(t, err)
+}
+
+func TestRunCLI_TimeoutAbortsScan(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, ".claude", "projects", "-test-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	sessionData := `{"sessionId":"sess1","cwd":"/nonexistent/project","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "projects", "--yes", "--timeout", "1ns"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "timed out")
+	assert.DirExists(t, projectDir)
+}
+
+// slowReader answers every prompt with line, but only after a delay, like
+// a user taking time to confirm.
+type slowReader struct {
+	delay time.Duration
+	line  string
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return copy(p, s.line), nil
+}
+
+func TestRunCLI_TimeoutDoesNotCoverPrompt(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, ".claude", "projects", "-test-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	sessionData := `{"sessionId":"sess1","cwd":"/nonexistent/project","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+	todo := filepath.Join(tmpDir, ".claude", "todos", "gone-agent-x.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(todo), 0755))
+	require.NoError(t, os.WriteFile(todo, []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	// The orphans are scanned after the projects were confirmed and removed
+	var stdout, stderr bytes.Buffer
+	stdin := &slowReader{delay: 300 * time.Millisecond, line: "y\n"}
+	code := runCLI([]string{"clean", "--timeout", "100ms"}, stdin, &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.NotContains(t, stderr.String(), "timed out")
+	assert.NoDirExists(t, projectDir)
+	assert.NoFileExists(t, todo)
+}
+
+func TestRunCLI_IncludeGlobalLocal(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+
+	globalSettings := `{"permissions":{"allow":["Bash(git:*)"]}}`
+	require.NoError(t, os.WriteFile(filepath.Join(claudeDir, "settings.json"), []byte(globalSettings), 0644))
+	homeLocal := filepath.Join(claudeDir, "settings.local.json")
+	require.NoError(t, os.WriteFile(homeLocal, []byte(`{"permissions":{"allow":["Bash(git:*)","Bash(npm:*)"]}}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	// Excluded by default
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "config", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "No local configs found.")
+
+	stdout.Reset()
+	code = runCLI([]string{"clean", "config", "--yes", "--include-global-local"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Deduplicated 1 config files, removed 1 duplicate entries\n")
+
+	data, err := os.ReadFile(homeLocal)
+	require.NoError(t, err)
+	assert.Equal(t, `{"permissions":{"allow":["Bash(npm:*)"]}}`, string(data))
+
+	// The global settings file itself is never touched
+	data, err = os.ReadFile(filepath.Join(claudeDir, "settings.json"))
+	require.NoError(t, err)
+	assert.Equal(t, globalSettings, string(data))
+}
+
+func TestRunCLI_ProjectCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectDir := filepath.Join(claudeDir, "projects", "-test-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	sessionData := `{"sessionId":"sess1","cwd":"/nonexistent/project","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+	cachePath := filepath.Join(claudeDir, "cccc-cache.json")
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "projects", "--no-cache"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.NoFileExists(t, cachePath)
+
+	code = runCLI([]string{"list", "projects"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.FileExists(t, cachePath)
+
+	stdout.Reset()
+	code = runCLI([]string{"cache", "clear"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "Cache cleared.")
+	assert.NoFileExists(t, cachePath)
+}
+
+func TestRunCLI_CleanOrphansUnknownTodos(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	unknownTodo := filepath.Join(todosDir, "notes.txt")
+	require.NoError(t, os.WriteFile(unknownTodo, []byte("x"), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "orphans", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "Skipping 1 unrecognized todo files")
+	assert.FileExists(t, unknownTodo)
+
+	stdout.Reset()
+	code = runCLI([]string{"clean", "orphans", "--yes", "--include-unknown"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Unrecognized todo file")
+	assert.NoFileExists(t, unknownTodo)
+}
+
+func TestParseArgs_TodoPattern(t *testing.T) {
+	args, err := parseArgs([]string{"clean", "orphans", "--todo-pattern", `^todo_(.+)\.json$`})
+	require.NoError(t, err)
+	require.NotNil(t, args.TodoPattern)
+
+	_, err = parseArgs([]string{"clean", "orphans", "--todo-pattern", `^todo_.+$`})
+	assert.Error(t, err)
+}
+
+func TestParseArgs_OrphanKind(t *testing.T) {
+	args, err := parseArgs([]string{"clean", "orphans", "file-history"})
+	require.NoError(t, err)
+	assert.Equal(t, "orphans", args.Subcommand)
+	assert.Equal(t, "file-history", args.OrphanKind)
+
+	_, err = parseArgs([]string{"clean", "projects", "todos"})
+	assert.Error(t, err)
+}
+
+func TestRunCLI_CleanOrphansSingleKind(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	orphanTodo := filepath.Join(claudeDir, "todos", "gone-agent-a.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(orphanTodo), 0755))
+	require.NoError(t, os.WriteFile(orphanTodo, []byte(`{}`), 0644))
+	orphanHistory := filepath.Join(claudeDir, "file-history", "gone")
+	require.NoError(t, os.MkdirAll(orphanHistory, 0755))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "orphans", "todos", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.NoFileExists(t, orphanTodo)
+	assert.DirExists(t, orphanHistory)
+}
+
+func TestRunCLI_ListProjectsLastUsed(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectsDir := filepath.Join(tmpDir, ".claude", "projects")
+
+	projectDir := filepath.Join(projectsDir, "-test-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	sessionData := `{"sessionId":"sess1","cwd":"/nonexistent/project","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	// Project with only an empty session was never used
+	unusedDir := filepath.Join(projectsDir, "-unused")
+	require.NoError(t, os.MkdirAll(unusedDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(unusedDir, "empty.jsonl"), []byte{}, 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "projects"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "ago")
+	assert.Contains(t, stdout.String(), "last used: never")
+	assert.NotContains(t, stdout.String(), "0001-01-01")
+
+	stdout.Reset()
+	code = runCLI([]string{"list", "projects", "--absolute-time"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "last used: 2025-01-01")
+	assert.Contains(t, stdout.String(), "last used: never")
+}
+
+func TestRunCLI_CleanAllIncludesTodosOfStaleProjects(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	staleDir := filepath.Join(claudeDir, "projects", "-stale")
+	require.NoError(t, os.MkdirAll(staleDir, 0755))
+	sessionData := `{"sessionId":"stale-sess","cwd":"/nonexistent/stale","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(staleDir, "stale-sess.jsonl"), []byte(sessionData), 0644))
+	todo := filepath.Join(claudeDir, "todos", "stale-sess-agent-a.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(todo), 0755))
+	require.NoError(t, os.WriteFile(todo, []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	// The dry run already counts the todo that removing the project orphans
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "--dry-run"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), todo)
+
+	stdout.Reset()
+	code = runCLI([]string{"clean", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.NoDirExists(t, staleDir)
+	assert.NoFileExists(t, todo)
+
+	// A standalone orphan clean keeps todos of stale projects that still exist
+	require.NoError(t, os.MkdirAll(staleDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(staleDir, "stale-sess.jsonl"), []byte(sessionData), 0644))
+	require.NoError(t, os.WriteFile(todo, []byte(`{}`), 0644))
+	code = runCLI([]string{"clean", "orphans", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.FileExists(t, todo)
+}
+
+func TestRunCLI_CleanOrphansSummaryOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+
+	orphanTodo := filepath.Join(todosDir, "orphan-agent-xyz.json")
+	require.NoError(t, os.WriteFile(orphanTodo, []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "orphans", "--summary-only", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "[DELETE] 1 item")
+	assert.NotContains(t, stdout.String(), orphanTodo)
+	assert.NoFileExists(t, orphanTodo)
+}
+
+func TestRunCLI_ListProjectsGuessesUnknownPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectsDir := filepath.Join(tmpDir, ".claude", "projects")
+	unknownDir := filepath.Join(projectsDir, "C--Users-me-Code")
+	require.NoError(t, os.MkdirAll(unknownDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(unknownDir, "empty.jsonl"), []byte{}, 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "projects"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), `C:\Users\me\Code (guessed from directory name)`)
+}
+
+func TestRunCLI_ListProjectsJSONStream(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectsDir := filepath.Join(tmpDir, ".claude", "projects")
+	existingDir := filepath.Join(tmpDir, "existing")
+	require.NoError(t, os.MkdirAll(existingDir, 0755))
+	for name, cwd := range map[string]string{"-stale": "/nonexistent/stale", "-active": filepath.ToSlash(existingDir)} {
+		dir := filepath.Join(projectsDir, name)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		sessionData := `{"sessionId":"s` + name + `","cwd":"` + cwd + `","timestamp":"2025-01-01T00:00:00Z"}`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "session.jsonl"), []byte(sessionData), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "projects", "--json-stream"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	require.Len(t, lines, 2)
+	for _, line := range lines {
+		var item projectJSON
+		require.NoError(t, json.Unmarshal([]byte(line), &item))
+		assert.NotEmpty(t, item.EncodedName)
+	}
+
+	stdout.Reset()
+	code = runCLI([]string{"list", "projects", "--json-stream", "--stale-only"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	var item projectJSON
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &item))
+	assert.Equal(t, "-stale", item.EncodedName)
+}
+
+func TestRunCLI_JSONStreamOnlyForProjects(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, ".claude", "projects"), 0755))
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "orphans", "--json-stream"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "--json-stream is only supported by list projects")
+}
+
+func TestParseArgs_Prune(t *testing.T) {
+	args, err := parseArgs([]string{"prune", "--older-than", "90d", "--keep-latest", "3"})
+	require.NoError(t, err)
+	assert.Equal(t, "prune", args.Command)
+	assert.Equal(t, 90*24*time.Hour, args.OlderThan)
+	assert.Equal(t, 3, args.KeepLatest)
+
+	args, err = parseArgs([]string{"prune", "--older-than=48h"})
+	require.NoError(t, err)
+	assert.Equal(t, 48*time.Hour, args.OlderThan)
+
+	_, err = parseArgs([]string{"prune"})
+	assert.ErrorContains(t, err, "prune requires --older-than or --newer-than")
+
+	_, err = parseArgs([]string{"prune", "--older-than", "soon"})
+	assert.ErrorContains(t, err, "invalid age")
+
+	_, err = parseArgs([]string{"prune", "--older-than", "1d", "--keep-latest", "-1"})
+	assert.ErrorContains(t, err, "invalid --keep-latest")
+}
+
+func TestRunCLI_PruneKeepLatest(t *testing.T) {
+	tmpDir := t.TempDir()
+	existingDir := filepath.Join(tmpDir, "existing")
+	require.NoError(t, os.MkdirAll(existingDir, 0755))
+	projectDir := filepath.Join(tmpDir, ".claude", "projects", "-existing")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+
+	sessions := map[string]string{
+		"newest": "2025-01-03T00:00:00Z",
+		"middle": "2025-01-02T00:00:00Z",
+		"oldest": "2025-01-01T00:00:00Z",
+	}
+	for id, ts := range sessions {
+		data := `{"sessionId":"` + id + `","cwd":"` + filepath.ToSlash(existingDir) + `","timestamp":"` + ts + `"}`
+		require.NoError(t, os.WriteFile(filepath.Join(projectDir, id+".jsonl"), []byte(data), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"prune", "--older-than", "30d", "--keep-latest", "1", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Pruned 2 sessions")
+	assert.FileExists(t, filepath.Join(projectDir, "newest.jsonl"))
+	assert.NoFileExists(t, filepath.Join(projectDir, "middle.jsonl"))
+	assert.NoFileExists(t, filepath.Join(projectDir, "oldest.jsonl"))
+}
+
+func TestParseArgs_NewerThan(t *testing.T) {
+	args, err := parseArgs([]string{"prune", "--newer-than", "1d"})
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, args.NewerThan)
+
+	args, err = parseArgs([]string{"clean", "projects", "--older-than", "7d", "--newer-than", "30d"})
+	require.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour, args.OlderThan)
+	assert.Equal(t, 30*24*time.Hour, args.NewerThan)
+
+	_, err = parseArgs([]string{"prune", "--older-than", "30d", "--newer-than", "7d"})
+	assert.ErrorContains(t, err, "--newer-than must be longer than --older-than")
+}
+
+func TestRunCLI_PruneAgeWindow(t *testing.T) {
+	origNow := now
+	now = func() time.Time { return time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = origNow }()
+
+	tmpDir := t.TempDir()
+	existingDir := filepath.Join(tmpDir, "existing")
+	require.NoError(t, os.MkdirAll(existingDir, 0755))
+	projectDir := filepath.Join(tmpDir, ".claude", "projects", "-existing")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+
+	sessions := map[string]string{
+		"today":    "2025-06-01T08:00:00Z",
+		"lastweek": "2025-05-25T00:00:00Z",
+		"lastyear": "2024-06-01T00:00:00Z",
+	}
+	for id, ts := range sessions {
+		data := `{"sessionId":"` + id + `","cwd":"` + filepath.ToSlash(existingDir) + `","timestamp":"` + ts + `"}`
+		require.NoError(t, os.WriteFile(filepath.Join(projectDir, id+".jsonl"), []byte(data), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"prune", "--older-than", "1d", "--newer-than", "30d", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Pruned 1 sessions")
+	assert.FileExists(t, filepath.Join(projectDir, "today.jsonl"))
+	assert.NoFileExists(t, filepath.Join(projectDir, "lastweek.jsonl"))
+	assert.FileExists(t, filepath.Join(projectDir, "lastyear.jsonl"))
+}
+
+func TestParseArgs_AgeFrom(t *testing.T) {
+	args, err := parseArgs([]string{"prune", "--older-than", "1d", "--age-from", "mtime"})
+	require.NoError(t, err)
+	assert.Equal(t, "mtime", args.AgeFrom)
+
+	args, err = parseArgs([]string{"prune", "--older-than", "1d", "--age-from=timestamp"})
+	require.NoError(t, err)
+	assert.Empty(t, args.AgeFrom)
+
+	_, err = parseArgs([]string{"prune", "--older-than", "1d", "--age-from", "ctime"})
+	assert.ErrorContains(t, err, "unknown age source")
+}
+
+func TestRunCLI_PruneAgeFromMtime(t *testing.T) {
+	tmpDir := t.TempDir()
+	existingDir := filepath.Join(tmpDir, "existing")
+	require.NoError(t, os.MkdirAll(existingDir, 0755))
+	projectDir := filepath.Join(tmpDir, ".claude", "projects", "-existing")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+
+	// The embedded timestamp claims the session is from the future, but the
+	// file has not been touched for a year
+	sessionPath := filepath.Join(projectDir, "bogus.jsonl")
+	data := `{"sessionId":"bogus","cwd":"` + filepath.ToSlash(existingDir) + `","timestamp":"2099-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(sessionPath, []byte(data), 0644))
+	old := time.Now().Add(-365 * 24 * time.Hour)
+	require.NoError(t, os.Chtimes(sessionPath, old, old))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"prune", "--older-than", "30d", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.FileExists(t, sessionPath)
+
+	stdout.Reset()
+	code = runCLI([]string{"prune", "--older-than", "30d", "--age-from", "mtime", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Pruned 1 sessions")
+	assert.NoFileExists(t, sessionPath)
+}
+
+func TestRunCLI_CleanAllPrintsTotalFreed(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	staleDir := filepath.Join(claudeDir, "projects", "-stale")
+	require.NoError(t, os.MkdirAll(staleDir, 0755))
+	sessionData := `{"sessionId":"stale-sess","cwd":"/nonexistent/stale","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(staleDir, "stale-sess.jsonl"), []byte(sessionData), 0644))
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(todosDir, "gone-agent-a.json"), []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	freed := ui.FormatSize(int64(len(sessionData) + len(`{}`)))
+	assert.Contains(t, stdout.String(), "Total freed: "+freed+" across projects, orphans, config (2 items)")
+}
+
+func TestParseArgs_AgentRequiresTodos(t *testing.T) {
+	args, err := parseArgs([]string{"clean", "orphans", "todos", "--agent", "abc"})
+	require.NoError(t, err)
+	assert.Equal(t, "abc", args.Agent)
+
+	_, err = parseArgs([]string{"clean", "orphans", "--agent", "abc"})
+	assert.ErrorContains(t, err, "--agent is only supported by orphans todos")
+}
+
+func TestRunCLI_CleanOrphanTodosByAgent(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	junk := filepath.Join(todosDir, "gone-agent-junk.json")
+	other := filepath.Join(todosDir, "gone-agent-other.json")
+	require.NoError(t, os.WriteFile(junk, []byte(`{}`), 0644))
+	require.NoError(t, os.WriteFile(other, []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "orphans", "todos", "--agent", "junk", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.NoFileExists(t, junk)
+	assert.FileExists(t, other)
+}
+
+func TestRunCLI_OutputFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	orphanTodo := filepath.Join(todosDir, "gone-agent-a.json")
+	require.NoError(t, os.WriteFile(orphanTodo, []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	// A dry run writes the plan only to the file
+	plan := filepath.Join(tmpDir, "plan.txt")
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "orphans", "--dry-run", "--output", plan}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Empty(t, stdout.String())
+	content, err := os.ReadFile(plan)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), orphanTodo)
+
+	// A real clean keeps the preview and prompt on screen as well
+	record := filepath.Join(tmpDir, "record.txt")
+	stdout.Reset()
+	code = runCLI([]string{"clean", "orphans", "-o", record}, strings.NewReader("y\n"), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Proceed?")
+	content, err = os.ReadFile(record)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), orphanTodo)
+	assert.NotContains(t, string(content), "Proceed?")
+	assert.NoFileExists(t, orphanTodo)
+}
+
+func TestRunCLI_OutputFileError(t *testing.T) {
+	tmpDir := t.TempDir()
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "--output", filepath.Join(tmpDir, "missing", "out.txt")}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "Error opening output file")
+}
+
+func TestRunCLI_ListConfigWarnsWithoutGlobalSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, ".claude", "projects"), 0755))
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "config"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stderr.String(), "Warning: no global settings found")
+}
+
+func TestParseArgs_AssumeMissingWithYes(t *testing.T) {
+	_, err := parseArgs([]string{"clean", "projects", "--assume-missing", "/mnt/old", "--yes"})
+	assert.ErrorContains(t, err, "--confirm-assume-missing")
+
+	args, err := parseArgs([]string{"clean", "projects", "--assume-missing", "/mnt/old", "--assume-missing", "/mnt/older", "--yes", "--confirm-assume-missing"})
+	require.NoError(t, err)
+	assert.Len(t, args.AssumeMissing, 2)
+}
+
+func TestRunCLI_CleanProjectsAssumeMissing(t *testing.T) {
+	tmpDir := t.TempDir()
+	existingDir := filepath.Join(tmpDir, "reused-mount", "project")
+	require.NoError(t, os.MkdirAll(existingDir, 0755))
+	projectDir := filepath.Join(tmpDir, ".claude", "projects", "-reused")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	sessionData := `{"sessionId":"s1","cwd":"` + filepath.ToSlash(existingDir) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "s1.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "projects", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "No stale projects found.")
+
+	stdout.Reset()
+	code = runCLI([]string{"clean", "projects", "--assume-missing", existingDir}, strings.NewReader("y\n"), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.NoDirExists(t, projectDir)
+	assert.DirExists(t, existingDir)
+}
+
+func TestReportScanWarnings(t *testing.T) {
+	warnings := []claude.ScanWarning{
+		{Path: "/p/-a", Err: os.ErrPermission},
+		{Path: "/p/-b", Err: os.ErrPermission},
+	}
+
+	var stderr bytes.Buffer
+	args := &Args{}
+	reportScanWarnings(args, &stderr, warnings)
+	reportScanWarnings(args, &stderr, warnings)
+	assert.Equal(t, "Warning: skipped 2 project directories due to read errors (use --verbose to list them)\n", stderr.String())
+
+	stderr.Reset()
+	reportScanWarnings(&Args{Verbose: true}, &stderr, warnings)
+	assert.Contains(t, stderr.String(), "due to read errors:\n")
+	assert.Contains(t, stderr.String(), "  /p/-a: permission denied\n")
+
+	stderr.Reset()
+	reportScanWarnings(&Args{}, &stderr, nil)
+	assert.Empty(t, stderr.String())
+}
+
+func TestRunCLI_Report(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	staleDir := filepath.Join(claudeDir, "projects", "-stale")
+	require.NoError(t, os.MkdirAll(staleDir, 0755))
+	sessionData := `{"sessionId":"stale-sess","cwd":"/nonexistent/stale","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(staleDir, "stale-sess.jsonl"), []byte(sessionData), 0644))
+	todo := filepath.Join(claudeDir, "todos", "stale-sess-agent-a.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(todo), 0755))
+	require.NoError(t, os.WriteFile(todo, []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	plan := filepath.Join(tmpDir, "plan.md")
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"report", "--output", plan}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	content, err := os.ReadFile(plan)
+	require.NoError(t, err)
+	report := string(content)
+	assert.Contains(t, report, "# Cleanup Plan")
+	assert.Contains(t, report, "## Stale Project Cleanup")
+	assert.Contains(t, report, "`/nonexistent/stale`")
+	assert.Contains(t, report, "`"+todo+"`")
+	assert.Contains(t, report, "## Config Deduplication")
+	assert.Contains(t, report, "| **Total** | **2** |")
+
+	// Nothing is changed
+	assert.DirExists(t, staleDir)
+	assert.FileExists(t, todo)
+}
+
+func TestRunCLI_ReportGroupByDisk(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	staleDir := filepath.Join(claudeDir, "projects", "-stale")
+	require.NoError(t, os.MkdirAll(staleDir, 0755))
+	sessionData := `{"sessionId":"stale-sess","cwd":"/nonexistent/stale","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(staleDir, "stale-sess.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"report", "--group-by-disk"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "## Reclaimable by Disk")
+	// The stale project's data is on the disk of the Claude home, not of its cwd
+	assert.Regexp(t, "\\| `[^`]+` \\| 1 \\| "+strconv.Itoa(len(sessionData))+" B \\|", stdout.String())
+
+	_, err := parseArgs([]string{"clean", "--group-by-disk"})
+	assert.ErrorContains(t, err, "--group-by-disk is only supported by report")
+}
+
+func TestParseArgs_IgnoreCaseRequiresNormalize(t *testing.T) {
+	_, err := parseArgs([]string{"list", "config", "--ignore-case"})
+	assert.ErrorContains(t, err, "--ignore-case requires --normalize")
+
+	args, err := parseArgs([]string{"list", "config", "--normalize", "--ignore-case"})
+	require.NoError(t, err)
+	assert.True(t, args.Normalize)
+	assert.True(t, args.IgnoreCase)
+}
+
+func TestRunCLI_ConfigConsolidate(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+	require.NoError(t, os.MkdirAll(projectsDir, 0755))
+
+	globalPath := filepath.Join(claudeDir, "settings.json")
+	require.NoError(t, os.WriteFile(globalPath, []byte(`{"permissions":{"allow":["Read(**)"]}}`), 0644))
+
+	// Two projects share Bash(git:*); only the first also has Bash(npm:*)
+	locals := map[string]string{
+		"proj-a": `{"permissions":{"allow":["Bash(git:*)","Bash(npm:*)"]}}`,
+		"proj-b": `{"permissions":{"allow":["Bash(git:*)"]}}`,
+	}
+	for name, content := range locals {
+		projectDir := filepath.Join(tmpDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Join(projectDir, ".claude"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(projectDir, ".claude", "settings.local.json"), []byte(content), 0644))
+
+		encodedDir := filepath.Join(projectsDir, "-"+name)
+		require.NoError(t, os.MkdirAll(encodedDir, 0755))
+		sessionData := `{"sessionId":"` + name + `","cwd":"` + filepath.ToSlash(projectDir) + `","timestamp":"2025-01-01T00:00:00Z"}`
+		require.NoError(t, os.WriteFile(filepath.Join(encodedDir, "session.jsonl"), []byte(sessionData), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"config", "consolidate", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Moved 1 permission entries into global settings, updated 2 local configs")
+
+	global, err := claude.LoadSettings(globalPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Read(**)", "Bash(git:*)"}, global.Permissions.Allow)
+
+	local, err := claude.LoadSettings(filepath.Join(tmpDir, "proj-a", ".claude", "settings.local.json"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bash(npm:*)"}, local.Permissions.Allow)
+	assert.NoFileExists(t, filepath.Join(tmpDir, "proj-b", ".claude", "settings.local.json"))
+}
+
+func TestRunCLI_ConfigConsolidateNothingCommon(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, ".claude", "projects"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, ".claude", "settings.json"), []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"config", "consolidate", "--dry-run"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "at least 2 are needed")
+}
+
+func TestRunCLI_CleanProjectsExplain(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectsDir := filepath.Join(tmpDir, ".claude", "projects")
+
+	existingDir := filepath.Join(tmpDir, "existing")
+	require.NoError(t, os.MkdirAll(existingDir, 0755))
+	goneDir := filepath.Join(tmpDir, "gone")
+	for name, cwd := range map[string]string{"-existing": existingDir, "-gone": goneDir} {
+		projectDir := filepath.Join(projectsDir, name)
+		require.NoError(t, os.MkdirAll(projectDir, 0755))
+		sessionData := `{"sessionId":"` + name + `","cwd":"` + filepath.ToSlash(cwd) + `","timestamp":"2025-01-01T00:00:00Z"}`
+		require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "projects", "--explain"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	output := stdout.String()
+	assert.Contains(t, output, "stale: cwd "+goneDir+" does not exist")
+	assert.Contains(t, output, existingDir+"\n        kept: cwd exists")
+	assert.Contains(t, output, "[DRY RUN]")
+	assert.DirExists(t, filepath.Join(projectsDir, "-gone"))
+
+	stdout.Reset()
+	code = runCLI([]string{"list", "projects", "--explain"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "stale: cwd "+goneDir+" does not exist")
+
+	_, err := parseArgs([]string{"list", "projects", "--explain", "--json"})
+	assert.ErrorContains(t, err, "--explain is only supported by the default output format")
+}
+
+func TestRunCLI_ListProjectsWithoutProjectsDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, ".claude"), 0755))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "projects"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "No projects found.")
+}
+
+func TestParseArgs_Concurrency(t *testing.T) {
+	args, err := parseArgs([]string{"list", "--concurrency", "1"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, args.Concurrency)
+
+	_, err = parseArgs([]string{"list", "--concurrency", "0"})
+	assert.ErrorContains(t, err, "invalid --concurrency")
+}
+
+func TestParseArgs_GroupByEntry(t *testing.T) {
+	args, err := parseArgs([]string{"list", "config", "--group-by-entry"})
+	require.NoError(t, err)
+	assert.True(t, args.GroupBy)
+
+	_, err = parseArgs([]string{"clean", "config", "--group-by-entry"})
+	assert.ErrorContains(t, err, "--group-by-entry is only supported by list config")
+}
+
+func TestRunCLI_ListConfigGroupByEntry(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+	require.NoError(t, os.MkdirAll(projectsDir, 0755))
+
+	globalSettings := `{"permissions":{"allow":["Bash(git:*)"]}}`
+	require.NoError(t, os.WriteFile(filepath.Join(claudeDir, "settings.json"), []byte(globalSettings), 0644))
+
+	// Two projects whose local configs share the same duplicate entry
+	var localPaths []string
+	for _, name := range []string{"alpha", "beta"} {
+		projectDir := filepath.Join(tmpDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Join(projectDir, ".claude"), 0755))
+		localPath := filepath.Join(projectDir, ".claude", "settings.local.json")
+		require.NoError(t, os.WriteFile(localPath, []byte(`{"permissions":{"allow":["Bash(git:*)","Bash(`+name+`:*)"]}}`), 0644))
+		localPaths = append(localPaths, localPath)
+
+		encodedProjectDir := filepath.Join(projectsDir, "-"+name)
+		require.NoError(t, os.MkdirAll(encodedProjectDir, 0755))
+		sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(projectDir) + `","timestamp":"2025-01-01T00:00:00Z"}`
+		require.NoError(t, os.WriteFile(filepath.Join(encodedProjectDir, "session.jsonl"), []byte(sessionData), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "config", "--group-by-entry"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+
+	output := stdout.String()
+	assert.Contains(t, output, "Duplicated entries by local config:")
+	assert.Contains(t, output, "allow: Bash(git:*) (2 configs)")
+	for _, p := range localPaths {
+		assert.Contains(t, output, p)
+	}
+	assert.NotContains(t, output, "Bash(alpha:*)")
+	assert.NotContains(t, output, "Config Deduplication")
+}
+
+func TestRunCLI_AuditLogOverride(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectDir := filepath.Join(claudeDir, "projects", "-nonexistent-path")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(filepath.Join(tmpDir, "gone")) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	auditLog := filepath.Join(tmpDir, "backup", "logs", "audit.log")
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "projects", "--yes", "--audit-log", auditLog}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+
+	content, err := os.ReadFile(auditLog)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "DELETE")
+	assert.NoFileExists(t, ui.DefaultAuditLogPath(claudeDir))
+}
+
+func TestRunCLI_AuditLogParentNotDirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	// Parsing alone does not touch the file system
+	cliArgs := []string{"clean", "--yes", "--audit-log", filepath.Join(file, "audit.log")}
+	_, err := parseArgs(cliArgs)
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI(cliArgs, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "is not a directory")
+}
+
+func TestRunCLI_CleanProjectsVerifyMarker(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectsDir := filepath.Join(tmpDir, ".claude", "projects")
+
+	gitDir := filepath.Join(tmpDir, "repo")
+	require.NoError(t, os.MkdirAll(filepath.Join(gitDir, ".git"), 0755))
+	reusedDir := filepath.Join(tmpDir, "reused")
+	require.NoError(t, os.MkdirAll(reusedDir, 0755))
+	for name, cwd := range map[string]string{"-repo": gitDir, "-reused": reusedDir} {
+		projectDir := filepath.Join(projectsDir, name)
+		require.NoError(t, os.MkdirAll(projectDir, 0755))
+		sessionData := `{"sessionId":"` + name + `","cwd":"` + filepath.ToSlash(cwd) + `","timestamp":"2025-01-01T00:00:00Z"}`
+		require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "projects", "--verify-marker", ".git", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stderr.String(), "Warning: "+reusedDir+" exists but contains none of .git")
+	assert.NoDirExists(t, filepath.Join(projectsDir, "-reused"))
+	assert.DirExists(t, filepath.Join(projectsDir, "-repo"))
+}
+
+func TestMatchesOnly(t *testing.T) {
+	p := claude.Project{EncodedName: "-home-me-work-api", ActualPath: "/home/me/work/api"}
+
+	assert.True(t, matchesOnly("work/api", p))
+	assert.True(t, matchesOnly("-work-", p))
+	assert.True(t, matchesOnly("/home/*/work/*", p))
+	assert.False(t, matchesOnly("/home/*/play/*", p))
+	assert.False(t, matchesOnly("other", p))
+}
+
+func TestRunCLI_ListProjectsOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectsDir := filepath.Join(tmpDir, ".claude", "projects")
+	for _, name := range []string{"alpha", "beta"} {
+		projectDir := filepath.Join(projectsDir, "-"+name)
+		require.NoError(t, os.MkdirAll(projectDir, 0755))
+		sessionData := `{"sessionId":"` + name + `","cwd":"/nonexistent/` + name + `","timestamp":"2025-01-01T00:00:00Z"}`
+		require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "projects", "--only", "alp"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	output := stdout.String()
+	assert.Contains(t, output, filepath.FromSlash("/nonexistent/alpha"))
+	assert.NotContains(t, output, filepath.FromSlash("/nonexistent/beta"))
+	assert.Contains(t, output, `Showing 1 projects matching "alp"`)
+	assert.Contains(t, output, "Total: 2 projects (2 stale)")
+
+	_, err := parseArgs([]string{"list", "projects", "--only", "[bad"})
+	assert.ErrorContains(t, err, "invalid --only pattern")
+}
+
+func TestRunCLI_CleanProjectsTrash(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectDir := filepath.Join(claudeDir, "projects", "-nonexistent-path")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	nonexistentPath := filepath.Join(tmpDir, "this-path-does-not-exist-anywhere")
+	sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(nonexistentPath) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "projects", "--trash", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+
+	assert.NoDirExists(t, projectDir)
+	trashed, err := filepath.Glob(filepath.Join(claudeDir, "cccc-trash", "*", "-nonexistent-path"))
+	require.NoError(t, err)
+	assert.Len(t, trashed, 1)
+	assert.Contains(t, stdout.String(), "cccc trash empty")
+
+	stdout.Reset()
+	code = runCLI([]string{"trash", "empty", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Emptied trash")
+	assert.NoDirExists(t, filepath.Dir(trashed[0]))
+
+	stdout.Reset()
+	code = runCLI([]string{"trash", "empty"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "Trash is empty.")
+}
+
+func TestRunCLI_TrashOnlyWithClean(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "projects", "--trash"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "--trash is only supported by clean")
+}
+
+func TestRunCLI_TrashListAndEmptyOlderThan(t *testing.T) {
+	tmpDir := t.TempDir()
+	trashRoot := filepath.Join(tmpDir, ".claude", "cccc-trash")
+	oldBatch := filepath.Join(trashRoot, "20250101-000000")
+	newBatch := filepath.Join(trashRoot, "20250301-000000")
+	require.NoError(t, os.MkdirAll(oldBatch, 0755))
+	require.NoError(t, os.MkdirAll(newBatch, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(oldBatch, "file"), []byte("old"), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	origNow := now
+	now = func() time.Time { return time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC) }
+	defer func() { now = origNow }()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"trash", "list"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), oldBatch)
+	assert.Contains(t, stdout.String(), newBatch)
+	assert.Contains(t, stdout.String(), "Total: 2 batches")
+
+	stdout.Reset()
+	code = runCLI([]string{"trash", "empty", "--older-than", "30d", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.NoDirExists(t, oldBatch)
+	assert.DirExists(t, newBatch)
+}
+
+func TestRunCLI_CleanOrphansMaxDelete(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	for _, name := range []string{"a-agent-x.json", "b-agent-x.json", "c-agent-x.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(todosDir, name), []byte(`{}`), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "orphans", "--max-delete", "2", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "3 items to clean exceed --max-delete 2")
+	assert.FileExists(t, filepath.Join(todosDir, "a-agent-x.json"))
+
+	stderr.Reset()
+	code = runCLI([]string{"clean", "orphans", "--max-delete", "2", "--force", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.NoFileExists(t, filepath.Join(todosDir, "a-agent-x.json"))
+}
+
+func TestRunCLI_CleanAllMaxDeleteCountsAllPhases(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectDir := filepath.Join(claudeDir, "projects", "-stale")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	sessionData := `{"sessionId":"s1","cwd":"` + filepath.ToSlash(filepath.Join(tmpDir, "gone")) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "s1.jsonl"), []byte(sessionData), 0644))
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	for _, name := range []string{"a-agent-x.json", "b-agent-x.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(todosDir, name), []byte(`{}`), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	// Each phase stays below the limit, but together they exceed it
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "--max-delete", "2", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "3 items to clean across projects, orphans and config exceed --max-delete 2")
+	assert.DirExists(t, projectDir)
+	assert.FileExists(t, filepath.Join(todosDir, "a-agent-x.json"))
+
+	stderr.Reset()
+	code = runCLI([]string{"clean", "--max-delete", "3", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.NoDirExists(t, projectDir)
+}
+
+func TestRunCLI_CleanProjectsSkipsClaudeHome(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectDir := filepath.Join(claudeDir, "projects", "-claude-scratch")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	missing := filepath.Join(claudeDir, "scratch-that-was-removed")
+	sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(missing) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "projects", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.DirExists(t, projectDir)
+	assert.Contains(t, stderr.String(), "inside the Claude home")
+	assert.Contains(t, stdout.String(), "No stale projects found.")
+
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"clean", "projects", "--include-claude-home", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.NoDirExists(t, projectDir)
+}
+
+func TestRunCLI_AuditSince(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	require.NoError(t, os.MkdirAll(claudeDir, 0755))
+	log := "2024-06-01T00:00:00Z DELETE /old/project (1.0 MB)\n" +
+		"2025-02-01T00:00:00Z DELETE /new/project (2.0 KB)\n" +
+		`{"time":"2025-02-02T00:00:00Z","action":"MODIFY","path":"/new/settings.local.json","size":0,"details":"removed 1 entry"}` + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(claudeDir, "cccc-audit.log"), []byte(log), 0600))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"audit", "--since", "2025-01-01"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.NotContains(t, stdout.String(), "/old/project")
+	assert.Contains(t, stdout.String(), "/new/project (2.0 KB)")
+	assert.Contains(t, stdout.String(), "/new/settings.local.json: removed 1 entry")
+	assert.Contains(t, stdout.String(), "Total: 2 entries (1 DELETE, 1 MODIFY), freed 2.0 KB")
+
+	stdout.Reset()
+	code = runCLI([]string{"audit", "--action", "modify"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Total: 1 entries (1 MODIFY), freed 0 B")
+}
+
+func TestParseArgs_SinceRequiresAudit(t *testing.T) {
+	_, err := parseArgs([]string{"list", "--since", "2025-01-01"})
+	assert.EqualError(t, err, "--since and --action are only supported by audit")
+
+	_, err = parseArgs([]string{"audit", "--since", "yesterday"})
+	assert.Error(t, err)
+}
+
+func TestRunCLI_ListOrphansSummary(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(todosDir, "a-agent-x.json"), []byte(`{}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(todosDir, "b-agent-x.json"), []byte(`{"a":1}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "orphans"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "By type:")
+	assert.Regexp(t, `todo\s+2 items\s+9 B`, stdout.String())
+
+	stdout.Reset()
+	code = runCLI([]string{"list", "orphans", "--json"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	var envelope struct {
+		Summary map[string]orphanSummaryJSON `json:"summary"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &envelope))
+	assert.Equal(t, orphanSummaryJSON{Count: 2, Size: 9}, envelope.Summary["todo"])
+}
+
+func TestParseArgs_IncludeEmpty(t *testing.T) {
+	args, err := parseArgs([]string{"list", "orphans"})
+	require.NoError(t, err)
+	assert.False(t, args.ExcludeEmpty)
+
+	args, err = parseArgs([]string{"list", "orphans", "--include-empty=false"})
+	require.NoError(t, err)
+	assert.True(t, args.ExcludeEmpty)
+
+	args, err = parseArgs([]string{"list", "orphans", "--include-empty"})
+	require.NoError(t, err)
+	assert.False(t, args.ExcludeEmpty)
+
+	_, err = parseArgs([]string{"list", "orphans", "--include-empty=maybe"})
+	assert.Error(t, err)
+}
+
+func TestRunCLI_ListPathsOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	todo := filepath.Join(todosDir, "gone-agent-x.json")
+	require.NoError(t, os.WriteFile(todo, []byte(`{}`), 0644))
+
+	existing := filepath.Join(tmpDir, "existing")
+	missing := filepath.Join(tmpDir, "missing")
+	require.NoError(t, os.MkdirAll(existing, 0755))
+	for name, cwd := range map[string]string{"-existing": existing, "-missing": missing} {
+		dir := filepath.Join(claudeDir, "projects", name)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		session := `{"sessionId":"s` + name + `","cwd":"` + filepath.ToSlash(cwd) + `","timestamp":"2025-01-01T00:00:00Z"}`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "s.jsonl"), []byte(session), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "orphans", "--paths-only"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, todo+"\n", stdout.String())
+
+	stdout.Reset()
+	code = runCLI([]string{"list", "projects", "--stale-only", "--paths-only"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, missing+"\n", stdout.String())
+
+	_, err := parseArgs([]string{"list", "projects", "--paths-only", "--json"})
+	assert.Error(t, err)
+}
+
+func TestParseArgs_OrphanLogsRequireCutoff(t *testing.T) {
+	_, err := parseArgs([]string{"list", "orphans", "logs"})
+	assert.EqualError(t, err, "orphans logs requires --logs-older-than")
+
+	args, err := parseArgs([]string{"clean", "orphans", "logs", "--logs-older-than", "30d"})
+	require.NoError(t, err)
+	assert.Equal(t, 30*24*time.Hour, args.LogsOlderThan)
+}
+
+func TestCleanErrors(t *testing.T) {
+	var stderr bytes.Buffer
+
+	bestEffort := &cleanErrors{}
+	assert.Equal(t, 0, bestEffort.exitCode(&stderr, "project"))
+	assert.False(t, bestEffort.add(&stderr, "project", "/a", os.ErrPermission))
+	assert.False(t, bestEffort.add(&stderr, "project", "/b", os.ErrPermission))
+	assert.Equal(t, 1, bestEffort.exitCode(&stderr, "project"))
+	assert.Contains(t, stderr.String(), "Error cleaning project /a: permission denied")
+	assert.Contains(t, stderr.String(), "Failed to clean 2 projects")
+
+	stderr.Reset()
+	failFast := &cleanErrors{failFast: true}
+	assert.True(t, failFast.add(&stderr, "orphan", "/a", os.ErrPermission))
+	assert.Equal(t, 1, failFast.exitCode(&stderr, "orphan"))
+	assert.Contains(t, stderr.String(), "Stopped at the first orphan that could not be cleaned (--fail-fast)")
+}
+
+func TestRunCLI_CleanAllContinuesAfterFailedPhase(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectDir := filepath.Join(claudeDir, "projects", "-stale")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	sessionData := `{"sessionId":"s1","cwd":"` + filepath.ToSlash(filepath.Join(tmpDir, "gone")) + `","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "s1.jsonl"), []byte(sessionData), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "todos"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(claudeDir, "todos", "gone-agent-x.json"), []byte(`{}`), 0644))
+	// A file where the trash directory belongs makes every removal fail
+	require.NoError(t, os.WriteFile(filepath.Join(claudeDir, "cccc-trash"), nil, 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "--yes", "--trash"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "Error cleaning project")
+	assert.Contains(t, stderr.String(), "Error cleaning orphan")
+	assert.Contains(t, stdout.String(), "Total freed:")
+
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"clean", "--yes", "--trash", "--fail-fast"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "Error cleaning project")
+	assert.NotContains(t, stderr.String(), "Error cleaning orphan")
+}
+
+func TestParseArgs_DedupPlan(t *testing.T) {
+	args, err := parseArgs([]string{"clean", "config", "--dry-run", "--save-plan", "plan.json"})
+	require.NoError(t, err)
+	assert.Equal(t, "plan.json", args.SavePlan)
+
+	args, err = parseArgs([]string{"clean", "config", "--apply-plan=plan.json"})
+	require.NoError(t, err)
+	assert.Equal(t, "plan.json", args.ApplyPlan)
+
+	_, err = parseArgs([]string{"clean", "config", "--save-plan", "plan.json"})
+	assert.EqualError(t, err, "--save-plan requires --dry-run")
+
+	_, err = parseArgs([]string{"clean", "orphans", "--apply-plan", "plan.json"})
+	assert.EqualError(t, err, "--save-plan and --apply-plan are only supported by clean config")
+}
+
+func TestRunCLI_CleanConfigSaveAndApplyPlan(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+	require.NoError(t, os.MkdirAll(projectsDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(claudeDir, "settings.json"), []byte(`{"permissions":{"allow":["Bash(git:*)"]}}`), 0644))
+
+	// proj-a only mirrors global settings and is planned for deletion,
+	// proj-b keeps its unique entry
+	locals := map[string]string{
+		"proj-a": `{"permissions":{"allow":["Bash(git:*)"]}}`,
+		"proj-b": `{"permissions":{"allow":["Bash(git:*)","Bash(npm:*)"]}}`,
+	}
+	for name, content := range locals {
+		projectDir := filepath.Join(tmpDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Join(projectDir, ".claude"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(projectDir, ".claude", "settings.local.json"), []byte(content), 0644))
+
+		encodedDir := filepath.Join(projectsDir, "-"+name)
+		require.NoError(t, os.MkdirAll(encodedDir, 0755))
+		sessionData := `{"sessionId":"` + name + `","cwd":"` + filepath.ToSlash(projectDir) + `","timestamp":"2025-01-01T00:00:00Z"}`
+		require.NoError(t, os.WriteFile(filepath.Join(encodedDir, "session.jsonl"), []byte(sessionData), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	planPath := filepath.Join(tmpDir, "plan.json")
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "config", "--dry-run", "--save-plan", planPath}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Saved plan to "+planPath)
+	assert.FileExists(t, planPath)
+
+	// proj-a gains an entry after the review, so deleting it would lose data
+	localA := filepath.Join(tmpDir, "proj-a", ".claude", "settings.local.json")
+	require.NoError(t, os.WriteFile(localA, []byte(`{"permissions":{"allow":["Bash(git:*)","Bash(make:*)"]}}`), 0644))
+
+	// Remove the sessions to show the plan is applied without scanning
+	require.NoError(t, os.RemoveAll(projectsDir))
+
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"clean", "config", "--apply-plan", planPath, "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stderr.String(), "Warning: skipping "+localA+": changed since the plan was saved")
+	assert.Contains(t, stdout.String(), "Deduplicated 1 config files")
+
+	assert.FileExists(t, localA)
+	local, err := claude.LoadSettings(filepath.Join(tmpDir, "proj-b", ".claude", "settings.local.json"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bash(npm:*)"}, local.Permissions.Allow)
+}
+
+func TestRunCLI_ListProjectsMachineTotals(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectsDir := filepath.Join(tmpDir, ".claude", "projects")
+
+	existingDir := filepath.Join(tmpDir, "existing-project")
+	require.NoError(t, os.MkdirAll(existingDir, 0755))
+	sessions := map[string]string{
+		"-existing": `{"sessionId":"kept","cwd":"` + filepath.ToSlash(existingDir) + `"}`,
+		"-gone":     `{"sessionId":"stale","cwd":"` + filepath.ToSlash(filepath.Join(tmpDir, "gone")) + `"}`,
+	}
+	var totalSize int
+	for name, content := range sessions {
+		projectDir := filepath.Join(projectsDir, name)
+		require.NoError(t, os.MkdirAll(projectDir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(content), 0644))
+		totalSize += len(content)
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "projects", "--machine-totals"}, strings.NewReader(""), &stdout, &stderr)
+
+	require.Equal(t, 0, code, stderr.String())
+	assert.True(t, strings.HasSuffix(stdout.String(), "\n# totals projects=2 stale=1 unavailable=0 bytes="+strconv.Itoa(totalSize)+"\n"), stdout.String())
+
+	// Off by default
+	stdout.Reset()
+	code = runCLI([]string{"list", "projects"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code)
+	assert.NotContains(t, stdout.String(), "# totals")
+}
+
+func TestParseArgs_MachineTotals(t *testing.T) {
+	args, err := parseArgs([]string{"list", "projects", "--machine-totals"})
+	require.NoError(t, err)
+	assert.True(t, args.MachineTotals)
+
+	_, err = parseArgs([]string{"list", "orphans", "--machine-totals"})
+	assert.EqualError(t, err, "--machine-totals is only supported by list projects")
+
+	_, err = parseArgs([]string{"list", "projects", "--machine-totals", "--json"})
+	assert.ErrorContains(t, err, "--machine-totals cannot be combined with --json")
+}
+
+func TestRunCLI_CleanSeveralClaudeHomes(t *testing.T) {
+	tmpDir := t.TempDir()
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	homes := []string{filepath.Join(tmpDir, ".claude-work"), filepath.Join(tmpDir, ".claude-personal")}
+	var sessionDirs []string
+	for _, home := range homes {
+		projectDir := filepath.Join(home, "projects", "-gone")
+		require.NoError(t, os.MkdirAll(projectDir, 0755))
+		sessionData := `{"sessionId":"sess1","cwd":"` + filepath.ToSlash(filepath.Join(tmpDir, "gone")) + `","timestamp":"2025-01-01T00:00:00Z"}`
+		require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(sessionData), 0644))
+		sessionDirs = append(sessionDirs, projectDir)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "projects", "--yes", "--claude-home", strings.Join(homes, ",")}, strings.NewReader(""), &stdout, &stderr)
+
+	require.Equal(t, 0, code, stderr.String())
+	output := stdout.String()
+	for i, home := range homes {
+		assert.Contains(t, output, "== Claude home "+home+" ==")
+		assert.NoDirExists(t, sessionDirs[i])
+
+		// Each home has its own audit log
+		audit, err := os.ReadFile(filepath.Join(home, "cccc-audit.log"))
+		require.NoError(t, err)
+		assert.Contains(t, string(audit), "DELETE "+filepath.Join(tmpDir, "gone"))
+	}
+	assert.Contains(t, output, "Finished 2 Claude homes (0 failed)")
+}
+
+func TestParseArgs_ClaudeHome(t *testing.T) {
+	args, err := parseArgs([]string{"clean", "--claude-home", "/a/.claude, /b/.claude", "--claude-home=/c/.claude"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/a/.claude", "/b/.claude", "/c/.claude"}, args.ClaudeHomes)
+
+	_, err = parseArgs([]string{"list", "projects", "--json", "--claude-home", "/a/.claude,/b/.claude"})
+	assert.ErrorContains(t, err, "several --claude-home directories cannot be combined with --json")
+
+	_, err = parseArgs([]string{"watch", "--claude-home", "/a/.claude,/b/.claude"})
+	assert.EqualError(t, err, "watch supports only one --claude-home")
+}
+
+func TestRunCLI_MissingClaudeHome(t *testing.T) {
+	tmpDir := t.TempDir()
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "--claude-home", filepath.Join(tmpDir, "typo")}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "Error discovering Claude paths:")
+}
+
+func TestRunCLI_CleanOrphansSortPreview(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	small := filepath.Join(todosDir, "a-agent-small.json")
+	large := filepath.Join(todosDir, "b-agent-large.json")
+	require.NoError(t, os.WriteFile(small, []byte(`{}`), 0644))
+	require.NoError(t, os.WriteFile(large, []byte(strings.Repeat(" ", 4096)), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "orphans", "--dry-run"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Less(t, strings.Index(stdout.String(), small), strings.Index(stdout.String(), large))
+
+	stdout.Reset()
+	code = runCLI([]string{"clean", "orphans", "--dry-run", "--sort-preview"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Less(t, strings.Index(stdout.String(), large), strings.Index(stdout.String(), small))
+}
+
+func TestRunCLI_CleanKeepNewestSession(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	staleDir := filepath.Join(claudeDir, "projects", "-stale")
+	require.NoError(t, os.MkdirAll(staleDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(staleDir, "old.jsonl"),
+		[]byte(`{"sessionId":"old","cwd":"/nonexistent/stale","timestamp":"2025-01-01T00:00:00Z"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(staleDir, "new.jsonl"),
+		[]byte(`{"sessionId":"new","cwd":"/nonexistent/stale","timestamp":"2025-02-01T00:00:00Z"}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "projects", "--keep-newest-session", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	archiveDir := filepath.Join(claudeDir, "cccc-archive")
+	assert.Contains(t, stdout.String(), "newest session is kept in "+archiveDir)
+	assert.Contains(t, stdout.String(), "Kept the newest session of 1 projects in "+archiveDir)
+	assert.NoDirExists(t, staleDir)
+	assert.FileExists(t, filepath.Join(archiveDir, "-stale", "new.jsonl"))
+	assert.NoFileExists(t, filepath.Join(archiveDir, "-stale", "old.jsonl"))
+}
+
+func TestParseArgs_KeepNewestSessionOnlyWithCleanProjects(t *testing.T) {
+	for _, argv := range [][]string{{"clean", "--keep-newest-session"}, {"clean", "projects", "--keep-newest-session"}} {
+		_, err := parseArgs(argv)
+		assert.NoError(t, err, argv)
+	}
+
+	for _, argv := range [][]string{{"clean", "orphans", "--keep-newest-session"}, {"prune", "--keep-newest-session"}} {
+		_, err := parseArgs(argv)
+		assert.ErrorContains(t, err, "--keep-newest-session is only supported by clean projects", argv)
+	}
+}
+
+func TestRunCLI_KeepNoCWD(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	noCWDDir := filepath.Join(claudeDir, "projects", "-no-cwd")
+	require.NoError(t, os.MkdirAll(noCWDDir, 0755))
+	// Sessions with content but no cwd cannot be matched to a directory
+	require.NoError(t, os.WriteFile(filepath.Join(noCWDDir, "sess.jsonl"), []byte(`{"type":"summary"}`+"\n"), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "projects", "--keep-no-cwd"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "[UNKNOWN]")
+	assert.Contains(t, stdout.String(), "(0 stale)")
+
+	stdout.Reset()
+	code = runCLI([]string{"clean", "projects", "--keep-no-cwd", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "No stale projects found.")
+	assert.DirExists(t, noCWDDir)
+
+	// Without the flag, the project is stale as before
+	stdout.Reset()
+	code = runCLI([]string{"list", "projects"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "[STALE]")
+}
+
+func TestRunCLI_CleanProjectsInput(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	activePath := filepath.Join(tmpDir, "active")
+	require.NoError(t, os.MkdirAll(activePath, 0755))
+
+	projects := map[string]string{"-active": activePath, "-other": filepath.Join(tmpDir, "other")}
+	for name, cwd := range projects {
+		dir := filepath.Join(claudeDir, "projects", name)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		line, err := json.Marshal(map[string]string{"sessionId": name, "cwd": cwd})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "sess.jsonl"), line, 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	// Unknown entries are refused and nothing is removed
+	var stdout, stderr bytes.Buffer
+	input := activePath + "/\n\n/not/a/project\n"
+	code := runCLI([]string{"clean", "projects", "--input", "-", "--yes"}, strings.NewReader(input), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "/not/a/project")
+	assert.DirExists(t, filepath.Join(claudeDir, "projects", "-active"))
+
+	// --force only overrides --max-delete and does not skip them
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"clean", "projects", "--input", "-", "--yes", "--force"}, strings.NewReader(input), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "Re-run with --skip-unknown")
+	assert.DirExists(t, filepath.Join(claudeDir, "projects", "-active"))
+
+	// With --skip-unknown they are skipped; the active project is cleaned even though it is not stale
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"clean", "projects", "--input", "-", "--yes", "--skip-unknown"}, strings.NewReader(input), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stderr.String(), "Warning: skipping /not/a/project: not a known project")
+	assert.Contains(t, stdout.String(), "Selected Project Cleanup")
+	assert.NoDirExists(t, filepath.Join(claudeDir, "projects", "-active"))
+	assert.DirExists(t, filepath.Join(claudeDir, "projects", "-other"))
+
+	audit, err := os.ReadFile(filepath.Join(claudeDir, "cccc-audit.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(audit), activePath)
+}
+
+func TestParseArgs_Input(t *testing.T) {
+	args, err := parseArgs([]string{"clean", "projects", "--input", "list.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, "list.txt", args.Input)
+
+	_, err = parseArgs([]string{"clean", "projects", "--input", "-"})
+	assert.ErrorContains(t, err, "requires --yes or --dry-run")
+
+	_, err = parseArgs([]string{"clean", "--input", "list.txt"})
+	assert.ErrorContains(t, err, "--input is only supported by clean projects")
+
+	args, err = parseArgs([]string{"clean", "projects", "--input", "list.txt", "--skip-unknown"})
+	require.NoError(t, err)
+	assert.True(t, args.SkipUnknown)
+
+	_, err = parseArgs([]string{"clean", "projects", "--skip-unknown"})
+	assert.ErrorContains(t, err, "--skip-unknown requires --input")
+}
+
+func TestRunCLI_ShowAuditLinesMatchesAuditLog(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	staleDir := filepath.Join(claudeDir, "projects", "-stale")
+	require.NoError(t, os.MkdirAll(staleDir, 0755))
+	sessionData := `{"sessionId":"stale-sess","cwd":"/nonexistent/stale","timestamp":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filepath.Join(staleDir, "stale-sess.jsonl"), []byte(sessionData), 0644))
+	todo := filepath.Join(claudeDir, "todos", "gone-sess-agent-a.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(todo), 0755))
+	require.NoError(t, os.WriteFile(todo, []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "--dry-run", "--show-audit-lines"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+
+	var planned []string
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if strings.HasPrefix(line, "DELETE ") || strings.HasPrefix(line, "MODIFY ") {
+			planned = append(planned, line)
+		}
+	}
+	require.Len(t, planned, 2)
+
+	code = runCLI([]string{"clean", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+
+	audit, err := os.ReadFile(filepath.Join(claudeDir, "cccc-audit.log"))
+	require.NoError(t, err)
+	var logged []string
+	for _, line := range strings.Split(strings.TrimSpace(string(audit)), "\n") {
+		_, entry, _ := strings.Cut(line, " ")
+		logged = append(logged, entry)
+	}
+	assert.Equal(t, planned, logged)
+
+	_, err = parseArgs([]string{"clean", "--show-audit-lines"})
+	assert.ErrorContains(t, err, "--show-audit-lines requires --dry-run")
+}
+
+func TestRunCLI_CleanProjectsSelect(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	for _, name := range []string{"-first", "-second"} {
+		dir := filepath.Join(claudeDir, "projects", name)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		line := `{"sessionId":"` + name + `","cwd":"/nonexistent/` + name + `"}`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "sess.jsonl"), []byte(line), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	// stdin is not a terminal, so every project is asked about in turn. The
+	// selection is not part of the --output record.
+	record := filepath.Join(tmpDir, "record.txt")
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "projects", "--select", "-o", record}, strings.NewReader("n\ny\n"), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "DELETE /nonexistent/-first")
+	assert.DirExists(t, filepath.Join(claudeDir, "projects", "-first"))
+	assert.NoDirExists(t, filepath.Join(claudeDir, "projects", "-second"))
+	content, err := os.ReadFile(record)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "/nonexistent/-second")
+	assert.NotContains(t, string(content), "[y/N/q]")
+
+	_, err = parseArgs([]string{"clean", "projects", "--select", "--yes"})
+	assert.ErrorContains(t, err, "--select cannot be combined")
+}
+
+func TestParseArgs_OrphanLocksRequireIncludeLocks(t *testing.T) {
+	_, err := parseArgs([]string{"clean", "orphans", "locks"})
+	assert.ErrorContains(t, err, "orphans locks requires --include-locks")
+
+	args, err := parseArgs([]string{"clean", "orphans", "locks", "--include-locks"})
+	require.NoError(t, err)
+	assert.True(t, args.IncludeLocks)
+	assert.Equal(t, "locks", args.OrphanKind)
+}
+
+func TestRunCLI_MeasureDisk(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, ".claude", "projects"), 0755))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "--measure-disk", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	if strings.Contains(stderr.String(), "cannot measure free disk space") {
+		t.Skip("measuring free space is not supported on this platform")
+	}
+	assert.Regexp(t, `Disk free: \S+ \S+ → \S+ \S+ \((reclaimed|decreased by) `, stdout.String())
+
+	_, err := parseArgs([]string{"clean", "--measure-disk", "--dry-run"})
+	assert.ErrorContains(t, err, "--measure-disk cannot be combined with --dry-run")
+}
+
+func TestRunCLI_ExplainOrphan(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	orphanTodo := filepath.Join(todosDir, "abc123-agent-xyz.json")
+	require.NoError(t, os.WriteFile(orphanTodo, []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "orphans", "--explain-orphan"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Reasons:\n  "+orphanTodo+"\n        todo references session abc123, which is not in any project\n")
+	assert.Contains(t, stdout.String(), "[DRY RUN]")
+	assert.FileExists(t, orphanTodo)
+
+	stdout.Reset()
+	code = runCLI([]string{"list", "orphans", "--json", "--explain-orphan"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), `"reason": "todo references session abc123, which is not in any project"`)
+
+	_, err := parseArgs([]string{"clean", "projects", "--explain-orphan"})
+	assert.ErrorContains(t, err, "--explain-orphan is only supported by list orphans, clean orphans and clean")
+}
+
+func TestRunCLI_ConfigLint(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectDir := filepath.Join(tmpDir, "proj")
+	encodedDir := filepath.Join(claudeDir, "projects", "-proj")
+	require.NoError(t, os.MkdirAll(encodedDir, 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(projectDir, ".claude"), 0755))
+
+	globalPath := filepath.Join(claudeDir, "settings.json")
+	localPath := filepath.Join(projectDir, ".claude", "settings.local.json")
+	require.NoError(t, os.WriteFile(globalPath, []byte(`{"permissions":{"allow":["Read"]}}`), 0644))
+	require.NoError(t, os.WriteFile(localPath, []byte(`{"permissions":{"allow":["Write","Write"]}}`), 0644))
+	sessionData := `{"sessionId":"s1","cwd":"` + filepath.ToSlash(projectDir) + `"}`
+	require.NoError(t, os.WriteFile(filepath.Join(encodedDir, "session.jsonl"), []byte(sessionData), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	// Warnings alone do not fail
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"config", "lint"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), localPath+"\n  warning allow \"Write\": listed 2 times in allow\n")
+	assert.NotContains(t, stdout.String(), globalPath)
+	assert.Contains(t, stdout.String(), "Found 0 errors and 1 warnings in 1 files")
+
+	require.NoError(t, os.WriteFile(globalPath, []byte(`{"permissions":{"allow":["Bash(git:*"]}}`), 0644))
+	stdout.Reset()
+	code = runCLI([]string{"config", "lint", "--json"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), `"severity": "error"`)
+	assert.Contains(t, stdout.String(), `"message": "unbalanced parentheses"`)
+}
+
+func TestRunCLI_CleanProjectsNoKept(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	activeDir := filepath.Join(tmpDir, "active")
+	require.NoError(t, os.MkdirAll(activeDir, 0755))
+	for name, cwd := range map[string]string{"-active": activeDir, "-stale": "/nonexistent/stale"} {
+		dir := filepath.Join(claudeDir, "projects", name)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "s.jsonl"),
+			[]byte(`{"sessionId":"`+name+`","cwd":"`+filepath.ToSlash(cwd)+`"}`), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"clean", "projects", "--dry-run"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Kept (no changes):")
+
+	stdout.Reset()
+	code = runCLI([]string{"clean", "projects", "--dry-run", "--no-kept"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "/nonexistent/stale")
+	assert.NotContains(t, stdout.String(), "Kept (no changes):")
+	assert.NotContains(t, stdout.String(), activeDir)
+}
+
+func TestRunCLI_ConfigDedupSummary(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(claudeDir, "settings.json"), []byte(`{"permissions":{"allow":["Read","Write"]}}`), 0644))
+
+	locals := map[string]string{
+		"proj-a": `{"permissions":{"allow":["Read","Write"]}}`,
+		"proj-b": `{"permissions":{"allow":["Read","Bash(npm:*)"]}}`,
+	}
+	for name, content := range locals {
+		projectDir := filepath.Join(tmpDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Join(projectDir, ".claude"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(projectDir, ".claude", "settings.local.json"), []byte(content), 0644))
+
+		encodedDir := filepath.Join(claudeDir, "projects", "-"+name)
+		require.NoError(t, os.MkdirAll(encodedDir, 0755))
+		sessionData := `{"sessionId":"` + name + `","cwd":"` + filepath.ToSlash(projectDir) + `"}`
+		require.NoError(t, os.WriteFile(filepath.Join(encodedDir, "session.jsonl"), []byte(sessionData), 0644))
+	}
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"list", "config", "--json"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	var envelope struct {
+		Summary dedupSummaryJSON `json:"summary"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &envelope))
+	assert.Equal(t, dedupSummaryJSON{Configs: 2, DuplicateEntries: 3, ConfigsToDelete: 1}, envelope.Summary)
+
+	stdout.Reset()
+	code = runCLI([]string{"clean", "config", "--yes"}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Deduplicated 2 config files, removed 3 duplicate entries (1 file deleted)\n")
+}
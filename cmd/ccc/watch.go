@@ -0,0 +1,143 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+	"github.com/mkoepf/claude-code-config-cleaner/internal/cleaner"
+	"github.com/mkoepf/claude-code-config-cleaner/internal/ui"
+)
+
+// defaultWatchInterval is the time between scans of the watch command.
+const defaultWatchInterval = time.Hour
+
+// handleWatch scans every --interval and reports stale projects and orphans
+// that appeared since the previous scan, cleaning them if --clean is set.
+// It runs until interrupted.
+func handleWatch(ctx context.Context, args *Args, paths *claude.Paths, stdin io.Reader, stdout, stderr io.Writer) int {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	interval := args.Interval
+	if interval == 0 {
+		interval = defaultWatchInterval
+	}
+	fmt.Fprintf(stdout, "Watching %s every %s (Ctrl-C to stop)\n", paths.Root, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	seen := make(map[string]struct{})
+	for {
+		if code := watchTick(ctx, args, paths, seen, stdin, stdout, stderr); code != 0 {
+			return code
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(stdout, "Stopped watching.")
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchTick runs a single scan of the watch command. seen holds the paths
+// reported by earlier scans and is updated with the new ones; paths no
+// longer found are dropped, so it does not grow over a long watch and an
+// item that reappears is reported again. A failed cleanup is reported and
+// the watch goes on, unless --fail-fast is set.
+func watchTick(ctx context.Context, args *Args, paths *claude.Paths, seen map[string]struct{}, stdin io.Reader, stdout, stderr io.Writer) int {
+	projects, err := scanProjects(ctx, args, paths, stderr)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0
+		}
+		fmt.Fprintln(stderr, "Error scanning projects:", err)
+		return 1
+	}
+
+	stale, _, _ := selectStaleProjects(args, paths, projects, stderr)
+
+	var validSessionIDs []string
+	for _, p := range projects {
+		validSessionIDs = append(validSessionIDs, p.SessionIDs...)
+	}
+	opts := &cleaner.OrphanOptions{TodoPattern: args.TodoPattern, ExcludeEmpty: args.ExcludeEmpty, LogCutoff: logCutoff(args)}
+	scanCtx, scanDone := scanContext(ctx, args)
+	orphans, err := cleaner.FindOrphansWithOptions(scanCtx, paths, validSessionIDs, opts)
+	scanDone(err)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0
+		}
+		fmt.Fprintln(stderr, "Error finding orphans:", err)
+		return 1
+	}
+
+	found := make(map[string]struct{}, len(stale)+len(orphans))
+	var newStale []claude.Project
+	for _, p := range stale {
+		key := "project:" + p.EncodedName
+		found[key] = struct{}{}
+		if markSeen(seen, key) {
+			newStale = append(newStale, p)
+		}
+	}
+	var newOrphans []cleaner.OrphanResult
+	var newSize int64
+	for _, o := range orphans {
+		key := "orphan:" + o.Path
+		found[key] = struct{}{}
+		if markSeen(seen, key) {
+			newOrphans = append(newOrphans, o)
+			newSize += o.SizeSaved
+		}
+	}
+	for key := range seen {
+		if _, ok := found[key]; !ok {
+			delete(seen, key)
+		}
+	}
+	for _, p := range newStale {
+		newSize += p.TotalSize
+	}
+
+	fmt.Fprintf(stdout, "[%s] %d new stale projects, %d new orphans (%s)\n",
+		now().Format("2006-01-02 15:04:05"), len(newStale), len(newOrphans), ui.FormatSize(newSize))
+	for _, p := range newStale {
+		fmt.Fprintf(stdout, "  stale   %s\n", projectDisplayPath(p))
+	}
+	for _, o := range newOrphans {
+		fmt.Fprintf(stdout, "  orphan  %s\n", o.Path)
+	}
+
+	if !args.WatchClean || len(newStale)+len(newOrphans) == 0 {
+		return 0
+	}
+
+	code := cleanProjects(ctx, args, paths, stdin, stdout, stderr, nil)
+	if code == 0 || !args.FailFast {
+		code = cmp.Or(code, cleanOrphans(ctx, args, paths, stdin, stdout, stderr, nil))
+	}
+	if code != 0 && !args.FailFast {
+		fmt.Fprintln(stderr, "Warning: cleanup failed; still watching (use --fail-fast to stop instead)")
+		return 0
+	}
+	return code
+}
+
+// markSeen adds key to seen and reports whether it was new.
+func markSeen(seen map[string]struct{}, key string) bool {
+	if _, ok := seen[key]; ok {
+		return false
+	}
+	seen[key] = struct{}{}
+	return true
+}
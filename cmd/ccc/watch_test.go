@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchTick_ReportsOnlyNewItems(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(todosDir, "a-agent-x.json"), []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+	paths, err := claude.DiscoverPaths("")
+	require.NoError(t, err)
+
+	args := &Args{Command: "watch", NoCache: true}
+	seen := make(map[string]struct{})
+	var stdout, stderr bytes.Buffer
+
+	require.Equal(t, 0, watchTick(context.Background(), args, paths, seen, strings.NewReader(""), &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "0 new stale projects, 1 new orphans")
+	assert.Contains(t, stdout.String(), "a-agent-x.json")
+
+	require.NoError(t, os.WriteFile(filepath.Join(todosDir, "b-agent-x.json"), []byte(`{}`), 0644))
+	stdout.Reset()
+	require.Equal(t, 0, watchTick(context.Background(), args, paths, seen, strings.NewReader(""), &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "0 new stale projects, 1 new orphans")
+	assert.NotContains(t, stdout.String(), "a-agent-x.json")
+	assert.Contains(t, stdout.String(), "b-agent-x.json")
+}
+
+func TestWatchTick_Clean(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	todo := filepath.Join(todosDir, "a-agent-x.json")
+	require.NoError(t, os.WriteFile(todo, []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+	paths, err := claude.DiscoverPaths("")
+	require.NoError(t, err)
+
+	args := &Args{Command: "watch", WatchClean: true, Yes: true, NoCache: true}
+	var stdout, stderr bytes.Buffer
+	code := watchTick(context.Background(), args, paths, make(map[string]struct{}), strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.NoFileExists(t, todo)
+}
+
+func TestWatchTick_PrunesGoneItems(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	todo := filepath.Join(todosDir, "a-agent-x.json")
+	require.NoError(t, os.WriteFile(todo, []byte(`{}`), 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+	paths, err := claude.DiscoverPaths("")
+	require.NoError(t, err)
+
+	args := &Args{Command: "watch", NoCache: true}
+	seen := make(map[string]struct{})
+	var stdout, stderr bytes.Buffer
+
+	require.Equal(t, 0, watchTick(context.Background(), args, paths, seen, strings.NewReader(""), &stdout, &stderr))
+	assert.Len(t, seen, 1)
+
+	require.NoError(t, os.Remove(todo))
+	require.Equal(t, 0, watchTick(context.Background(), args, paths, seen, strings.NewReader(""), &stdout, &stderr))
+	assert.Empty(t, seen)
+
+	// An item that reappears is reported again
+	require.NoError(t, os.WriteFile(todo, []byte(`{}`), 0644))
+	stdout.Reset()
+	require.Equal(t, 0, watchTick(context.Background(), args, paths, seen, strings.NewReader(""), &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "a-agent-x.json")
+}
+
+func TestWatchTick_CleanFailureKeepsWatching(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	todosDir := filepath.Join(claudeDir, "todos")
+	require.NoError(t, os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755))
+	require.NoError(t, os.MkdirAll(todosDir, 0755))
+	todo := filepath.Join(todosDir, "a-agent-x.json")
+	require.NoError(t, os.WriteFile(todo, []byte(`{}`), 0644))
+	// A file where the trash directory belongs makes every removal fail
+	require.NoError(t, os.WriteFile(filepath.Join(claudeDir, "cccc-trash"), nil, 0644))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+	paths, err := claude.DiscoverPaths("")
+	require.NoError(t, err)
+
+	args := &Args{Command: "watch", WatchClean: true, Yes: true, NoCache: true}
+	args.trashBatch = filepath.Join(claudeDir, "cccc-trash", "batch")
+	var stdout, stderr bytes.Buffer
+	code := watchTick(context.Background(), args, paths, make(map[string]struct{}), strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stderr.String(), "still watching")
+	assert.FileExists(t, todo)
+
+	args.FailFast = true
+	stderr.Reset()
+	code = watchTick(context.Background(), args, paths, make(map[string]struct{}), strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.NotContains(t, stderr.String(), "still watching")
+}
+
+func TestHandleWatch_StopsWhenCancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, ".claude", "projects"), 0755))
+
+	cleanup := setTestHome(t, tmpDir)
+	defer cleanup()
+	paths, err := claude.DiscoverPaths("")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var stdout, stderr bytes.Buffer
+	code := handleWatch(ctx, &Args{Command: "watch", Interval: time.Hour, NoCache: true}, paths, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "Stopped watching.")
+}
+
+func TestParseArgs_WatchCleanRequiresYes(t *testing.T) {
+	_, err := parseArgs([]string{"watch", "--clean"})
+	assert.Error(t, err)
+
+	args, err := parseArgs([]string{"watch", "--clean", "--yes", "--interval", "30m"})
+	require.NoError(t, err)
+	assert.True(t, args.WatchClean)
+	assert.Equal(t, 30*time.Minute, args.Interval)
+
+	_, err = parseArgs([]string{"list", "--interval", "30m"})
+	assert.Error(t, err)
+}
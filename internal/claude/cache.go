@@ -0,0 +1,170 @@
+package claude
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// projectCacheVersion is bumped whenever the cache layout changes; caches
+// with a different version are discarded.
+const projectCacheVersion = 6
+
+// DefaultCachePath returns the location of the project scan cache.
+func DefaultCachePath(claudeRoot string) string {
+	return filepath.Join(claudeRoot, "cccc-cache.json")
+}
+
+// projectFingerprint summarizes the state of a project directory. If it is
+// unchanged, the cached Project is still accurate and the session files need
+// not be parsed again. The directory mtime alone misses appends to existing
+// session files, so the session files' sizes and mtimes are included too.
+type projectFingerprint struct {
+	DirModTime int64 `json:"dir_mod_time"`
+	Files      int   `json:"files"`
+	Size       int64 `json:"size"`
+	MaxModTime int64 `json:"max_mod_time"`
+}
+
+// projectCacheEntry is the cached scan result of one project directory.
+type projectCacheEntry struct {
+	Fingerprint projectFingerprint `json:"fingerprint"`
+	Project     Project            `json:"project"`
+}
+
+// ProjectCache is an on-disk cache of scanned project metadata, keyed by
+// project directory name. It is best-effort: a missing or unreadable cache
+// file simply results in a full scan.
+type ProjectCache struct {
+	mu      sync.Mutex // Guards Entries and dirty during parallel scans
+	path    string
+	dirty   bool
+	Version int                          `json:"version"`
+	Entries map[string]projectCacheEntry `json:"entries"`
+}
+
+// LoadProjectCache reads the cache at path. A missing, corrupt or outdated
+// cache file yields an empty cache.
+func LoadProjectCache(path string) *ProjectCache {
+	cache := &ProjectCache{
+		path:    path,
+		Version: projectCacheVersion,
+		Entries: make(map[string]projectCacheEntry),
+	}
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return cache
+	}
+
+	var loaded ProjectCache
+	if err := json.Unmarshal(data, &loaded); err != nil || loaded.Version != projectCacheVersion || loaded.Entries == nil {
+		cache.dirty = true // Rewrite the unusable cache on Save
+		return cache
+	}
+
+	cache.Entries = loaded.Entries
+	return cache
+}
+
+// Save writes the cache back to disk if it changed since it was loaded.
+func (c *ProjectCache) Save() error {
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated cache
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// ClearProjectCache removes the cache file at path. A missing file is not an error.
+func ClearProjectCache(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// lookup returns the cached project for name if its fingerprint matches.
+func (c *ProjectCache) lookup(name string, fp projectFingerprint) (Project, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.Entries[name]
+	if !ok || entry.Fingerprint != fp {
+		return Project{}, false
+	}
+	return entry.Project, true
+}
+
+// store records the scan result of project name.
+func (c *ProjectCache) store(name string, fp projectFingerprint, project Project) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Entries[name] = projectCacheEntry{Fingerprint: fp, Project: project}
+	c.dirty = true
+}
+
+// prune drops entries for project directories that no longer exist.
+func (c *ProjectCache) prune(seen map[string]struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name := range c.Entries {
+		if _, ok := seen[name]; !ok {
+			delete(c.Entries, name)
+			c.dirty = true
+		}
+	}
+}
+
+// fingerprintProjectDir computes the fingerprint of a project directory
+// from directory metadata only, without reading any session file.
+func fingerprintProjectDir(projectPath string) (projectFingerprint, error) {
+	var fp projectFingerprint
+
+	dirInfo, err := os.Stat(projectPath)
+	if err != nil {
+		return fp, err
+	}
+	fp.DirModTime = dirInfo.ModTime().UnixNano()
+
+	entries, err := os.ReadDir(projectPath)
+	if err != nil {
+		return fp, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !IsSessionFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return fp, err
+		}
+		fp.Files++
+		fp.Size += info.Size()
+		fp.MaxModTime = max(fp.MaxModTime, info.ModTime().UnixNano())
+	}
+
+	return fp, nil
+}
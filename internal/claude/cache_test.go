@@ -0,0 +1,88 @@
+package claude
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanProjectsWithOptions_CacheReusesUnchangedProjects(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectsDir := filepath.Join(tmpDir, "projects")
+	projectDir := createTestProject(t, projectsDir, "-Users-test-myproject", "/original/path")
+	cachePath := filepath.Join(tmpDir, "cache.json")
+
+	cache := LoadProjectCache(cachePath)
+	projects, _, err := ScanProjectsWithOptions(context.Background(), projectsDir, ScanOptions{Cache: cache})
+	require.NoError(t, err)
+	require.Len(t, projects, 1)
+	require.NoError(t, cache.Save())
+	assert.FileExists(t, cachePath)
+
+	// Rewrite the session with the same size and mtime: the cached result is used
+	sessionFile := filepath.Join(projectDir, "session.jsonl")
+	info, err := os.Stat(sessionFile)
+	require.NoError(t, err)
+	dirInfo, err := os.Stat(projectDir)
+	require.NoError(t, err)
+	content := `{"sessionId":"test-session","cwd":"/modified/path","timestamp":"2025-12-06T10:00:00Z"}`
+	require.NoError(t, os.WriteFile(sessionFile, []byte(content), 0644))
+	require.NoError(t, os.Chtimes(sessionFile, info.ModTime(), info.ModTime()))
+	require.NoError(t, os.Chtimes(projectDir, dirInfo.ModTime(), dirInfo.ModTime()))
+
+	cache = LoadProjectCache(cachePath)
+	projects, _, err = ScanProjectsWithOptions(context.Background(), projectsDir, ScanOptions{Cache: cache})
+	require.NoError(t, err)
+	require.Len(t, projects, 1)
+	assert.Equal(t, filepath.FromSlash("/original/path"), projects[0].ActualPath)
+
+	// Touching the session file invalidates the entry
+	later := info.ModTime().Add(time.Minute)
+	require.NoError(t, os.Chtimes(sessionFile, later, later))
+
+	projects, _, err = ScanProjectsWithOptions(context.Background(), projectsDir, ScanOptions{Cache: cache})
+	require.NoError(t, err)
+	require.Len(t, projects, 1)
+	assert.Equal(t, filepath.FromSlash("/modified/path"), projects[0].ActualPath)
+}
+
+func TestScanProjectsWithOptions_CachePrunesRemovedProjects(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectsDir := filepath.Join(tmpDir, "projects")
+	projectDir := createTestProject(t, projectsDir, "-Users-test-myproject", "/some/path")
+
+	cache := LoadProjectCache(filepath.Join(tmpDir, "cache.json"))
+	_, _, err := ScanProjectsWithOptions(context.Background(), projectsDir, ScanOptions{Cache: cache})
+	require.NoError(t, err)
+	assert.Len(t, cache.Entries, 1)
+
+	require.NoError(t, os.RemoveAll(projectDir))
+	projects, _, err := ScanProjectsWithOptions(context.Background(), projectsDir, ScanOptions{Cache: cache})
+	require.NoError(t, err)
+	assert.Empty(t, projects)
+	assert.Empty(t, cache.Entries)
+}
+
+func TestLoadProjectCache_Corrupt(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+	require.NoError(t, os.WriteFile(cachePath, []byte("not json"), 0600))
+
+	cache := LoadProjectCache(cachePath)
+	assert.Empty(t, cache.Entries)
+}
+
+func TestClearProjectCache(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+	require.NoError(t, os.WriteFile(cachePath, []byte("{}"), 0600))
+
+	require.NoError(t, ClearProjectCache(cachePath))
+	assert.NoFileExists(t, cachePath)
+
+	// Clearing a missing cache is fine
+	require.NoError(t, ClearProjectCache(cachePath))
+}
@@ -9,6 +9,28 @@ import (
 // Settings represents Claude Code settings configuration.
 type Settings struct {
 	Permissions Permissions `json:"permissions"`
+
+	// NoPermissions is set by LoadSettings if the file has no permissions
+	// key at all, e.g. because it only configures env or hooks. Such files
+	// are never deduplicated, unlike files with empty permission lists.
+	NoPermissions bool `json:"-"`
+}
+
+// UnmarshalJSON decodes settings and records whether the permissions key
+// is present.
+func (s *Settings) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Permissions *Permissions `json:"permissions"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*s = Settings{NoPermissions: raw.Permissions == nil}
+	if raw.Permissions != nil {
+		s.Permissions = *raw.Permissions
+	}
+	return nil
 }
 
 // Permissions represents the permissions configuration.
@@ -98,6 +98,24 @@ func TestLoadSettings_PartialPermissions(t *testing.T) {
 	assert.Equal(t, []string{"Bash(git:*)"}, settings.Permissions.Allow)
 	assert.Empty(t, settings.Permissions.Deny)
 	assert.Empty(t, settings.Permissions.Ask)
+	assert.False(t, settings.NoPermissions)
+}
+
+func TestLoadSettings_NoPermissionsKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	settingsPath := filepath.Join(tmpDir, "settings.json")
+	require.NoError(t, os.WriteFile(settingsPath, []byte(`{"env": {"FOO": "bar"}, "hooks": {}}`), 0644))
+
+	settings, err := LoadSettings(settingsPath)
+	require.NoError(t, err)
+	assert.True(t, settings.NoPermissions)
+	assert.True(t, settings.IsEmpty())
+
+	// An empty permissions section is still a permissions section
+	require.NoError(t, os.WriteFile(settingsPath, []byte(`{"permissions": {}}`), 0644))
+	settings, err = LoadSettings(settingsPath)
+	require.NoError(t, err)
+	assert.False(t, settings.NoPermissions)
 }
 
 func TestSettings_Diff_AllUnique(t *testing.T) {
@@ -1,10 +1,21 @@
 package claude
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 )
 
+// ErrUnsafeRoot is returned by DiscoverPaths if the Claude root resolves to a
+// directory that must never be scanned or cleaned, like / or the home
+// directory itself.
+var ErrUnsafeRoot = errors.New("refusing to use unsafe Claude directory")
+
+// ErrNotDirectory is returned if a Claude directory exists but is not a
+// directory, e.g. because ~/.claude/projects was replaced by a file.
+var ErrNotDirectory = errors.New("expected directory, found file")
+
 // Paths contains the standard Claude Code directory paths.
 type Paths struct {
 	Root        string // ~/.claude
@@ -12,11 +23,24 @@ type Paths struct {
 	Todos       string // ~/.claude/todos
 	FileHistory string // ~/.claude/file-history
 	SessionEnv  string // ~/.claude/session-env
-	Settings    string // ~/.claude/settings.json
+	Logs        string // ~/.claude/logs
+	Settings    string // ~/.claude/settings.json, or the first alternate location that exists
+
+	// SettingsFound reports whether Settings exists. If no candidate
+	// location exists, Settings is the default ~/.claude/settings.json.
+	SettingsFound bool
+}
+
+// settingsCandidates lists the known locations of the global settings file,
+// relative to the Claude root, in order of preference.
+var settingsCandidates = []string{
+	"settings.json",
+	filepath.Join("settings", "settings.json"),
 }
 
 // DiscoverPaths returns the Claude Code paths for the current user.
-// If claudeHome is empty, it uses the default ~/.claude location.
+// If claudeHome is empty, it uses the default ~/.claude location. It returns
+// ErrUnsafeRoot if the root is a filesystem root or the home directory.
 func DiscoverPaths(claudeHome string) (*Paths, error) {
 	root := claudeHome
 	if root == "" {
@@ -27,12 +51,77 @@ func DiscoverPaths(claudeHome string) (*Paths, error) {
 		root = filepath.Join(home, ".claude")
 	}
 
-	return &Paths{
-		Root:        root,
-		Projects:    filepath.Join(root, "projects"),
-		Todos:       filepath.Join(root, "todos"),
-		FileHistory: filepath.Join(root, "file-history"),
-		SessionEnv:  filepath.Join(root, "session-env"),
-		Settings:    filepath.Join(root, "settings.json"),
-	}, nil
+	if err := checkRoot(root, claudeHome != ""); err != nil {
+		return nil, err
+	}
+
+	settings, found := resolveSettings(root)
+
+	paths := &Paths{
+		Root:          root,
+		Projects:      filepath.Join(root, "projects"),
+		Todos:         filepath.Join(root, "todos"),
+		FileHistory:   filepath.Join(root, "file-history"),
+		SessionEnv:    filepath.Join(root, "session-env"),
+		Logs:          filepath.Join(root, "logs"),
+		Settings:      settings,
+		SettingsFound: found,
+	}
+
+	for _, dir := range []string{paths.Projects, paths.Todos, paths.FileHistory, paths.SessionEnv, paths.Logs} {
+		if err := checkDir(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	return paths, nil
+}
+
+// checkDir returns a descriptive error if path exists but cannot be used as
+// a directory: it is a file, or a symlink that is part of a loop. A
+// missing path is fine, e.g. on a fresh install.
+func checkDir(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot use %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s: %w", path, ErrNotDirectory)
+	}
+	return nil
+}
+
+// checkRoot verifies that root is safe to operate on: not a filesystem root,
+// not the home directory, and named .claude unless explicitly given.
+func checkRoot(root string, explicit bool) error {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+
+	if filepath.Dir(abs) == abs {
+		return fmt.Errorf("%w: %s is a filesystem root", ErrUnsafeRoot, abs)
+	}
+	if home, err := os.UserHomeDir(); err == nil && filepath.Clean(home) == abs {
+		return fmt.Errorf("%w: %s is the home directory", ErrUnsafeRoot, abs)
+	}
+	if !explicit && filepath.Base(abs) != ".claude" {
+		return fmt.Errorf("%w: %s is not a .claude directory", ErrUnsafeRoot, abs)
+	}
+	return nil
+}
+
+// resolveSettings returns the first settings candidate under root that
+// exists, or the default location and false if none does.
+func resolveSettings(root string) (string, bool) {
+	for _, candidate := range settingsCandidates {
+		path := filepath.Join(root, candidate)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return filepath.Join(root, settingsCandidates[0]), false
 }
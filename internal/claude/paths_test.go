@@ -43,3 +43,84 @@ func TestDiscoverPaths_AllPathsPopulated(t *testing.T) {
 	assert.NotEmpty(t, paths.SessionEnv, "SessionEnv path should not be empty")
 	assert.NotEmpty(t, paths.Settings, "Settings path should not be empty")
 }
+
+func TestDiscoverPaths_SettingsLayouts(t *testing.T) {
+	t.Run("default layout", func(t *testing.T) {
+		root := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(root, "settings.json"), []byte(`{}`), 0644))
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "settings"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, "settings", "settings.json"), []byte(`{}`), 0644))
+
+		paths, err := DiscoverPaths(root)
+		require.NoError(t, err)
+
+		assert.Equal(t, filepath.Join(root, "settings.json"), paths.Settings)
+		assert.True(t, paths.SettingsFound)
+	})
+
+	t.Run("settings directory layout", func(t *testing.T) {
+		root := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "settings"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, "settings", "settings.json"), []byte(`{}`), 0644))
+
+		paths, err := DiscoverPaths(root)
+		require.NoError(t, err)
+
+		assert.Equal(t, filepath.Join(root, "settings", "settings.json"), paths.Settings)
+		assert.True(t, paths.SettingsFound)
+	})
+
+	t.Run("no settings", func(t *testing.T) {
+		root := t.TempDir()
+
+		paths, err := DiscoverPaths(root)
+		require.NoError(t, err)
+
+		assert.Equal(t, filepath.Join(root, "settings.json"), paths.Settings)
+		assert.False(t, paths.SettingsFound)
+	})
+}
+
+func TestDiscoverPaths_RefusesUnsafeRoot(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	for _, root := range []string{string(filepath.Separator), home} {
+		_, err := DiscoverPaths(root)
+		assert.ErrorIs(t, err, ErrUnsafeRoot, root)
+	}
+
+	paths, err := DiscoverPaths("")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".claude"), paths.Root)
+}
+
+func TestDiscoverPaths_ProjectsIsFile(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "projects"), []byte("oops"), 0644))
+
+	_, err := DiscoverPaths(root)
+	require.ErrorIs(t, err, ErrNotDirectory)
+	assert.Contains(t, err.Error(), filepath.Join(root, "projects")+": expected directory, found file")
+
+	_, err = ScanProjects(filepath.Join(root, "projects"))
+	require.ErrorIs(t, err, ErrNotDirectory)
+}
+
+func TestDiscoverPaths_SymlinkLoop(t *testing.T) {
+	root := t.TempDir()
+	todos := filepath.Join(root, "todos")
+	if err := os.Symlink(todos, todos); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+
+	_, err := DiscoverPaths(root)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot use "+todos)
+}
+
+func TestDiscoverPaths_MissingDirectoriesAreFine(t *testing.T) {
+	_, err := DiscoverPaths(t.TempDir())
+	require.NoError(t, err)
+}
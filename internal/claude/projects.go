@@ -1,19 +1,26 @@
 package claude
 
 import (
+	"context"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"time"
 )
 
 // Project represents a Claude Code project with its session data.
 type Project struct {
-	EncodedName string    // Directory name: -Users-mhk-Code-ccc
-	ActualPath  string    // From cwd field: /Users/mhk/Code/ccc
-	SessionIDs  []string  // UUIDs of sessions in this project
-	TotalSize   int64     // Bytes used by session files
-	LastUsed    time.Time // Most recent session timestamp
-	FileCount   int       // Number of session files
+	EncodedName  string    // Directory name: -Users-mhk-Code-ccc
+	ActualPath   string    // From cwd field: /Users/mhk/Code/ccc
+	SessionIDs   []string  // UUIDs of sessions in this project
+	TotalSize    int64     // Bytes used by session files
+	LastUsed     time.Time // Most recent session timestamp
+	LastModified time.Time // Most recent session file modification time
+	FileCount    int       // Number of session files
+	GitBranch    string    // Git branch recorded by the most recently used session
 }
 
 // Exists checks if the project's actual path exists on disk.
@@ -25,63 +32,271 @@ func (p *Project) Exists() bool {
 	return err == nil
 }
 
+// HasOnlyEmptySessions reports whether all of the project's session files
+// are empty (0 bytes), so removing it loses nothing of value.
+func (p *Project) HasOnlyEmptySessions() bool {
+	return p.FileCount > 0 && p.TotalSize == 0
+}
+
+// DecodeProjectName makes a best-effort guess at the path a project
+// directory name was encoded from. Claude Code replaces path separators (and
+// the colon of Windows drive letters) with "-", so hyphens in the original
+// path are decoded as separators too. Use it for display only, when no
+// session provides the real cwd.
+//
+//	-Users-mhk-Code-ccc  ->  /Users/mhk/Code/ccc
+//	C--Users-me-Code     ->  C:\Users\me\Code
+func DecodeProjectName(encoded string) string {
+	if len(encoded) >= 3 && isDriveLetter(encoded[0]) && encoded[1:3] == "--" {
+		return encoded[:1] + `:\` + strings.ReplaceAll(encoded[3:], "-", `\`)
+	}
+	if strings.HasPrefix(encoded, "-") {
+		return strings.ReplaceAll(encoded, "-", "/")
+	}
+	return encoded
+}
+
+// NormalizePath cleans a path recorded in a session so that paths compare and
+// display consistently: separators are normalized and trailing or duplicate
+// separators and "." and ".." segments are removed. Windows drive paths are
+// normalized to backslashes on every OS, so sessions copied between machines
+// still compare equal. Symlinks are not resolved. An empty path stays empty.
+//
+//	/Users/mhk/Code/ccc/      ->  /Users/mhk/Code/ccc
+//	/Users/mhk/Code/x/../ccc  ->  /Users/mhk/Code/ccc
+//	C:/Users/me\Code\         ->  C:\Users\me\Code
+func NormalizePath(p string) string {
+	if p == "" {
+		return ""
+	}
+	if len(p) >= 2 && isDriveLetter(p[0]) && p[1] == ':' {
+		rest := strings.ReplaceAll(p[2:], `\`, "/")
+		if rest != "" {
+			rest = path.Clean(rest)
+		}
+		return p[:2] + strings.ReplaceAll(rest, "/", `\`)
+	}
+	return filepath.Clean(filepath.FromSlash(p))
+}
+
+// isDriveLetter reports whether c is an ASCII letter.
+func isDriveLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
 // ScanProjects scans the projects directory and returns information about each project.
 func ScanProjects(projectsDir string) ([]Project, error) {
+	projects, _, err := ScanProjectsWithOptions(context.Background(), projectsDir, ScanOptions{})
+	return projects, err
+}
+
+// ScanWarning describes a project directory that was skipped because it
+// could not be read, e.g. due to missing permissions.
+type ScanWarning struct {
+	Path string
+	Err  error
+}
+
+// ScanOptions customizes ScanProjectsWithOptions. The zero value scans
+// without a cache and collects all projects.
+type ScanOptions struct {
+	// Cache reuses the metadata of project directories that have not
+	// changed since they were cached, and records freshly scanned ones.
+	// Nil disables caching.
+	Cache *ProjectCache
+	// Concurrency is the number of project directories scanned in
+	// parallel. Zero means runtime.NumCPU(); 1 scans one at a time.
+	Concurrency int
+	// Each, if set, is called for each project as soon as it is scanned
+	// instead of collecting the projects, so memory stays flat on installs
+	// with thousands of projects. It is called from the calling goroutine
+	// and in directory order, so only the I/O is parallel. Scanning stops at
+	// the first error returned by Each, which is then returned.
+	Each func(Project) error
+}
+
+// projectScan is the result of scanning one project directory.
+type projectScan struct {
+	project Project
+	fp      projectFingerprint
+	cached  bool // project was taken from the cache
+	err     error
+}
+
+// ScanProjectsWithOptions is like ScanProjects but configured by opts and
+// stops when ctx is done, returning the projects scanned so far together
+// with ctx.Err(). It also returns the project directories that were skipped
+// because they could not be read; these warnings are not fatal. A missing
+// projects directory (e.g. on a fresh install) has no projects.
+func ScanProjectsWithOptions(ctx context.Context, projectsDir string, opts ScanOptions) ([]Project, []ScanWarning, error) {
+	var projects []Project
+	fn := opts.Each
+	if fn == nil {
+		fn = func(p Project) error {
+			projects = append(projects, p)
+			return nil
+		}
+	}
+	cache := opts.Cache
+	concurrency := opts.Concurrency
+	if concurrency == 0 {
+		concurrency = runtime.NumCPU()
+	}
+
 	entries, err := os.ReadDir(projectsDir)
 	if err != nil {
-		return nil, err
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		if dirErr := checkDir(projectsDir); dirErr != nil {
+			return nil, nil, dirErr
+		}
+		return nil, nil, err
 	}
 
-	var projects []Project
+	var names []string
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Every directory has its own result channel so results can be consumed
+	// in order. A slot is only freed once its result has been consumed,
+	// which bounds the number of scanned but unconsumed projects.
+	results := make([]chan projectScan, len(names))
+	for i := range results {
+		results[i] = make(chan projectScan, 1)
+	}
+	slots := make(chan struct{}, max(concurrency, 1))
+	go func() {
+		for i, name := range names {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func() {
+				results[i] <- scanProjectDir(ctx, projectsDir, name, cache)
+			}()
+		}
+	}()
+
+	var warnings []ScanWarning
+	seen := make(map[string]struct{})
+	for i, name := range names {
+		if err := ctx.Err(); err != nil {
+			return projects, warnings, err
+		}
+
+		var r projectScan
+		select {
+		case r = <-results[i]:
+			<-slots
+		case <-ctx.Done():
+			return projects, warnings, ctx.Err()
+		}
+
+		seen[name] = struct{}{}
+		if r.err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return projects, warnings, ctxErr
+			}
+			warnings = append(warnings, ScanWarning{Path: filepath.Join(projectsDir, name), Err: r.err})
 			continue
 		}
 
-		projectPath := filepath.Join(projectsDir, entry.Name())
-		project := Project{
-			EncodedName: entry.Name(),
+		if cache != nil && !r.cached {
+			cache.store(name, r.fp, r.project)
+		}
+		if err := fn(r.project); err != nil {
+			return projects, warnings, err
+		}
+	}
+
+	if cache != nil {
+		cache.prune(seen)
+	}
+
+	return projects, warnings, nil
+}
+
+// scanProjectDir scans the project directory name, taking the project from
+// the cache if its fingerprint is unchanged.
+func scanProjectDir(ctx context.Context, projectsDir, name string, cache *ProjectCache) projectScan {
+	projectPath := filepath.Join(projectsDir, name)
+
+	var r projectScan
+	if cache != nil {
+		r.fp, r.err = fingerprintProjectDir(projectPath)
+		if r.err != nil {
+			return r
+		}
+		if project, ok := cache.lookup(name, r.fp); ok {
+			r.project, r.cached = project, true
+			return r
+		}
+	}
+
+	r.project, r.err = scanProject(ctx, projectPath, name)
+	return r
+}
+
+// scanProject parses the session files of a single project directory.
+func scanProject(ctx context.Context, projectPath, encodedName string) (Project, error) {
+	project := Project{
+		EncodedName: encodedName,
+	}
+
+	// Scan session files in the project directory
+	sessionEntries, err := os.ReadDir(projectPath)
+	if err != nil {
+		return project, err
+	}
+
+	for _, sessionEntry := range sessionEntries {
+		if err := ctx.Err(); err != nil {
+			return project, err
+		}
+		if sessionEntry.IsDir() {
+			continue
+		}
+		if !IsSessionFile(sessionEntry.Name()) {
+			continue
 		}
 
-		// Scan session files in the project directory
-		sessionEntries, err := os.ReadDir(projectPath)
+		sessionPath := filepath.Join(projectPath, sessionEntry.Name())
+		info, err := ParseSessionFile(sessionPath)
 		if err != nil {
 			continue
 		}
 
-		for _, sessionEntry := range sessionEntries {
-			if sessionEntry.IsDir() {
-				continue
-			}
-			if filepath.Ext(sessionEntry.Name()) != ".jsonl" {
-				continue
-			}
+		project.FileCount++
+		project.TotalSize += info.Size
 
-			sessionPath := filepath.Join(projectPath, sessionEntry.Name())
-			info, err := ParseSessionFile(sessionPath)
-			if err != nil {
-				continue
+		if !info.IsEmpty {
+			if project.ActualPath == "" {
+				project.ActualPath = NormalizePath(info.CWD)
 			}
-
-			project.FileCount++
-			project.TotalSize += info.Size
-
-			if !info.IsEmpty {
-				if project.ActualPath == "" {
-					// Normalize path separators for the current OS
-					project.ActualPath = filepath.FromSlash(info.CWD)
-				}
-				if info.ID != "" {
-					project.SessionIDs = append(project.SessionIDs, info.ID)
-				}
-				if info.Timestamp.After(project.LastUsed) {
-					project.LastUsed = info.Timestamp
+			for _, id := range info.IDs {
+				if !slices.Contains(project.SessionIDs, id) {
+					project.SessionIDs = append(project.SessionIDs, id)
 				}
 			}
+			if info.GitBranch != "" && (project.GitBranch == "" || info.Timestamp.After(project.LastUsed)) {
+				project.GitBranch = info.GitBranch
+			}
+			if info.Timestamp.After(project.LastUsed) {
+				project.LastUsed = info.Timestamp
+			}
+			if info.ModTime.After(project.LastModified) {
+				project.LastModified = info.ModTime
+			}
 		}
-
-		projects = append(projects, project)
 	}
 
-	return projects, nil
+	return project, nil
 }
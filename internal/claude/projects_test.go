@@ -1,9 +1,15 @@
 package claude
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -51,6 +57,13 @@ func TestScanProjects_EmptyDirectory(t *testing.T) {
 	assert.Empty(t, projects)
 }
 
+func TestScanProjects_MissingDirectory(t *testing.T) {
+	projects, err := ScanProjects(filepath.Join(t.TempDir(), "projects"))
+	require.NoError(t, err)
+
+	assert.Empty(t, projects)
+}
+
 func TestScanProjects_SingleProject(t *testing.T) {
 	tmpDir := t.TempDir()
 	existingPath := t.TempDir()
@@ -93,6 +106,20 @@ func TestScanProjects_CalculatesSize(t *testing.T) {
 	assert.Equal(t, int64(len(content)), projects[0].TotalSize)
 }
 
+func TestScanProjects_LastModified(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := createTestProject(t, tmpDir, "-Users-test-mtime", "/tmp/test")
+	modTime := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(projectDir, "session.jsonl"), modTime, modTime))
+
+	projects, err := ScanProjects(tmpDir)
+	require.NoError(t, err)
+
+	require.Len(t, projects, 1)
+	assert.True(t, modTime.Equal(projects[0].LastModified))
+	assert.Equal(t, time.Date(2025, 12, 6, 10, 0, 0, 0, time.UTC), projects[0].LastUsed)
+}
+
 func TestScanProjects_CountsFiles(t *testing.T) {
 	tmpDir := t.TempDir()
 	projectDir := filepath.Join(tmpDir, "-Users-test-multifile")
@@ -141,3 +168,211 @@ func TestScanProjects_HandlesEmptySessionFiles(t *testing.T) {
 	require.Len(t, projects, 1)
 	assert.Empty(t, projects[0].ActualPath, "expected empty actual path for project with only empty session files")
 }
+
+func TestScanProjectsWithOptions_Cancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestProject(t, tmpDir, "-Users-test-myproject", t.TempDir())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	projects, _, err := ScanProjectsWithOptions(ctx, tmpDir, ScanOptions{})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, projects)
+}
+
+func TestDecodeProjectName(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    string
+	}{
+		{"-Users-mhk-Code-ccc", "/Users/mhk/Code/ccc"},
+		{"-home-me", "/home/me"},
+		{"C--Users-me-Code", `C:\Users\me\Code`},
+		{"d--work", `d:\work`},
+		{"plain", "plain"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			assert.Equal(t, tt.want, DecodeProjectName(tt.encoded))
+		})
+	}
+}
+
+func TestScanProjectsWithOptions_StopsOnCallbackError(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"-a", "-b", "-c"} {
+		dir := filepath.Join(tmpDir, name)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "s.jsonl"), []byte(`{"sessionId":"s","cwd":"/x"}`), 0644))
+	}
+
+	errStop := errors.New("stop")
+	var visited []string
+	_, _, err := ScanProjectsWithOptions(context.Background(), tmpDir, ScanOptions{Each: func(p Project) error {
+		visited = append(visited, p.EncodedName)
+		if len(visited) == 2 {
+			return errStop
+		}
+		return nil
+	}})
+
+	assert.ErrorIs(t, err, errStop)
+	assert.Len(t, visited, 2)
+}
+
+func TestScanProjectsWithOptions_KeepsDirectoryOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+	var expected []string
+	for i := range 20 {
+		name := fmt.Sprintf("-project-%02d", i)
+		createTestProject(t, tmpDir, name, "/"+name)
+		expected = append(expected, name)
+	}
+
+	for _, concurrency := range []int{1, 4, 64} {
+		cache := LoadProjectCache(filepath.Join(t.TempDir(), "cache.json"))
+		var visited []string
+		_, _, err := ScanProjectsWithOptions(context.Background(), tmpDir, ScanOptions{
+			Cache:       cache,
+			Concurrency: concurrency,
+			Each: func(p Project) error {
+				visited = append(visited, p.EncodedName)
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, expected, visited, "concurrency %d", concurrency)
+
+		// Collected projects keep the directory order too
+		projects, _, err := ScanProjectsWithOptions(context.Background(), tmpDir, ScanOptions{Concurrency: concurrency})
+		require.NoError(t, err)
+		var collected []string
+		for _, p := range projects {
+			collected = append(collected, p.EncodedName)
+		}
+		assert.Equal(t, expected, collected, "concurrency %d", concurrency)
+		assert.Len(t, cache.Entries, len(expected))
+	}
+}
+
+func TestScanProjectsWithOptions_UnreadableProject(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestProject(t, tmpDir, "-readable", "/readable")
+	locked := filepath.Join(tmpDir, "-locked")
+	require.NoError(t, os.MkdirAll(locked, 0755))
+	require.NoError(t, os.Chmod(locked, 0))
+	defer os.Chmod(locked, 0755)
+	if _, err := os.ReadDir(locked); err == nil {
+		t.Skip("directory permissions are not enforced (e.g. running as root)")
+	}
+
+	projects, warnings, err := ScanProjectsWithOptions(context.Background(), tmpDir, ScanOptions{})
+
+	require.NoError(t, err)
+	require.Len(t, projects, 1)
+	assert.Equal(t, "-readable", projects[0].EncodedName)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, locked, warnings[0].Path)
+	assert.ErrorIs(t, warnings[0].Err, os.ErrPermission)
+}
+
+func TestScanProjects_CollectsSessionIDsFromAllLines(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "-work")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	content := `{"cwd":"/work"}` + "\n" +
+		`{"type":"progress"}` + "\n" +
+		`{"sessionId":"line-three","cwd":"/work"}` + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(content), 0644))
+
+	projects, err := ScanProjects(tmpDir)
+	require.NoError(t, err)
+
+	require.Len(t, projects, 1)
+	assert.Equal(t, []string{"line-three"}, projects[0].SessionIDs)
+}
+
+func TestScanProjects_GzippedSessions(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "-Users-test-compressed")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(`{"sessionId":"old","cwd":"/Users/test/compressed","timestamp":"2024-01-01T00:00:00Z"}` + "\n"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "old.jsonl.gz"), buf.Bytes(), 0644))
+
+	projects, err := ScanProjects(tmpDir)
+	require.NoError(t, err)
+
+	require.Len(t, projects, 1)
+	assert.Equal(t, filepath.FromSlash("/Users/test/compressed"), projects[0].ActualPath)
+	assert.Equal(t, []string{"old"}, projects[0].SessionIDs)
+	assert.Equal(t, int64(buf.Len()), projects[0].TotalSize)
+	assert.Equal(t, 1, projects[0].FileCount)
+}
+
+func TestNormalizePath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"empty", "", ""},
+		{"posix clean", "/Users/mhk/Code/ccc", filepath.FromSlash("/Users/mhk/Code/ccc")},
+		{"posix trailing slash", "/Users/mhk/Code/ccc/", filepath.FromSlash("/Users/mhk/Code/ccc")},
+		{"posix duplicate separators", "/Users//mhk///Code/ccc", filepath.FromSlash("/Users/mhk/Code/ccc")},
+		{"posix dot segments", "/Users/mhk/./Code/other/../ccc", filepath.FromSlash("/Users/mhk/Code/ccc")},
+		{"posix root", "/", filepath.FromSlash("/")},
+		{"windows clean", `C:\Users\me\Code`, `C:\Users\me\Code`},
+		{"windows forward slashes", "C:/Users/me/Code", `C:\Users\me\Code`},
+		{"windows mixed separators", `C:/Users\me/Code\`, `C:\Users\me\Code`},
+		{"windows dot segments", `d:\work\.\tmp\..\repo`, `d:\work\repo`},
+		{"windows drive root", `C:\`, `C:\`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizePath(tt.path))
+		})
+	}
+}
+
+func TestScanProjects_NormalizesActualPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "-Users-test-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	content := `{"sessionId":"s1","cwd":"/Users/test/tmp/../project/"}`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(content), 0644))
+
+	projects, err := ScanProjects(tmpDir)
+	require.NoError(t, err)
+
+	require.Len(t, projects, 1)
+	assert.Equal(t, filepath.FromSlash("/Users/test/project"), projects[0].ActualPath)
+}
+
+func TestScanProjects_GitBranchOfLatestSession(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "-work")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	sessions := map[string]string{
+		"old.jsonl":      `{"sessionId":"old","cwd":"/work","timestamp":"2025-01-01T00:00:00Z","gitBranch":"main"}`,
+		"new.jsonl":      `{"sessionId":"new","cwd":"/work","timestamp":"2025-02-01T00:00:00Z","gitBranch":"feature/login"}`,
+		"nobranch.jsonl": `{"sessionId":"nobranch","cwd":"/work","timestamp":"2025-03-01T00:00:00Z"}`,
+	}
+	for name, content := range sessions {
+		require.NoError(t, os.WriteFile(filepath.Join(projectDir, name), []byte(content), 0644))
+	}
+
+	projects, err := ScanProjects(tmpDir)
+	require.NoError(t, err)
+
+	require.Len(t, projects, 1)
+	assert.Equal(t, "feature/login", projects[0].GitBranch)
+}
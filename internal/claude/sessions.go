@@ -2,18 +2,26 @@ package claude
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
 // SessionInfo contains metadata extracted from a session file.
 type SessionInfo struct {
-	ID        string
+	ID        string   // Session ID of the first line with a cwd
+	IDs       []string // All distinct session IDs in the file, in order of appearance
 	CWD       string
 	Timestamp time.Time
+	GitBranch string    // Git branch of the last line that recorded one
+	ModTime   time.Time // Modification time of the session file
 	FilePath  string
 	Size      int64
 	IsEmpty   bool
@@ -21,15 +29,81 @@ type SessionInfo struct {
 
 // sessionLine represents a single line from a session JSONL file.
 type sessionLine struct {
-	SessionID string    `json:"sessionId"`
-	CWD       string    `json:"cwd"`
-	Timestamp time.Time `json:"timestamp"`
+	SessionID string      `json:"sessionId"`
+	CWD       string      `json:"cwd"`
+	Timestamp sessionTime `json:"timestamp"`
+	GitBranch string      `json:"gitBranch"`
 }
 
+// sessionTime is the timestamp of a session line, which is recorded either
+// as an RFC 3339 string or as milliseconds since the Unix epoch.
+type sessionTime struct {
+	time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *sessionTime) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || data[0] == '"' || string(data) == "null" {
+		return t.Time.UnmarshalJSON(data)
+	}
+
+	var millis float64
+	if err := json.Unmarshal(data, &millis); err != nil {
+		return fmt.Errorf("invalid timestamp %s: %w", data, err)
+	}
+	t.Time = time.UnixMilli(int64(millis)).UTC()
+	return nil
+}
+
+// Session file extensions. Old sessions may have been gzip-compressed to save
+// space, which is read transparently.
+const (
+	sessionExt           = ".jsonl"
+	compressedSessionExt = ".jsonl.gz"
+)
+
+// IsSessionFile reports whether name is a session file, either plain
+// (.jsonl) or gzip-compressed (.jsonl.gz).
+func IsSessionFile(name string) bool {
+	return strings.HasSuffix(name, sessionExt) || strings.HasSuffix(name, compressedSessionExt)
+}
+
+// SessionFileID returns the session file name without its extension, which
+// is the session ID for files written by Claude Code.
+func SessionFileID(name string) string {
+	if id, ok := strings.CutSuffix(name, compressedSessionExt); ok {
+		return id
+	}
+	return strings.TrimSuffix(name, sessionExt)
+}
+
+// utf8BOM is the byte order mark some editors write at the start of a file.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
 // ErrNoCWD is returned when no cwd field can be found in session files.
 var ErrNoCWD = errors.New("no cwd field found in session files")
 
-// ParseSessionFile reads a session JSONL file and extracts metadata.
+// SessionParseError reports a line in a session file that could not be read
+// or parsed.
+type SessionParseError struct {
+	Line int // 1-based line number
+	Err  error
+}
+
+func (e *SessionParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *SessionParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseSessionFile reads a session JSONL file and extracts metadata. The cwd
+// and timestamp come from the first line with a cwd, while the session IDs
+// are collected from all lines. Malformed lines are skipped; if no line has a
+// cwd, the error for the first malformed line is returned, or ErrNoCWD if
+// there was none. A leading UTF-8 byte order mark and blank lines are
+// ignored.
 func ParseSessionFile(path string) (*SessionInfo, error) {
 	stat, err := os.Stat(path)
 	if err != nil {
@@ -39,6 +113,7 @@ func ParseSessionFile(path string) (*SessionInfo, error) {
 	info := &SessionInfo{
 		FilePath: path,
 		Size:     stat.Size(),
+		ModTime:  stat.ModTime(),
 	}
 
 	if stat.Size() == 0 {
@@ -46,36 +121,189 @@ func ParseSessionFile(path string) (*SessionInfo, error) {
 		return info, nil
 	}
 
+	seen := make(map[string]struct{})
+	var parseErr error
+	err = readSessionLines(path, func(lineNum int, line []byte) error {
+		var sl sessionLine
+		if err := json.Unmarshal(line, &sl); err != nil {
+			// Skip malformed lines, such as a truncated first line or the
+			// partial last line of a session that is still being written,
+			// so the cwd and session IDs of the other lines are kept
+			if parseErr == nil {
+				parseErr = &SessionParseError{Line: lineNum, Err: err}
+			}
+			return nil
+		}
+
+		if sl.SessionID != "" {
+			if _, ok := seen[sl.SessionID]; !ok {
+				seen[sl.SessionID] = struct{}{}
+				info.IDs = append(info.IDs, sl.SessionID)
+			}
+		}
+		if sl.GitBranch != "" {
+			info.GitBranch = sl.GitBranch
+		}
+		if sl.CWD != "" && info.CWD == "" {
+			info.ID = sl.SessionID
+			info.CWD = sl.CWD
+			info.Timestamp = sl.Timestamp.Time
+		}
+		return nil
+	})
+	// A read error after the cwd was found keeps what was read before it
+	if err != nil && info.CWD == "" {
+		return nil, err
+	}
+
+	if info.CWD == "" {
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		return nil, ErrNoCWD
+	}
+	return info, nil
+}
+
+// ValidateSessionFile checks every line of a session file, unlike
+// ParseSessionFile, which skips malformed lines. It
+// returns a *SessionParseError for the first line that is not a valid
+// session line, or nil if all lines are valid.
+func ValidateSessionFile(path string) error {
+	return readSessionLines(path, func(lineNum int, line []byte) error {
+		var sl sessionLine
+		if err := json.Unmarshal(line, &sl); err != nil {
+			return &SessionParseError{Line: lineNum, Err: err}
+		}
+		return nil
+	})
+}
+
+// readSessionLines calls fn with the 1-based number and content of each
+// non-blank line of the session file at path, reading gzip-compressed files
+// transparently and dropping a leading UTF-8 byte order mark. It stops at the
+// first error of fn and returns it; read errors are returned as
+// *SessionParseError.
+func readSessionLines(path string, fn func(lineNum int, line []byte) error) error {
 	cleanPath := filepath.Clean(path)
 	file, err := os.Open(cleanPath) // #nosec G304 -- path is sanitized with filepath.Clean
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		if len(line) == 0 {
-			continue
+	var r io.Reader = file
+	if strings.HasSuffix(path, compressedSessionExt) {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return &SessionParseError{Line: 1, Err: err}
 		}
+		defer gz.Close()
+		r = gz
+	}
 
-		var sl sessionLine
-		if err := json.Unmarshal(line, &sl); err != nil {
-			return nil, err
+	// Read lines of any length; transcript lines can be very large
+	reader := bufio.NewReader(r)
+	for lineNum := 1; ; lineNum++ {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return &SessionParseError{Line: lineNum, Err: readErr}
+		}
+		if lineNum == 1 {
+			line = bytes.TrimPrefix(line, utf8BOM)
 		}
 
-		if sl.CWD != "" {
-			info.ID = sl.SessionID
-			info.CWD = sl.CWD
-			info.Timestamp = sl.Timestamp
-			return info, nil
+		// Blank lines, e.g. left by an editor, are skipped
+		if len(bytes.TrimSpace(line)) > 0 {
+			if err := fn(lineNum, line); err != nil {
+				return err
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
 		}
 	}
+}
+
+// ExtractCWD returns the normalized cwd of the most recently modified
+// session file in projectDir that records one, or ErrNoCWD if none does.
+// Unlike a full scan, it only looks at the newest session, which is the one a
+// running Claude Code would be writing to.
+func ExtractCWD(projectDir string) (string, error) {
+	entries, err := os.ReadDir(projectDir)
+	if err != nil {
+		return "", err
+	}
 
-	if err := scanner.Err(); err != nil {
+	var newest *SessionInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !IsSessionFile(entry.Name()) {
+			continue
+		}
+		info, err := ParseSessionFile(filepath.Join(projectDir, entry.Name()))
+		if err != nil || info.IsEmpty {
+			continue
+		}
+		if newest == nil || info.ModTime.After(newest.ModTime) {
+			newest = info
+		}
+	}
+
+	if newest == nil {
+		return "", ErrNoCWD
+	}
+	return NormalizePath(newest.CWD), nil
+}
+
+// SessionFile is a session file found by ScanSessions.
+type SessionFile struct {
+	Path    string
+	Project string // Encoded name of the project directory
+	Size    int64
+	ModTime time.Time
+}
+
+// ScanSessions returns the session files of all projects in projectsDir, in
+// directory order. Files are only stat'ed, not parsed, so it stays quick on
+// installs with many large transcripts. Project directories that cannot be
+// read are skipped; a missing projects directory has no sessions.
+func ScanSessions(projectsDir string) ([]SessionFile, error) {
+	entries, err := os.ReadDir(projectsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
 		return nil, err
 	}
 
-	return nil, ErrNoCWD
+	var sessions []SessionFile
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		projectPath := filepath.Join(projectsDir, entry.Name())
+		sessionEntries, err := os.ReadDir(projectPath)
+		if err != nil {
+			continue
+		}
+
+		for _, sessionEntry := range sessionEntries {
+			if sessionEntry.IsDir() || !IsSessionFile(sessionEntry.Name()) {
+				continue
+			}
+			info, err := sessionEntry.Info()
+			if err != nil {
+				continue
+			}
+			sessions = append(sessions, SessionFile{
+				Path:    filepath.Join(projectPath, sessionEntry.Name()),
+				Project: entry.Name(),
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			})
+		}
+	}
+
+	return sessions, nil
 }
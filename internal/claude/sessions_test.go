@@ -3,7 +3,10 @@ package claude
 import (
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -41,6 +44,138 @@ func TestParseSessionFile_MalformedJSON(t *testing.T) {
 	assert.Error(t, err, "expected error for malformed JSON")
 }
 
+func TestParseSessionFile_MalformedJSONReportsLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"type":"summary"}` + "\n\n" + `{"cwd": broken` + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	_, err := ParseSessionFile(path)
+
+	var parseErr *SessionParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, 3, parseErr.Line)
+	assert.Contains(t, err.Error(), "line 3:")
+}
+
+func TestParseSessionFile_CollectsSessionIDsFromAllLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"type":"summary"}` + "\n" +
+		`{"sessionId":"first","cwd":"/work","timestamp":"2025-01-01T00:00:00Z"}` + "\n" +
+		`{"sessionId":"resumed","cwd":"/work"}` + "\n" +
+		`{"sessionId":"first"}` + "\n" +
+		`{"sessionId":"partial","cw`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	info, err := ParseSessionFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "first", info.ID)
+	assert.Equal(t, []string{"first", "resumed"}, info.IDs)
+	assert.Equal(t, "/work", info.CWD)
+}
+
+func TestParseSessionFile_SkipsMalformedMiddleLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"sessionId":"first","cwd":"/work"}` + "\n" +
+		`{"sessionId":"broken","timestamp":true}` + "\n" +
+		`not json` + "\n" +
+		`{"sessionId":"resumed"}` + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	info, err := ParseSessionFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"first", "resumed"}, info.IDs)
+}
+
+func TestParseSessionFile_SkipsMalformedFirstLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"sessionId":"trunc` + "\n" +
+		`{"sessionId":"first","cwd":"/work","timestamp":"2025-01-01T00:00:00Z"}` + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	info, err := ParseSessionFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "first", info.ID)
+	assert.Equal(t, "/work", info.CWD)
+}
+
+func TestValidateSessionFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("\xEF\xBB\xBF"+`{"sessionId":"s1","cwd":"/work"}`+"\n\n"+`{"sessionId":"s1"}`+"\n"), 0644))
+	assert.NoError(t, ValidateSessionFile(path))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"sessionId":"s1","cwd":"/work"}`+"\n"+`{"sessionId":"s1"}`+"\n"+`oops`+"\n"), 0644))
+	var parseErr *SessionParseError
+	require.ErrorAs(t, ValidateSessionFile(path), &parseErr)
+	assert.Equal(t, 3, parseErr.Line)
+}
+
+func TestParseSessionFile_TimestampFormats(t *testing.T) {
+	expected := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		timestamp string
+	}{
+		{"RFC 3339", `"2025-01-02T03:04:05Z"`},
+		{"epoch millis", strconv.FormatInt(expected.UnixMilli(), 10)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "session.jsonl")
+			content := `{"sessionId":"s","cwd":"/work","timestamp":` + tc.timestamp + `}`
+			require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+			info, err := ParseSessionFile(path)
+			require.NoError(t, err)
+			assert.True(t, expected.Equal(info.Timestamp), "got %v", info.Timestamp)
+		})
+	}
+}
+
+func TestSessionTime_UnmarshalJSON(t *testing.T) {
+	var st sessionTime
+	require.NoError(t, st.UnmarshalJSON([]byte("null")))
+	assert.True(t, st.IsZero())
+
+	assert.Error(t, st.UnmarshalJSON([]byte(`"yesterday"`)))
+	assert.Error(t, st.UnmarshalJSON([]byte(`true`)))
+}
+
+func TestParseSessionFile_SessionIDOnlyOnLaterLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"type":"summary"}` + "\n" +
+		`{"cwd":"/work"}` + "\n" +
+		`{"sessionId":"late"}` + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	info, err := ParseSessionFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"late"}, info.IDs)
+}
+
+func TestParseSessionFile_BOMAndBlankLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := "\xEF\xBB\xBF\n  \r\n" +
+		`  {"sessionId":"s1","cwd":"/work"}` + "\r\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	info, err := ParseSessionFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "/work", info.CWD)
+
+	// BOM directly in front of the first line
+	require.NoError(t, os.WriteFile(path, []byte("\xEF\xBB\xBF"+`{"sessionId":"s1","cwd":"/work"}`+"\n"), 0644))
+
+	info, err = ParseSessionFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "/work", info.CWD)
+}
+
 func TestParseSessionFile_MissingCWDField(t *testing.T) {
 	path := testdataPath(t, "no_cwd.jsonl")
 
@@ -66,3 +201,129 @@ func TestParseSessionFile_ReturnsFileSize(t *testing.T) {
 
 	assert.Equal(t, stat.Size(), info.Size)
 }
+
+func TestParseSessionFile_Gzipped(t *testing.T) {
+	path := testdataPath(t, "valid.jsonl.gz")
+
+	info, err := ParseSessionFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/Users/testuser/Code/myproject", info.CWD)
+	assert.Equal(t, "abc123", info.ID)
+
+	stat, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, stat.Size(), info.Size, "size should be the compressed size on disk")
+}
+
+func TestParseSessionFile_InvalidGzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.jsonl.gz")
+	require.NoError(t, os.WriteFile(path, []byte(`{"sessionId":"x","cwd":"/x"}`), 0644))
+
+	_, err := ParseSessionFile(path)
+	var parseErr *SessionParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, 1, parseErr.Line)
+}
+
+func TestSessionFileID(t *testing.T) {
+	assert.True(t, IsSessionFile("abc.jsonl"))
+	assert.True(t, IsSessionFile("abc.jsonl.gz"))
+	assert.False(t, IsSessionFile("abc.json"))
+	assert.Equal(t, "abc", SessionFileID("abc.jsonl"))
+	assert.Equal(t, "abc", SessionFileID("abc.jsonl.gz"))
+}
+
+func TestParseSessionFile_LinesLongerThan64KB(t *testing.T) {
+	// Tool output can make single transcript lines far larger than the 64KB
+	// default limit of bufio.Scanner
+	output := strings.Repeat("x", 1<<20)
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"long line before cwd", `{"type":"tool_result","content":"` + output + `"}` + "\n" +
+			`{"sessionId":"long","cwd":"/work"}` + "\n"},
+		{"long line with cwd", `{"sessionId":"long","cwd":"/work","content":"` + output + `"}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "session.jsonl")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+
+			info, err := ParseSessionFile(path)
+			require.NoError(t, err)
+
+			assert.Equal(t, "/work", info.CWD)
+			assert.Equal(t, []string{"long"}, info.IDs)
+		})
+	}
+}
+
+func TestParseSessionFile_GitBranch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"sessionId":"s1","cwd":"/work","gitBranch":"main"}` + "\n" +
+		`{"sessionId":"s1","cwd":"/work"}` + "\n" +
+		`{"sessionId":"s1","cwd":"/work","gitBranch":"feature/login"}` + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	info, err := ParseSessionFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "feature/login", info.GitBranch)
+}
+
+func TestExtractCWD_NewestSession(t *testing.T) {
+	dir := t.TempDir()
+	older := filepath.Join(dir, "older.jsonl")
+	newer := filepath.Join(dir, "newer.jsonl")
+	require.NoError(t, os.WriteFile(older, []byte(`{"cwd":"/old/project"}`), 0644))
+	require.NoError(t, os.WriteFile(newer, []byte(`{"cwd":"/new/project/"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty.jsonl"), nil, 0644))
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(older, past, past))
+
+	cwd, err := ExtractCWD(dir)
+	require.NoError(t, err)
+	assert.Equal(t, NormalizePath("/new/project"), cwd)
+}
+
+func TestExtractCWD_NoSessions(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty.jsonl"), nil, 0644))
+
+	_, err := ExtractCWD(dir)
+	assert.ErrorIs(t, err, ErrNoCWD)
+
+	_, err = ExtractCWD(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestScanSessions(t *testing.T) {
+	projectsDir := t.TempDir()
+	projectA := filepath.Join(projectsDir, "-a")
+	projectB := filepath.Join(projectsDir, "-b")
+	require.NoError(t, os.MkdirAll(filepath.Join(projectA, "subagents"), 0755))
+	require.NoError(t, os.MkdirAll(projectB, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(projectA, "s1.jsonl"), []byte("12345"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(projectA, "notes.txt"), []byte("x"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(projectB, "s2.jsonl.gz"), []byte("123"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(projectsDir, "stray.jsonl"), []byte("x"), 0644))
+
+	sessions, err := ScanSessions(projectsDir)
+	require.NoError(t, err)
+
+	require.Len(t, sessions, 2)
+	assert.Equal(t, filepath.Join(projectA, "s1.jsonl"), sessions[0].Path)
+	assert.Equal(t, "-a", sessions[0].Project)
+	assert.Equal(t, int64(5), sessions[0].Size)
+	assert.False(t, sessions[0].ModTime.IsZero())
+	assert.Equal(t, "-b", sessions[1].Project)
+	assert.Equal(t, int64(3), sessions[1].Size)
+
+	sessions, err = ScanSessions(filepath.Join(projectsDir, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
@@ -0,0 +1,121 @@
+package claude
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ProjectStatus classifies a project by whether its source directory is present.
+type ProjectStatus int
+
+const (
+	// StatusActive means the project's ActualPath exists.
+	StatusActive ProjectStatus = iota
+	// StatusStale means the ActualPath no longer exists.
+	StatusStale
+	// StatusNoCWD means no session recorded a cwd, so the project cannot be
+	// matched to a directory. Such projects are treated as stale.
+	StatusNoCWD
+	// StatusUnavailable means the ActualPath cannot be checked right now,
+	// e.g. because it lives on a network or removable drive that is not mounted.
+	StatusUnavailable
+	// StatusUnknown is StatusNoCWD for callers that keep projects without a
+	// cwd instead of treating them as stale. Status never returns it.
+	StatusUnknown
+)
+
+// String returns the status label used in listings. Projects without a cwd
+// are listed as stale.
+func (s ProjectStatus) String() string {
+	switch s {
+	case StatusStale, StatusNoCWD:
+		return "STALE"
+	case StatusUnavailable:
+		return "UNAVAILABLE"
+	case StatusUnknown:
+		return "UNKNOWN"
+	default:
+		return "OK"
+	}
+}
+
+// IsStale reports whether projects with this status are cleaned as stale.
+func (s ProjectStatus) IsStale() bool {
+	return s == StatusStale || s == StatusNoCWD
+}
+
+// Status determines the status of the project. A missing path is only
+// considered stale if the filesystem it would live on is reachable; paths
+// under an unmounted volume, or that fail with errors other than "not exist",
+// are reported as unavailable so their data is not deleted by accident.
+func (p *Project) Status() ProjectStatus {
+	status, _ := p.ExplainStatus()
+	return status
+}
+
+// ExplainStatus is like Status but also returns the reason for the
+// decision, e.g. "stale: cwd /x/y does not exist".
+func (p *Project) ExplainStatus() (ProjectStatus, string) {
+	if p.ActualPath == "" {
+		if p.HasOnlyEmptySessions() {
+			return StatusNoCWD, "stale: project has only empty sessions"
+		}
+		return StatusNoCWD, "stale: no cwd found in any session"
+	}
+
+	_, err := os.Stat(p.ActualPath)
+	if err == nil {
+		return StatusActive, "kept: cwd exists"
+	}
+	if !os.IsNotExist(err) {
+		return StatusUnavailable, fmt.Sprintf("unavailable: cannot check cwd %s: %v", p.ActualPath, err)
+	}
+
+	if root := mountRoot(p.ActualPath); root != "" {
+		if _, err := os.Stat(root); err != nil {
+			return StatusUnavailable, fmt.Sprintf("unavailable: %s is not mounted", root)
+		}
+	}
+
+	return StatusStale, fmt.Sprintf("stale: cwd %s does not exist", p.ActualPath)
+}
+
+// mountRoot returns the likely mount point of a path on a network or
+// removable drive, or "" if the path is not under a well-known mount location.
+// This is a best-effort heuristic based on path prefixes: Windows volumes,
+// /Volumes/<name> (macOS), /mnt/<name>, /media/[<user>/]<name> and
+// /run/media/<user>/<name> (Linux).
+func mountRoot(p string) string {
+	if vol := filepath.VolumeName(p); vol != "" {
+		return vol + string(filepath.Separator)
+	}
+
+	parts := strings.Split(filepath.ToSlash(p), "/")
+	if len(parts) < 3 || parts[0] != "" {
+		return ""
+	}
+
+	n := 0
+	switch parts[1] {
+	case "Volumes", "mnt":
+		n = 3
+	case "media":
+		// /media/<label> or /media/<user>/<label>
+		n = 3
+		if len(parts) > 4 {
+			n = 4
+		}
+	case "run":
+		if parts[2] == "media" && len(parts) >= 5 {
+			n = 5
+		}
+	}
+	if n == 0 {
+		return ""
+	}
+
+	return filepath.FromSlash("/" + path.Join(parts[1:n]...))
+}
@@ -0,0 +1,104 @@
+package claude
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProject_Status(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		path     string
+		expected ProjectStatus
+	}{
+		{"existing path", tmpDir, StatusActive},
+		{"deleted path", filepath.Join(tmpDir, "deleted"), StatusStale},
+		{"empty path", "", StatusNoCWD},
+		{"unmounted macOS volume", "/Volumes/ccc-test-unmounted-volume/project", StatusUnavailable},
+		{"unmounted linux mount", "/mnt/ccc-test-unmounted-share/project", StatusUnavailable},
+		{"unmounted removable media", "/run/media/user/ccc-test-usb/project", StatusUnavailable},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			project := Project{EncodedName: "test", ActualPath: tc.path}
+			assert.Equal(t, tc.expected, project.Status())
+		})
+	}
+}
+
+func TestProject_ExplainStatus(t *testing.T) {
+	tmpDir := t.TempDir()
+	deleted := filepath.Join(tmpDir, "deleted")
+
+	tests := []struct {
+		name   string
+		path   string
+		status ProjectStatus
+		reason string
+	}{
+		{"existing path", tmpDir, StatusActive, "kept: cwd exists"},
+		{"deleted path", deleted, StatusStale, "stale: cwd " + deleted + " does not exist"},
+		{"empty path", "", StatusNoCWD, "stale: no cwd found in any session"},
+		{"unmounted linux mount", "/mnt/ccc-test-unmounted-share/project", StatusUnavailable,
+			"unavailable: " + filepath.FromSlash("/mnt/ccc-test-unmounted-share") + " is not mounted"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			project := Project{EncodedName: "test", ActualPath: tc.path}
+			status, reason := project.ExplainStatus()
+			assert.Equal(t, tc.status, status)
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+}
+
+func TestProject_ExplainStatus_OnlyEmptySessions(t *testing.T) {
+	project := Project{EncodedName: "test", FileCount: 2}
+	status, reason := project.ExplainStatus()
+	assert.Equal(t, StatusNoCWD, status)
+	assert.Equal(t, "stale: project has only empty sessions", reason)
+
+	// Sessions with content but without cwd are not empty
+	project.TotalSize = 10
+	_, reason = project.ExplainStatus()
+	assert.Equal(t, "stale: no cwd found in any session", reason)
+}
+
+func TestProjectStatus_StringAndIsStale(t *testing.T) {
+	tests := []struct {
+		status ProjectStatus
+		label  string
+		stale  bool
+	}{
+		{StatusActive, "OK", false},
+		{StatusStale, "STALE", true},
+		{StatusNoCWD, "STALE", true},
+		{StatusUnavailable, "UNAVAILABLE", false},
+		{StatusUnknown, "UNKNOWN", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.label, func(t *testing.T) {
+			assert.Equal(t, tc.label, tc.status.String())
+			assert.Equal(t, tc.stale, tc.status.IsStale())
+		})
+	}
+}
+
+func TestMountRoot(t *testing.T) {
+	if filepath.Separator != '/' {
+		t.Skip("POSIX mount prefixes only")
+	}
+
+	assert.Equal(t, "/Volumes/Backup", mountRoot("/Volumes/Backup/Code/project"))
+	assert.Equal(t, "/mnt/share", mountRoot("/mnt/share/project"))
+	assert.Equal(t, "/media/user/usb", mountRoot("/media/user/usb/project"))
+	assert.Equal(t, "/run/media/user/usb", mountRoot("/run/media/user/usb/project"))
+	assert.Equal(t, "", mountRoot("/home/user/project"))
+}
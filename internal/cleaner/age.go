@@ -0,0 +1,22 @@
+package cleaner
+
+import "time"
+
+// FilterByAge returns the items whose age at now lies within [min, max],
+// where the age is taken from the item's timestamp. A zero min or max leaves
+// that side of the window open, so FilterByAge(items, now, 0, 0, ts)
+// returns all items.
+func FilterByAge[T any](items []T, now time.Time, min, max time.Duration, timestamp func(T) time.Time) []T {
+	var filtered []T
+	for _, item := range items {
+		age := now.Sub(timestamp(item))
+		if min > 0 && age < min {
+			continue
+		}
+		if max > 0 && age > max {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+	return filtered
+}
@@ -0,0 +1,37 @@
+package cleaner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterByAge(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	items := []time.Time{
+		now.Add(-2 * time.Hour),
+		now.Add(-3 * day),
+		now.Add(-10 * day),
+		now.Add(-100 * day),
+	}
+	ts := func(t time.Time) time.Time { return t }
+
+	tests := []struct {
+		name     string
+		min, max time.Duration
+		expected []time.Time
+	}{
+		{"no bounds", 0, 0, items},
+		{"older than", 7 * day, 0, items[2:]},
+		{"newer than", 0, day, items[:1]},
+		{"window", 2 * day, 30 * day, items[1:3]},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, FilterByAge(items, now, tc.min, tc.max, ts))
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package cleaner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+	"github.com/mkoepf/claude-code-config-cleaner/internal/ui"
+)
+
+// MinConsolidateConfigs is the number of local configs needed for
+// consolidation. With a single local config every entry would be "common",
+// which would move the whole config into the global settings.
+const MinConsolidateConfigs = 2
+
+// FindCommonLocalEntries returns the permission entries present in every
+// local config but missing from global, in the order of the first local
+// config. It returns empty settings for fewer than MinConsolidateConfigs
+// local configs.
+func FindCommonLocalEntries(global *claude.Settings, locals []*claude.Settings) *claude.Settings {
+	common := &claude.Settings{}
+	if len(locals) < MinConsolidateConfigs {
+		return common
+	}
+
+	common.Permissions.Allow = commonEntries(global.Permissions.Allow, locals, func(s *claude.Settings) []string { return s.Permissions.Allow })
+	common.Permissions.Deny = commonEntries(global.Permissions.Deny, locals, func(s *claude.Settings) []string { return s.Permissions.Deny })
+	common.Permissions.Ask = commonEntries(global.Permissions.Ask, locals, func(s *claude.Settings) []string { return s.Permissions.Ask })
+	return common
+}
+
+// commonEntries returns the entries of list(locals[i]) present for every i
+// but not in global.
+func commonEntries(global []string, locals []*claude.Settings, list func(*claude.Settings) []string) []string {
+	var common []string
+	for _, entry := range diffSlice(list(locals[0]), global) {
+		inAll := true
+		for _, local := range locals[1:] {
+			if !slices.Contains(list(local), entry) {
+				inAll = false
+				break
+			}
+		}
+		if inAll && !slices.Contains(common, entry) {
+			common = append(common, entry)
+		}
+	}
+	return common
+}
+
+// diffSlice returns the elements of a that are not in b.
+func diffSlice(a, b []string) []string {
+	var result []string
+	for _, v := range a {
+		if !slices.Contains(b, v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// BuildConsolidation returns the deduplication results that strip the
+// common entries from each local config. Local configs that only consist of
+// common entries are marked for deletion; configs with anything else left,
+// e.g. env or hooks, are only stripped.
+func BuildConsolidation(common *claude.Settings, localPaths []string, locals []*claude.Settings) []DedupResult {
+	var results []DedupResult
+	for i, path := range localPaths {
+		result := DeduplicateConfig(path, common, locals[i])
+		// Consolidation only moves the common entries
+		result.SelfDuplicateAllow, result.SelfDuplicateDeny, result.SelfDuplicateAsk = nil, nil, nil
+		if result.SuggestDelete && !remainsEmpty(result) {
+			result.SuggestDelete = false
+		}
+		if result.HasDuplicates() {
+			results = append(results, *result)
+		}
+	}
+	return results
+}
+
+// AddPermissionEntries adds the permission entries of add to the settings
+// file at path, creating it if needed. Entries are appended to the existing
+// lists so the rest of the file keeps its formatting. If a permission list
+// does not exist yet, the file is rewritten with its members in
+// alphabetical order. The file is replaced atomically, see writeFileAtomic.
+func AddPermissionEntries(path string, add *claude.Settings) error {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	out, err := addPermissionEntries(data, map[string][]string{
+		"allow": add.Permissions.Allow,
+		"deny":  add.Permissions.Deny,
+		"ask":   add.Permissions.Ask,
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, out, 0600)
+}
+
+// addPermissionEntries returns the JSON settings document data with the
+// given entries appended to its permissions lists (keyed by list name).
+func addPermissionEntries(data []byte, add map[string][]string) ([]byte, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		data = []byte("{}")
+	}
+
+	members, err := objectMembers(data)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, ok := members["permissions"]
+	if !ok || !bytes.HasPrefix(permissions.raw, []byte("{")) {
+		return addPermissionEntriesRewrite(data, add)
+	}
+
+	lists, err := objectMembers(permissions.raw)
+	if err != nil {
+		return nil, err
+	}
+	for name, entries := range add {
+		if _, ok := lists[name]; !ok && len(entries) > 0 {
+			return addPermissionEntriesRewrite(data, add)
+		}
+	}
+
+	type edit struct {
+		start, end int
+		text       []byte
+	}
+	var edits []edit
+	for name, entries := range add {
+		list, ok := lists[name]
+		if !ok || len(entries) == 0 {
+			continue
+		}
+		if !bytes.HasPrefix(list.raw, []byte("[")) {
+			return nil, fmt.Errorf("permissions.%s is not a list", name)
+		}
+
+		text, err := appendArrayEntries(list.raw, entries)
+		if err != nil {
+			return nil, err
+		}
+
+		start := permissions.start + list.start
+		edits = append(edits, edit{start, start + len(list.raw), text})
+	}
+
+	// Apply edits back to front so earlier offsets stay valid
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	out := append([]byte(nil), data...)
+	for _, e := range edits {
+		out = append(out[:e.start], append(e.text, out[e.end:]...)...)
+	}
+
+	return out, nil
+}
+
+// addPermissionEntriesRewrite adds the entries by decoding and re-encoding
+// the document. Unknown members are kept, but their order is not.
+func addPermissionEntriesRewrite(data []byte, add map[string][]string) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]json.RawMessage)
+	if raw, ok := doc["permissions"]; ok {
+		if err := json.Unmarshal(raw, &permissions); err != nil {
+			return nil, fmt.Errorf("permissions is not an object: %w", err)
+		}
+	}
+
+	for name, entries := range add {
+		if len(entries) == 0 {
+			continue
+		}
+		var list []string
+		if raw, ok := permissions[name]; ok {
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return nil, fmt.Errorf("permissions.%s is not a list of strings: %w", name, err)
+			}
+		}
+		raw, err := marshalNoEscape(append(list, entries...))
+		if err != nil {
+			return nil, err
+		}
+		permissions[name] = raw
+	}
+
+	raw, err := marshalNoEscape(permissions)
+	if err != nil {
+		return nil, err
+	}
+	doc["permissions"] = raw
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// appendArrayEntries returns the JSON array text with the string entries
+// appended, using the separator between the existing elements.
+func appendArrayEntries(array []byte, entries []string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(array))
+	if err := expectDelim(dec, '['); err != nil {
+		return nil, err
+	}
+	open := int(dec.InputOffset())
+
+	var elements []jsonSpan
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		end := int(dec.InputOffset())
+		elements = append(elements, jsonSpan{end - len(raw), end, raw})
+	}
+	if err := expectDelim(dec, ']'); err != nil {
+		return nil, err
+	}
+	closing := int(dec.InputOffset()) - 1
+
+	var values [][]byte
+	for _, e := range entries {
+		raw, err := marshalNoEscape(e)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, raw)
+	}
+
+	if len(elements) == 0 {
+		var out []byte
+		out = append(out, '[')
+		out = append(out, bytes.Join(values, []byte(", "))...)
+		return append(out, array[closing:]...), nil
+	}
+
+	// Reuse the separator of the existing elements, or derive it from the
+	// indentation of a single element
+	last := elements[len(elements)-1]
+	sep := []byte(", ")
+	if len(elements) > 1 {
+		sep = array[elements[len(elements)-2].end:last.start]
+	} else if indent := array[open:last.start]; bytes.ContainsRune(indent, '\n') {
+		sep = append([]byte(","), indent...)
+	}
+
+	var out []byte
+	out = append(out, array[:last.end]...)
+	for _, v := range values {
+		out = append(out, sep...)
+		out = append(out, v...)
+	}
+	return append(out, array[last.end:]...), nil
+}
+
+// marshalNoEscape marshals v to JSON without escaping HTML characters, which
+// are common in permission rules (e.g. "Bash(a && b)").
+func marshalNoEscape(v any) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// BuildConsolidationPreview creates a preview of the entries added to the
+// global settings and removed from the local configs.
+func BuildConsolidationPreview(globalPath string, common *claude.Settings, results []DedupResult) *ui.Preview {
+	preview := &ui.Preview{
+		Title: "Config Consolidation",
+	}
+
+	var added []string
+	for _, list := range []struct {
+		name    string
+		entries []string
+	}{
+		{"allow", common.Permissions.Allow},
+		{"deny", common.Permissions.Deny},
+		{"ask", common.Permissions.Ask},
+	} {
+		if len(list.entries) > 0 {
+			added = append(added, list.name+": "+strings.Join(list.entries, ", "))
+		}
+	}
+	preview.Changes = append(preview.Changes, ui.Change{
+		Action:      ui.ActionModify,
+		Path:        globalPath,
+		Description: "Add " + strings.Join(added, "; "),
+	})
+
+	for _, r := range results {
+		action := ui.ActionModify
+		description := fmt.Sprintf("Remove %s now in global settings", pluralEntries(r.TotalDuplicates()))
+		if r.SuggestDelete {
+			action = ui.ActionDelete
+			description = "Only contains entries moved to global settings, will be deleted"
+		}
+		before, after := r.Sizes()
+		preview.Changes = append(preview.Changes, ui.Change{
+			Action:      action,
+			Path:        r.LocalPath,
+			Description: description,
+			Size:        before - after,
+		})
+	}
+
+	return preview
+}
+
+// pluralEntries formats "n entry/entries".
+func pluralEntries(n int) string {
+	if n == 1 {
+		return "1 entry"
+	}
+	return fmt.Sprintf("%d entries", n)
+}
@@ -0,0 +1,181 @@
+package cleaner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+	"github.com/mkoepf/claude-code-config-cleaner/internal/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindCommonLocalEntries(t *testing.T) {
+	global := &claude.Settings{}
+	global.Permissions.Allow = []string{"Read(**)"}
+
+	a := &claude.Settings{}
+	a.Permissions.Allow = []string{"Bash(git:*)", "Read(**)", "Bash(npm:*)"}
+	a.Permissions.Deny = []string{"Bash(rm:*)"}
+	b := &claude.Settings{}
+	b.Permissions.Allow = []string{"Bash(npm:*)", "Read(**)", "Bash(git:*)"}
+	b.Permissions.Deny = []string{"Bash(rm:*)"}
+	c := &claude.Settings{}
+	c.Permissions.Allow = []string{"Bash(git:*)", "Bash(npm:*)", "Bash(go:*)"}
+
+	common := FindCommonLocalEntries(global, []*claude.Settings{a, b, c})
+
+	assert.Equal(t, []string{"Bash(git:*)", "Bash(npm:*)"}, common.Permissions.Allow)
+	assert.Empty(t, common.Permissions.Deny)
+}
+
+func TestFindCommonLocalEntries_SingleConfig(t *testing.T) {
+	local := &claude.Settings{}
+	local.Permissions.Allow = []string{"Bash(git:*)"}
+
+	common := FindCommonLocalEntries(&claude.Settings{}, []*claude.Settings{local})
+
+	assert.True(t, common.IsEmpty())
+}
+
+func TestAddPermissionEntries_PreservesFormatting(t *testing.T) {
+	tmpDir := t.TempDir()
+	settingsPath := filepath.Join(tmpDir, "settings.json")
+	content := `{
+    "model": "opus",
+    "permissions": {
+        "deny": ["Bash(rm:*)"],
+        "allow": [
+            "Read(**)"
+        ]
+    }
+}
+`
+	require.NoError(t, os.WriteFile(settingsPath, []byte(content), 0644))
+
+	add := &claude.Settings{}
+	add.Permissions.Allow = []string{"Bash(a && b)", "Bash(npm:*)"}
+	add.Permissions.Deny = []string{"Bash(sudo:*)"}
+
+	require.NoError(t, AddPermissionEntries(settingsPath, add))
+
+	expected := `{
+    "model": "opus",
+    "permissions": {
+        "deny": ["Bash(rm:*)", "Bash(sudo:*)"],
+        "allow": [
+            "Read(**)",
+            "Bash(a && b)",
+            "Bash(npm:*)"
+        ]
+    }
+}
+`
+	data, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(data))
+}
+
+func TestAddPermissionEntries_MissingList(t *testing.T) {
+	tmpDir := t.TempDir()
+	settingsPath := filepath.Join(tmpDir, "settings.json")
+	require.NoError(t, os.WriteFile(settingsPath, []byte(`{"model":"opus"}`), 0644))
+
+	add := &claude.Settings{}
+	add.Permissions.Allow = []string{"Bash(git:*)"}
+
+	require.NoError(t, AddPermissionEntries(settingsPath, add))
+
+	settings, err := claude.LoadSettings(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bash(git:*)"}, settings.Permissions.Allow)
+
+	data, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"model": "opus"`)
+}
+
+func TestAddPermissionEntries_CreatesFile(t *testing.T) {
+	settingsPath := filepath.Join(t.TempDir(), "settings.json")
+
+	add := &claude.Settings{}
+	add.Permissions.Ask = []string{"Bash(curl:*)"}
+
+	require.NoError(t, AddPermissionEntries(settingsPath, add))
+
+	settings, err := claude.LoadSettings(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bash(curl:*)"}, settings.Permissions.Ask)
+}
+
+func TestAddPermissionEntries_FollowsSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles-settings.json")
+	require.NoError(t, os.WriteFile(target, []byte(`{"permissions":{"allow":[]}}`), 0600))
+	settingsPath := filepath.Join(dir, "settings.json")
+	require.NoError(t, os.Symlink(target, settingsPath))
+
+	add := &claude.Settings{}
+	add.Permissions.Allow = []string{"Bash(git:*)"}
+	require.NoError(t, AddPermissionEntries(settingsPath, add))
+
+	info, err := os.Lstat(settingsPath)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink, "the symlink must be kept")
+	settings, err := claude.LoadSettings(target)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bash(git:*)"}, settings.Permissions.Allow)
+	assert.NoFileExists(t, target+".tmp")
+}
+
+func TestBuildConsolidation_KeepsOtherSettings(t *testing.T) {
+	dir := t.TempDir()
+	contents := []string{
+		`{"permissions":{"allow":["Bash(git:*)"]}}`,
+		`{"permissions":{"allow":["Bash(git:*)"]},"env":{"DEBUG":"1"}}`,
+		`{"permissions":{"allow":["Bash(git:*)"],"defaultMode":"acceptEdits"}}`,
+	}
+	var paths []string
+	var locals []*claude.Settings
+	for i, content := range contents {
+		path := filepath.Join(dir, fmt.Sprintf("settings%d.json", i))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		local, err := claude.LoadSettings(path)
+		require.NoError(t, err)
+		paths = append(paths, path)
+		locals = append(locals, local)
+	}
+
+	common := FindCommonLocalEntries(&claude.Settings{}, locals)
+	results := BuildConsolidation(common, paths, locals)
+
+	require.Len(t, results, 3)
+	assert.True(t, results[0].SuggestDelete, "only common entries")
+	assert.False(t, results[1].SuggestDelete, "env must be kept")
+	assert.False(t, results[2].SuggestDelete, "defaultMode must be kept")
+
+	require.NoError(t, ApplyDedup(&results[1], false))
+	data, err := os.ReadFile(paths[1])
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"DEBUG"`)
+	assert.NotContains(t, string(data), "Bash(git:*)")
+}
+
+func TestBuildConsolidationPreview(t *testing.T) {
+	common := &claude.Settings{}
+	common.Permissions.Allow = []string{"Bash(git:*)"}
+	results := []DedupResult{
+		{LocalPath: "/a/.claude/settings.local.json", DuplicateAllow: []string{"Bash(git:*)"}, SuggestDelete: true},
+		{LocalPath: "/b/.claude/settings.local.json", DuplicateAllow: []string{"Bash(git:*)"}},
+	}
+
+	preview := BuildConsolidationPreview("/home/.claude/settings.json", common, results)
+
+	require.Len(t, preview.Changes, 3)
+	assert.Equal(t, "Config Consolidation", preview.Title)
+	assert.Equal(t, "Add allow: Bash(git:*)", preview.Changes[0].Description)
+	assert.Equal(t, ui.ActionDelete, preview.Changes[1].Action)
+	assert.Equal(t, "Remove 1 entry now in global settings", preview.Changes[2].Description)
+}
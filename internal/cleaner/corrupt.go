@@ -0,0 +1,79 @@
+package cleaner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+)
+
+// CorruptSession represents a session file that could not be parsed.
+type CorruptSession struct {
+	Path    string
+	Project string // EncodedName of the owning project directory
+	Line    int    // 1-based line number of the first unparseable line
+	Err     error
+	Size    int64
+}
+
+// FindCorruptSessions finds session files in the projects directory with a
+// line that fails to parse, wherever it is in the file. ScanProjects skips
+// such files or lines; this surfaces them so they can be inspected or
+// removed. Files without a cwd are not considered corrupt.
+func FindCorruptSessions(projectsDir string) ([]CorruptSession, error) {
+	var corrupt []CorruptSession
+
+	if _, err := os.Stat(projectsDir); os.IsNotExist(err) {
+		return corrupt, nil
+	}
+
+	entries, err := os.ReadDir(projectsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		projectPath := filepath.Join(projectsDir, entry.Name())
+		sessionEntries, err := os.ReadDir(projectPath)
+		if err != nil {
+			continue
+		}
+
+		for _, sessionEntry := range sessionEntries {
+			if sessionEntry.IsDir() {
+				continue
+			}
+			if !claude.IsSessionFile(sessionEntry.Name()) {
+				continue
+			}
+
+			sessionPath := filepath.Join(projectPath, sessionEntry.Name())
+			err := claude.ValidateSessionFile(sessionPath)
+
+			var parseErr *claude.SessionParseError
+			if !errors.As(err, &parseErr) {
+				continue
+			}
+
+			var size int64
+			if info, err := sessionEntry.Info(); err == nil {
+				size = info.Size()
+			}
+
+			corrupt = append(corrupt, CorruptSession{
+				Path:    sessionPath,
+				Project: entry.Name(),
+				Line:    parseErr.Line,
+				Err:     parseErr.Err,
+				Size:    size,
+			})
+		}
+	}
+
+	return corrupt, nil
+}
@@ -0,0 +1,50 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindCorruptSessions(t *testing.T) {
+	projectsDir := t.TempDir()
+	projectDir := filepath.Join(projectsDir, "-test-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+
+	valid := filepath.Join(projectDir, "valid.jsonl")
+	require.NoError(t, os.WriteFile(valid, []byte(`{"sessionId":"s1","cwd":"/test"}`), 0644))
+
+	noCWD := filepath.Join(projectDir, "no-cwd.jsonl")
+	require.NoError(t, os.WriteFile(noCWD, []byte(`{"sessionId":"s2"}`), 0644))
+
+	corruptContent := `{"type":"summary"}` + "\n" + `not json`
+	corruptFile := filepath.Join(projectDir, "corrupt.jsonl")
+	require.NoError(t, os.WriteFile(corruptFile, []byte(corruptContent), 0644))
+
+	// A malformed line after the cwd is found too
+	lateContent := `{"sessionId":"s3","cwd":"/test"}` + "\n" + `{"sessionId":` + "\n" + `{"sessionId":"s3"}` + "\n"
+	late := filepath.Join(projectDir, "late.jsonl")
+	require.NoError(t, os.WriteFile(late, []byte(lateContent), 0644))
+
+	corrupt, err := FindCorruptSessions(projectsDir)
+	require.NoError(t, err)
+
+	require.Len(t, corrupt, 2)
+	assert.Equal(t, late, corrupt[1].Path)
+	assert.Equal(t, 2, corrupt[1].Line)
+
+	assert.Equal(t, corruptFile, corrupt[0].Path)
+	assert.Equal(t, "-test-project", corrupt[0].Project)
+	assert.Equal(t, 2, corrupt[0].Line)
+	assert.Error(t, corrupt[0].Err)
+	assert.Equal(t, int64(len(corruptContent)), corrupt[0].Size)
+}
+
+func TestFindCorruptSessions_MissingDirectory(t *testing.T) {
+	corrupt, err := FindCorruptSessions(filepath.Join(t.TempDir(), "does-not-exist"))
+	require.NoError(t, err)
+	assert.Empty(t, corrupt)
+}
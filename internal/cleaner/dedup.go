@@ -1,10 +1,15 @@
 package cleaner
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
@@ -13,23 +18,118 @@ import (
 
 // DedupResult represents the result of deduplicating a local config.
 type DedupResult struct {
-	LocalPath      string
-	DuplicateAllow []string
-	DuplicateDeny  []string
-	DuplicateAsk   []string
-	SuggestDelete  bool // True if local becomes empty after dedup
+	LocalPath      string   `json:"local_path"`
+	DuplicateAllow []string `json:"duplicate_allow,omitempty"`
+	DuplicateDeny  []string `json:"duplicate_deny,omitempty"`
+	DuplicateAsk   []string `json:"duplicate_ask,omitempty"`
+	SuggestDelete  bool     `json:"suggest_delete"` // True if local becomes empty after dedup
+
+	// SelfDuplicateAllow, -Deny and -Ask hold the repeated occurrences of
+	// entries listed more than once in the local config itself, once per
+	// extra occurrence. Entries are compared exactly. The first occurrence
+	// is kept; entries that also duplicate a global one are not included, as
+	// they are removed entirely.
+	SelfDuplicateAllow []string `json:"self_duplicate_allow,omitempty"`
+	SelfDuplicateDeny  []string `json:"self_duplicate_deny,omitempty"`
+	SelfDuplicateAsk   []string `json:"self_duplicate_ask,omitempty"`
+
+	// NormalizedMatches maps duplicate local entries that only match a global
+	// entry after normalization to that global entry.
+	NormalizedMatches map[string]string `json:"normalized_matches,omitempty"`
 }
 
-// HasDuplicates returns true if any duplicate entries were found.
+// DedupOptions controls how local entries are matched against global ones.
+// The zero value compares entries exactly.
+type DedupOptions struct {
+	Normalize  bool // Ignore whitespace around punctuation and repeated spaces
+	IgnoreCase bool // Also ignore case differences (requires Normalize)
+}
+
+// normalize returns the form of entry used for comparison.
+func (o *DedupOptions) normalize(entry string) string {
+	if o == nil || !o.Normalize {
+		return entry
+	}
+	entry = normalizeWhitespace(entry)
+	if o.IgnoreCase {
+		entry = strings.ToLower(entry)
+	}
+	return entry
+}
+
+// normalizeWhitespace collapses runs of whitespace to a single space and
+// drops whitespace next to the punctuation of permission rules, so that
+// "Bash( git :* )" and "Bash(git:*)" compare equal while "git commit" keeps
+// its space.
+func normalizeWhitespace(s string) string {
+	const punctuation = "():,*"
+
+	fields := strings.Fields(s)
+	var b strings.Builder
+	for i, f := range fields {
+		if i > 0 {
+			prev := fields[i-1][len(fields[i-1])-1]
+			if !strings.ContainsRune(punctuation, rune(prev)) && !strings.ContainsRune(punctuation, rune(f[0])) {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(f)
+	}
+	return b.String()
+}
+
+// Sizes returns the size of the local config now and after deduplication
+// (0 if it will be deleted). If the deduplicated content cannot be computed,
+// after equals before. A missing file has size 0.
+func (r *DedupResult) Sizes() (before, after int64) {
+	info, err := os.Stat(r.LocalPath)
+	if err != nil {
+		return 0, 0
+	}
+	before = info.Size()
+
+	if r.SuggestDelete {
+		return before, 0
+	}
+	data, err := dedupedContent(r)
+	if err != nil {
+		return before, before
+	}
+	return before, int64(len(data))
+}
+
+// HasDuplicates returns true if any duplicate entries were found, of global
+// entries or within the local config.
 func (r *DedupResult) HasDuplicates() bool {
-	return len(r.DuplicateAllow) > 0 ||
-		len(r.DuplicateDeny) > 0 ||
-		len(r.DuplicateAsk) > 0
+	return r.TotalDuplicates() > 0
 }
 
-// TotalDuplicates returns the total number of duplicate entries found.
+// TotalDuplicates returns the total number of duplicate entries found,
+// including repeated occurrences within the local config.
 func (r *DedupResult) TotalDuplicates() int {
-	return len(r.DuplicateAllow) + len(r.DuplicateDeny) + len(r.DuplicateAsk)
+	return len(r.DuplicateAllow) + len(r.DuplicateDeny) + len(r.DuplicateAsk) +
+		len(r.SelfDuplicateAllow) + len(r.SelfDuplicateDeny) + len(r.SelfDuplicateAsk)
+}
+
+// DedupSummary aggregates deduplication results.
+type DedupSummary struct {
+	Configs int // Local configs changed or deleted
+	Entries int // Duplicate entries removed, see TotalDuplicates
+	Deleted int // Local configs deleted because only duplicates remained
+}
+
+// SummarizeDedup returns the number of configs, duplicate entries and
+// deleted configs of results.
+func SummarizeDedup(results []DedupResult) DedupSummary {
+	var s DedupSummary
+	for _, r := range results {
+		s.Configs++
+		s.Entries += r.TotalDuplicates()
+		if r.SuggestDelete {
+			s.Deleted++
+		}
+	}
+	return s
 }
 
 // FormatAuditDetails returns a human-readable description of the changes made.
@@ -52,6 +152,15 @@ func (r *DedupResult) FormatAuditDetails() string {
 	if len(r.DuplicateAsk) > 0 {
 		parts = append(parts, "ask: "+strings.Join(r.DuplicateAsk, ", "))
 	}
+	if len(r.SelfDuplicateAllow) > 0 {
+		parts = append(parts, "repeated allow: "+strings.Join(r.SelfDuplicateAllow, ", "))
+	}
+	if len(r.SelfDuplicateDeny) > 0 {
+		parts = append(parts, "repeated deny: "+strings.Join(r.SelfDuplicateDeny, ", "))
+	}
+	if len(r.SelfDuplicateAsk) > 0 {
+		parts = append(parts, "repeated ask: "+strings.Join(r.SelfDuplicateAsk, ", "))
+	}
 
 	return "removed " + strings.Join(parts, "; ")
 }
@@ -87,46 +196,143 @@ func FindLocalConfigsFromProjects(projectPaths []string, excludePath string) []s
 	return configs
 }
 
+// DefaultConfigSearchDepth is the default number of directory levels below a
+// project root that FindLocalConfigsRecursive descends into.
+const DefaultConfigSearchDepth = 4
+
+// skipConfigSearchDirs are directories never descended into when searching
+// for nested local configs: large ones that never hold project settings, and
+// .claude itself, whose settings are checked from the directory containing it.
+var skipConfigSearchDirs = []string{".git", "node_modules", ".claude"}
+
+// FindLocalConfigsRecursive finds all .claude/settings.local.json files at or
+// below the given project roots, in directories at most maxDepth levels below
+// a root. This finds per-package configs in monorepos that
+// FindLocalConfigsFromProjects misses. Configs reachable from several (nested)
+// roots are reported once, and the config at excludePath is skipped.
+func FindLocalConfigsRecursive(projectPaths []string, excludePath string, maxDepth int) []string {
+	var configs []string
+	seen := make(map[string]struct{})
+
+	if excludePath != "" {
+		excludePath = filepath.Clean(excludePath)
+	}
+
+	for _, root := range projectPaths {
+		_ = walkBounded(root, maxDepth, skipConfigSearchDirs, func(path string, d fs.DirEntry, err error) error {
+			// Unreadable directories are skipped, the walk goes on
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+
+			settingsPath := filepath.Join(path, ".claude", "settings.local.json")
+			if info, err := os.Stat(settingsPath); err != nil || info.IsDir() {
+				return nil
+			}
+			if _, dup := seen[settingsPath]; !dup && settingsPath != excludePath {
+				seen[settingsPath] = struct{}{}
+				configs = append(configs, settingsPath)
+			}
+			return nil
+		})
+	}
+
+	return configs
+}
+
 // DeduplicateConfig compares local settings against global settings
 // and identifies duplicate entries.
 func DeduplicateConfig(localPath string, global, local *claude.Settings) *DedupResult {
+	return DeduplicateConfigWithOptions(localPath, global, local, nil)
+}
+
+// DeduplicateConfigWithOptions is like DeduplicateConfig but matches entries
+// as configured by opts (which may be nil for exact matching).
+func DeduplicateConfigWithOptions(localPath string, global, local *claude.Settings, opts *DedupOptions) *DedupResult {
 	result := &DedupResult{
 		LocalPath: localPath,
 	}
 
+	// Nothing can duplicate a global without permissions, and a local
+	// without permissions only holds other settings, which must be kept
+	if global.NoPermissions || local.NoPermissions {
+		return result
+	}
+
 	// Find duplicates in each permission list
-	result.DuplicateAllow = findDuplicates(local.Permissions.Allow, global.Permissions.Allow)
-	result.DuplicateDeny = findDuplicates(local.Permissions.Deny, global.Permissions.Deny)
-	result.DuplicateAsk = findDuplicates(local.Permissions.Ask, global.Permissions.Ask)
+	result.DuplicateAllow = findDuplicates(local.Permissions.Allow, global.Permissions.Allow, opts, result)
+	result.DuplicateDeny = findDuplicates(local.Permissions.Deny, global.Permissions.Deny, opts, result)
+	result.DuplicateAsk = findDuplicates(local.Permissions.Ask, global.Permissions.Ask, opts, result)
+	result.SelfDuplicateAllow = findSelfDuplicates(local.Permissions.Allow, result.DuplicateAllow)
+	result.SelfDuplicateDeny = findSelfDuplicates(local.Permissions.Deny, result.DuplicateDeny)
+	result.SelfDuplicateAsk = findSelfDuplicates(local.Permissions.Ask, result.DuplicateAsk)
 
 	// Check if local would become empty after removing duplicates
-	uniqueSettings := local.Diff(global)
-	result.SuggestDelete = uniqueSettings.IsEmpty()
+	if opts != nil && opts.Normalize {
+		result.SuggestDelete = len(result.DuplicateAllow) == len(local.Permissions.Allow) &&
+			len(result.DuplicateDeny) == len(local.Permissions.Deny) &&
+			len(result.DuplicateAsk) == len(local.Permissions.Ask)
+	} else {
+		uniqueSettings := local.Diff(global)
+		result.SuggestDelete = uniqueSettings.IsEmpty()
+	}
+	// The permission lists alone don't tell whether env, hooks or other
+	// settings would be left behind, so check the file itself
+	if result.SuggestDelete && !remainsEmpty(result) {
+		result.SuggestDelete = false
+	}
 
 	return result
 }
 
-// findDuplicates returns entries in local that also exist in global.
-func findDuplicates(local, global []string) []string {
+// findDuplicates returns entries in local that also exist in global. Entries
+// that only match after normalization are recorded in
+// result.NormalizedMatches.
+func findDuplicates(local, global []string, opts *DedupOptions, result *DedupResult) []string {
 	if len(local) == 0 || len(global) == 0 {
 		return nil
 	}
 
-	globalSet := make(map[string]struct{}, len(global))
+	globalSet := make(map[string]string, len(global))
 	for _, v := range global {
-		globalSet[v] = struct{}{}
+		key := opts.normalize(v)
+		if _, exists := globalSet[key]; !exists || key == v {
+			globalSet[key] = v
+		}
 	}
 
 	var duplicates []string
 	for _, v := range local {
-		if _, exists := globalSet[v]; exists {
-			duplicates = append(duplicates, v)
+		match, exists := globalSet[opts.normalize(v)]
+		if !exists {
+			continue
+		}
+		duplicates = append(duplicates, v)
+		if match != v {
+			if result.NormalizedMatches == nil {
+				result.NormalizedMatches = make(map[string]string)
+			}
+			result.NormalizedMatches[v] = match
 		}
 	}
 
 	return duplicates
 }
 
+// findSelfDuplicates returns the second and later occurrences of entries in
+// local, except for the global duplicates, which are removed entirely.
+func findSelfDuplicates(local, duplicates []string) []string {
+	seen := make(map[string]bool, len(local))
+	var repeated []string
+	for _, v := range local {
+		if seen[v] && !slices.Contains(duplicates, v) {
+			repeated = append(repeated, v)
+		}
+		seen[v] = true
+	}
+	return repeated
+}
+
 // ApplyDedup applies the deduplication result to the local config file.
 // If dryRun is true, returns without making changes.
 func ApplyDedup(result *DedupResult, dryRun bool) error {
@@ -145,44 +351,266 @@ func ApplyDedup(result *DedupResult, dryRun bool) error {
 	}
 
 	// Otherwise, update the file by removing duplicates
-	settings, err := claude.LoadSettings(result.LocalPath)
+	data, err := dedupedContent(result)
 	if err != nil {
 		return err
 	}
 
-	// Remove duplicates from each list
-	settings.Permissions.Allow = removeEntries(settings.Permissions.Allow, result.DuplicateAllow)
-	settings.Permissions.Deny = removeEntries(settings.Permissions.Deny, result.DuplicateDeny)
-	settings.Permissions.Ask = removeEntries(settings.Permissions.Ask, result.DuplicateAsk)
+	return writeFileAtomic(result.LocalPath, data, 0600)
+}
 
-	// Write updated settings back
-	data, err := json.MarshalIndent(settings, "", "  ")
-	if err != nil {
+// writeFileAtomic writes data to path via a temporary file in the same
+// directory and a rename, so that a crash or a full disk never leaves a
+// truncated settings file behind. A symlink at path is followed, so the
+// file it points to is replaced and the link is kept.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
 		return err
 	}
+	return nil
+}
+
+// dedupedContent returns the contents of result.LocalPath with the duplicate
+// entries removed, as ApplyDedup writes them.
+//
+// Only the duplicated array elements are cut out of the original text; key
+// order, indentation and any settings besides the permission lists are kept
+// exactly as they were, so the rewrite produces a minimal diff.
+func dedupedContent(result *DedupResult) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Clean(result.LocalPath))
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return data, nil
+	}
 
-	return os.WriteFile(result.LocalPath, data, 0600)
+	return removePermissionEntries(data, map[string][]string{
+		"allow": result.DuplicateAllow,
+		"deny":  result.DuplicateDeny,
+		"ask":   result.DuplicateAsk,
+	}, map[string][]string{
+		"allow": result.SelfDuplicateAllow,
+		"deny":  result.SelfDuplicateDeny,
+		"ask":   result.SelfDuplicateAsk,
+	})
 }
 
-// removeEntries returns a new slice with specified entries removed.
-func removeEntries(slice, toRemove []string) []string {
-	if len(slice) == 0 {
-		return nil
+// remainsEmpty reports whether nothing but empty permission lists is left
+// of the local config of r once its duplicates are removed. Other settings,
+// or content that cannot be read, mean the file must be kept; a missing file
+// has nothing left to keep. Both deduplication and consolidation use it to
+// decide whether a local config may be deleted.
+func remainsEmpty(r *DedupResult) bool {
+	stripped := *r
+	stripped.SuggestDelete = false
+	data, err := dedupedContent(&stripped)
+	if err != nil {
+		return errors.Is(err, fs.ErrNotExist)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return true
 	}
 
-	removeSet := make(map[string]struct{}, len(toRemove))
-	for _, v := range toRemove {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	for key, raw := range doc {
+		if key != "permissions" {
+			return false
+		}
+		var lists map[string][]json.RawMessage
+		if err := json.Unmarshal(raw, &lists); err != nil {
+			return false
+		}
+		for _, list := range lists {
+			if len(list) > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// jsonSpan is the byte range of a JSON value within its enclosing document.
+type jsonSpan struct {
+	start, end int
+	raw        json.RawMessage
+}
+
+// removePermissionEntries removes the given string entries from the
+// permissions lists (keyed by list name) in the JSON settings document data.
+// Of the entries in repeated, only the first occurrence is kept.
+func removePermissionEntries(data []byte, remove, repeated map[string][]string) ([]byte, error) {
+	members, err := objectMembers(data)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, ok := members["permissions"]
+	if !ok || !bytes.HasPrefix(permissions.raw, []byte("{")) {
+		return data, nil
+	}
+
+	lists, err := objectMembers(permissions.raw)
+	if err != nil {
+		return nil, err
+	}
+
+	type edit struct {
+		start, end int
+		text       []byte
+	}
+	var edits []edit
+	for _, name := range []string{"allow", "deny", "ask"} {
+		entries := remove[name]
+		list, ok := lists[name]
+		if !ok || (len(entries) == 0 && len(repeated[name]) == 0) || !bytes.HasPrefix(list.raw, []byte("[")) {
+			continue
+		}
+
+		text, err := removeArrayEntries(list.raw, entries, repeated[name])
+		if err != nil {
+			return nil, err
+		}
+
+		start := permissions.start + list.start
+		edits = append(edits, edit{start, start + len(list.raw), text})
+	}
+
+	// Apply edits back to front so earlier offsets stay valid
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	out := append([]byte(nil), data...)
+	for _, e := range edits {
+		out = append(out[:e.start], append(e.text, out[e.end:]...)...)
+	}
+
+	return out, nil
+}
+
+// removeArrayEntries returns the JSON array text with all string elements
+// contained in entries removed, and all but the first of those contained in
+// repeated. The whitespace and separators of the kept elements are
+// preserved.
+func removeArrayEntries(array []byte, entries, repeated []string) ([]byte, error) {
+	removeSet := make(map[string]struct{}, len(entries))
+	for _, v := range entries {
 		removeSet[v] = struct{}{}
 	}
+	// Entries of repeated are removed once their first occurrence is kept
+	keptOnce := make(map[string]bool, len(repeated))
 
-	var result []string
-	for _, v := range slice {
-		if _, exists := removeSet[v]; !exists {
-			result = append(result, v)
+	dec := json.NewDecoder(bytes.NewReader(array))
+	if err := expectDelim(dec, '['); err != nil {
+		return nil, err
+	}
+	open := int(dec.InputOffset())
+
+	var elements []jsonSpan
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
 		}
+		end := int(dec.InputOffset())
+		elements = append(elements, jsonSpan{end - len(raw), end, raw})
+	}
+	if err := expectDelim(dec, ']'); err != nil {
+		return nil, err
 	}
+	closing := int(dec.InputOffset()) - 1
+
+	var out []byte
+	out = append(out, array[:open]...)
+	prevEnd := open
+	kept := 0
+	for _, el := range elements {
+		var value string
+		if json.Unmarshal(el.raw, &value) == nil {
+			if _, found := removeSet[value]; found {
+				prevEnd = el.end
+				continue
+			}
+			if slices.Contains(repeated, value) {
+				if keptOnce[value] {
+					prevEnd = el.end
+					continue
+				}
+				keptOnce[value] = true
+			}
+		}
 
-	return result
+		// Reuse the original separator; the first kept element takes the
+		// leading whitespace of the original first element instead.
+		sep := array[prevEnd:el.start]
+		if kept == 0 {
+			sep = array[open:elements[0].start]
+		}
+		out = append(out, sep...)
+		out = append(out, el.raw...)
+		prevEnd = el.end
+		kept++
+	}
+
+	if kept > 0 {
+		out = append(out, array[elements[len(elements)-1].end:closing]...)
+	}
+	out = append(out, array[closing:]...)
+
+	return out, nil
+}
+
+// objectMembers returns the members of the JSON object in data, keyed by
+// name, with the byte span of each value relative to data.
+func objectMembers(data []byte) (map[string]jsonSpan, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+
+	members := make(map[string]jsonSpan)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected token %v", tok)
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		end := int(dec.InputOffset())
+		members[key] = jsonSpan{end - len(raw), end, raw}
+	}
+
+	return members, nil
+}
+
+// expectDelim reads the next token from dec and checks that it is delim.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %q, got %v", delim, tok)
+	}
+	return nil
 }
 
 // BuildDedupPreview creates a preview of configs to be deduplicated.
@@ -195,19 +623,20 @@ func BuildDedupPreview(results []DedupResult) *ui.Preview {
 		var action ui.Action
 		var description string
 
+		before, after := r.Sizes()
 		if r.SuggestDelete {
 			action = ui.ActionDelete
 			description = "Empty after deduplication, will be deleted"
 		} else {
 			action = ui.ActionModify
-			description = formatDuplicateDescription(r)
+			description = formatDuplicateDescription(r) + ", " + formatShrink(before, after)
 		}
 
 		preview.Changes = append(preview.Changes, ui.Change{
 			Action:      action,
 			Path:        r.LocalPath,
 			Description: description,
-			Size:        0, // Config files are typically small
+			Size:        before - after,
 		})
 	}
 
@@ -223,6 +652,25 @@ func formatDuplicateDescription(r DedupResult) string {
 	return fmt.Sprintf("%d duplicate entries to remove", total)
 }
 
+// formatEntries joins duplicate entries for verbose output, noting the
+// global entry for those that only match after normalization.
+func (r *DedupResult) formatEntries(entries []string) string {
+	formatted := make([]string, len(entries))
+	for i, e := range entries {
+		formatted[i] = e
+		if match, ok := r.NormalizedMatches[e]; ok {
+			formatted[i] = fmt.Sprintf("%s (normalized match for %s)", e, match)
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// formatShrink describes how a config file's size changes, e.g.
+// "will shrink from 1.2 KB to 840 B".
+func formatShrink(before, after int64) string {
+	return fmt.Sprintf("will shrink from %s to %s", ui.FormatSize(before), ui.FormatSize(after))
+}
+
 // BuildDedupPreviewVerbose creates a verbose preview of configs to be deduplicated.
 func BuildDedupPreviewVerbose(results []DedupResult, globalPath string) *ui.Preview {
 	preview := &ui.Preview{
@@ -233,19 +681,20 @@ func BuildDedupPreviewVerbose(results []DedupResult, globalPath string) *ui.Prev
 		var action ui.Action
 		var description string
 
+		before, after := r.Sizes()
 		if r.SuggestDelete {
 			action = ui.ActionDelete
 			description = formatVerboseDescription(r, globalPath, true)
 		} else {
 			action = ui.ActionModify
-			description = formatVerboseDescription(r, globalPath, false)
+			description = formatVerboseDescription(r, globalPath, false) + "     File will " + formatShrink(before, after)
 		}
 
 		preview.Changes = append(preview.Changes, ui.Change{
 			Action:      action,
 			Path:        r.LocalPath,
 			Description: description,
-			Size:        0,
+			Size:        before - after,
 		})
 	}
 
@@ -256,26 +705,47 @@ func BuildDedupPreviewVerbose(results []DedupResult, globalPath string) *ui.Prev
 func formatVerboseDescription(r DedupResult, globalPath string, willDelete bool) string {
 	var sb strings.Builder
 
-	sb.WriteString(fmt.Sprintf("Duplicates of %s:\n", globalPath))
+	if len(r.DuplicateAllow)+len(r.DuplicateDeny)+len(r.DuplicateAsk) > 0 {
+		sb.WriteString(fmt.Sprintf("Duplicates of %s:\n", globalPath))
+	}
 
 	if len(r.DuplicateAllow) > 0 {
 		sb.WriteString("     allow: ")
-		sb.WriteString(strings.Join(r.DuplicateAllow, ", "))
+		sb.WriteString(r.formatEntries(r.DuplicateAllow))
 		sb.WriteString("\n")
 	}
 
 	if len(r.DuplicateDeny) > 0 {
 		sb.WriteString("     deny: ")
-		sb.WriteString(strings.Join(r.DuplicateDeny, ", "))
+		sb.WriteString(r.formatEntries(r.DuplicateDeny))
 		sb.WriteString("\n")
 	}
 
 	if len(r.DuplicateAsk) > 0 {
 		sb.WriteString("     ask: ")
-		sb.WriteString(strings.Join(r.DuplicateAsk, ", "))
+		sb.WriteString(r.formatEntries(r.DuplicateAsk))
 		sb.WriteString("\n")
 	}
 
+	if len(r.SelfDuplicateAllow)+len(r.SelfDuplicateDeny)+len(r.SelfDuplicateAsk) > 0 {
+		if sb.Len() > 0 {
+			sb.WriteString("     ")
+		}
+		sb.WriteString("Repeated within the file (first occurrence is kept):\n")
+		for _, list := range []struct {
+			name    string
+			entries []string
+		}{
+			{"allow", r.SelfDuplicateAllow},
+			{"deny", r.SelfDuplicateDeny},
+			{"ask", r.SelfDuplicateAsk},
+		} {
+			if len(list.entries) > 0 {
+				sb.WriteString("     " + list.name + ": " + strings.Join(list.entries, ", ") + "\n")
+			}
+		}
+	}
+
 	if willDelete {
 		sb.WriteString("     File will be deleted (no unique entries remain)")
 	}
@@ -0,0 +1,134 @@
+package cleaner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+)
+
+// DedupPlanVersion is the version of the dedup plan file format. Plans with
+// a different version are rejected.
+const DedupPlanVersion = 2
+
+// DedupPlan is a saved set of config deduplication results, so that what
+// was reviewed in a dry run can be applied later without re-scanning.
+type DedupPlan struct {
+	Version int               `json:"version"`
+	Created time.Time         `json:"created"`
+	Results []DedupResult     `json:"results"`
+	Hashes  map[string]string `json:"hashes"` // SHA-256 of each local config when the plan was saved
+}
+
+// SaveDedupPlan writes the deduplication results to path as a plan,
+// together with the content hash of each local config.
+func SaveDedupPlan(path string, results []DedupResult, created time.Time) error {
+	hashes := make(map[string]string, len(results))
+	for _, r := range results {
+		hash, err := fileHash(r.LocalPath)
+		if err != nil {
+			return err
+		}
+		hashes[r.LocalPath] = hash
+	}
+
+	data, err := json.MarshalIndent(DedupPlan{
+		Version: DedupPlanVersion,
+		Created: created,
+		Results: results,
+		Hashes:  hashes,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// LoadDedupPlan reads a plan written by SaveDedupPlan.
+func LoadDedupPlan(path string) (*DedupPlan, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var plan DedupPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("invalid plan: %w", err)
+	}
+	if plan.Version != DedupPlanVersion {
+		return nil, fmt.Errorf("unsupported plan version %d (expected %d)", plan.Version, DedupPlanVersion)
+	}
+	return &plan, nil
+}
+
+// fileHash returns the hex-encoded SHA-256 of the file at path.
+func fileHash(path string) (string, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CheckDrift reports how the local config of a planned result changed since
+// the plan was saved, or nil if it is unchanged, see CheckDedupDrift.
+func (p *DedupPlan) CheckDrift(r *DedupResult) error {
+	return CheckDedupDrift(r, p.Hashes[r.LocalPath])
+}
+
+// CheckDedupDrift reports how the local config of a planned result changed
+// since the plan was made, or nil if the plan can still be applied as
+// reviewed. Any change to the file, whose content hash was hash when the
+// plan was made, is drift, including settings other than permissions that
+// deleting the file would lose. Where possible the error names the
+// permission entry that was added or removed.
+func CheckDedupDrift(r *DedupResult, hash string) error {
+	current, err := fileHash(r.LocalPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.New("file no longer exists")
+		}
+		return err
+	}
+	if current == hash {
+		return nil
+	}
+
+	local, err := claude.LoadSettings(r.LocalPath)
+	if err != nil {
+		return err
+	}
+
+	for _, list := range []struct {
+		name      string
+		current   []string
+		duplicate []string
+	}{
+		{"allow", local.Permissions.Allow, r.DuplicateAllow},
+		{"deny", local.Permissions.Deny, r.DuplicateDeny},
+		{"ask", local.Permissions.Ask, r.DuplicateAsk},
+	} {
+		for _, entry := range list.duplicate {
+			if !slices.Contains(list.current, entry) {
+				return fmt.Errorf("%s entry %q was removed", list.name, entry)
+			}
+		}
+		if !r.SuggestDelete {
+			continue
+		}
+		for _, entry := range list.current {
+			if !slices.Contains(list.duplicate, entry) {
+				return fmt.Errorf("%s entry %q was added", list.name, entry)
+			}
+		}
+	}
+	return errors.New("file content changed")
+}
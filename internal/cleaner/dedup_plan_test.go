@@ -0,0 +1,106 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveDedupPlan_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	planPath := filepath.Join(dir, "plan.json")
+	localPath := filepath.Join(dir, "settings.local.json")
+	require.NoError(t, os.WriteFile(localPath, []byte(`{"permissions":{"allow":["Bash(git :*)"]}}`), 0644))
+	results := []DedupResult{{
+		LocalPath:         localPath,
+		DuplicateAllow:    []string{"Bash(git :*)"},
+		SuggestDelete:     true,
+		NormalizedMatches: map[string]string{"Bash(git :*)": "Bash(git:*)"},
+	}}
+	created := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, SaveDedupPlan(planPath, results, created))
+
+	plan, err := LoadDedupPlan(planPath)
+	require.NoError(t, err)
+	assert.Equal(t, DedupPlanVersion, plan.Version)
+	assert.True(t, created.Equal(plan.Created))
+	assert.Equal(t, results, plan.Results)
+	assert.NoError(t, plan.CheckDrift(&plan.Results[0]))
+}
+
+func TestLoadDedupPlan_RejectsOtherVersions(t *testing.T) {
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	require.NoError(t, os.WriteFile(planPath, []byte(`{"version":99,"results":[]}`), 0644))
+
+	_, err := LoadDedupPlan(planPath)
+	assert.EqualError(t, err, "unsupported plan version 99 (expected 2)")
+}
+
+func TestCheckDedupDrift(t *testing.T) {
+	const planned = `{"permissions":{"allow":["Bash(git:*)","Bash(npm:*)"]}}`
+	deleteResult := DedupResult{DuplicateAllow: []string{"Bash(git:*)", "Bash(npm:*)"}, SuggestDelete: true}
+
+	tests := []struct {
+		name    string
+		content string
+		result  DedupResult
+		wantErr string
+	}{
+		{
+			name:    "unchanged",
+			content: planned,
+			result:  DedupResult{DuplicateAllow: []string{"Bash(git:*)"}},
+		},
+		{
+			name:    "duplicate removed",
+			content: `{"permissions":{"allow":["Bash(npm:*)"]}}`,
+			result:  DedupResult{DuplicateAllow: []string{"Bash(git:*)"}},
+			wantErr: `allow entry "Bash(git:*)" was removed`,
+		},
+		{
+			name:    "entry added to file planned for deletion",
+			content: `{"permissions":{"allow":["Bash(git:*)","Bash(npm:*)"],"deny":["Read(.env)"]}}`,
+			result:  deleteResult,
+			wantErr: `deny entry "Read(.env)" was added`,
+		},
+		{
+			name:    "non-permission setting added to file planned for deletion",
+			content: `{"permissions":{"allow":["Bash(git:*)","Bash(npm:*)"]},"env":{"DEBUG":"1"}}`,
+			result:  deleteResult,
+			wantErr: "file content changed",
+		},
+		{
+			name:    "entry added to file planned for modification",
+			content: `{"permissions":{"allow":["Bash(git:*)","Bash(npm:*)","Bash(make:*)"]}}`,
+			result:  DedupResult{DuplicateAllow: []string{"Bash(git:*)"}},
+			wantErr: "file content changed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.result.LocalPath = filepath.Join(t.TempDir(), "settings.local.json")
+			require.NoError(t, os.WriteFile(tt.result.LocalPath, []byte(planned), 0644))
+			hash, err := fileHash(tt.result.LocalPath)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(tt.result.LocalPath, []byte(tt.content), 0644))
+
+			err = CheckDedupDrift(&tt.result, hash)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckDedupDrift_MissingFile(t *testing.T) {
+	r := &DedupResult{LocalPath: filepath.Join(t.TempDir(), "settings.local.json")}
+	assert.EqualError(t, CheckDedupDrift(r, ""), "file no longer exists")
+}
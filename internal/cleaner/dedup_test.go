@@ -1,6 +1,7 @@
 package cleaner
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -68,6 +69,58 @@ func TestFindLocalConfigsFromProjects_ExcludesGlobalConfig(t *testing.T) {
 	assert.Equal(t, localSettings, configs[0])
 }
 
+func TestFindLocalConfigsRecursive_FindsNestedConfigs(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Monorepo with a root config and per-package configs
+	repo := filepath.Join(tmpDir, "monorepo")
+	rootConfig := filepath.Join(repo, ".claude", "settings.local.json")
+	pkgConfig := filepath.Join(repo, "packages", "api", ".claude", "settings.local.json")
+	deepConfig := filepath.Join(repo, "a", "b", "c", "d", "e", ".claude", "settings.local.json")
+	ignoredConfig := filepath.Join(repo, "node_modules", "dep", ".claude", "settings.local.json")
+
+	for _, path := range []string{rootConfig, pkgConfig, deepConfig, ignoredConfig} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(`{}`), 0644))
+	}
+
+	configs := FindLocalConfigsRecursive([]string{repo, "/nonexistent/path"}, "", DefaultConfigSearchDepth)
+
+	assert.ElementsMatch(t, []string{rootConfig, pkgConfig}, configs)
+}
+
+func TestFindLocalConfigsRecursive_DepthBoundary(t *testing.T) {
+	repo := t.TempDir()
+	atLimit := filepath.Join(repo, "a", "b", ".claude", "settings.local.json")
+	beyondLimit := filepath.Join(repo, "x", "y", "z", ".claude", "settings.local.json")
+	for _, path := range []string{atLimit, beyondLimit} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(`{}`), 0644))
+	}
+
+	assert.Equal(t, []string{atLimit}, FindLocalConfigsRecursive([]string{repo}, "", 2))
+	assert.ElementsMatch(t, []string{atLimit, beyondLimit}, FindLocalConfigsRecursive([]string{repo}, "", 3))
+	assert.Empty(t, FindLocalConfigsRecursive([]string{repo}, "", 1))
+}
+
+func TestFindLocalConfigsRecursive_DedupesAndExcludes(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Home directory registered as a project, containing another project
+	homeLocalSettings := filepath.Join(tmpDir, ".claude", "settings.local.json")
+	projectDir := filepath.Join(tmpDir, "myproject")
+	localSettings := filepath.Join(projectDir, ".claude", "settings.local.json")
+
+	for _, path := range []string{homeLocalSettings, localSettings} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(`{}`), 0644))
+	}
+
+	configs := FindLocalConfigsRecursive([]string{tmpDir, projectDir}, homeLocalSettings, DefaultConfigSearchDepth)
+
+	assert.Equal(t, []string{localSettings}, configs)
+}
+
 func TestDeduplicateConfig_AllDuplicate(t *testing.T) {
 	global := &claude.Settings{
 		Permissions: claude.Permissions{
@@ -172,6 +225,97 @@ func TestDeduplicateConfig_EmptyGlobal(t *testing.T) {
 	assert.False(t, result.SuggestDelete)
 }
 
+func TestDeduplicateConfig_GlobalWithoutPermissions(t *testing.T) {
+	tmpDir := t.TempDir()
+	globalPath := filepath.Join(tmpDir, "settings.json")
+	localPath := filepath.Join(tmpDir, "settings.local.json")
+	require.NoError(t, os.WriteFile(globalPath, []byte(`{"env": {"FOO": "bar"}}`), 0644))
+	require.NoError(t, os.WriteFile(localPath, []byte(`{"permissions": {"allow": ["Bash(npm:*)"]}}`), 0644))
+
+	global, err := claude.LoadSettings(globalPath)
+	require.NoError(t, err)
+	local, err := claude.LoadSettings(localPath)
+	require.NoError(t, err)
+
+	for _, opts := range []*DedupOptions{nil, {Normalize: true}} {
+		result := DeduplicateConfigWithOptions(localPath, global, local, opts)
+		assert.False(t, result.HasDuplicates())
+		assert.False(t, result.SuggestDelete)
+	}
+}
+
+func TestDeduplicateConfig_LocalWithoutPermissions(t *testing.T) {
+	global := &claude.Settings{
+		Permissions: claude.Permissions{
+			Allow: []string{"Bash(git:*)"},
+		},
+	}
+	// Only hooks or env, which deduplication must not delete
+	local := &claude.Settings{NoPermissions: true}
+
+	result := DeduplicateConfig("/path/to/local/settings.json", global, local)
+
+	assert.False(t, result.HasDuplicates())
+	assert.False(t, result.SuggestDelete)
+}
+
+func TestDeduplicateConfig_SelfDuplicates(t *testing.T) {
+	tmpDir := t.TempDir()
+	localPath := filepath.Join(tmpDir, "settings.local.json")
+	content := `{
+  "permissions": {
+    "allow": ["Bash(git:*)", "Read", "Bash(git:*)", "Bash(npm:*)", "Bash(git:*)", "Read"],
+    "deny": ["Bash(rm:*)"]
+  }
+}`
+	require.NoError(t, os.WriteFile(localPath, []byte(content), 0644))
+	local, err := claude.LoadSettings(localPath)
+	require.NoError(t, err)
+	global := &claude.Settings{
+		Permissions: claude.Permissions{
+			Allow: []string{"Read"},
+		},
+	}
+
+	result := DeduplicateConfig(localPath, global, local)
+
+	// Read duplicates a global entry, so it is removed entirely
+	assert.Equal(t, []string{"Read", "Read"}, result.DuplicateAllow)
+	assert.Equal(t, []string{"Bash(git:*)", "Bash(git:*)"}, result.SelfDuplicateAllow)
+	assert.Empty(t, result.SelfDuplicateDeny)
+	assert.Equal(t, 4, result.TotalDuplicates())
+	assert.False(t, result.SuggestDelete)
+	assert.Equal(t, "removed allow: Read, Read; repeated allow: Bash(git:*), Bash(git:*)", result.FormatAuditDetails())
+
+	require.NoError(t, ApplyDedup(result, false))
+	data, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "permissions": {
+    "allow": ["Bash(git:*)", "Bash(npm:*)"],
+    "deny": ["Bash(rm:*)"]
+  }
+}`, string(data))
+}
+
+func TestDeduplicateConfig_OnlySelfDuplicates(t *testing.T) {
+	global := &claude.Settings{}
+	local := &claude.Settings{
+		Permissions: claude.Permissions{
+			Allow: []string{"Bash(git:*)", "Bash(git:*)"},
+		},
+	}
+
+	for _, opts := range []*DedupOptions{nil, {Normalize: true}} {
+		result := DeduplicateConfigWithOptions("/path/to/local/settings.json", global, local, opts)
+
+		// One occurrence remains, so the file must not be deleted
+		assert.True(t, result.HasDuplicates())
+		assert.Equal(t, []string{"Bash(git:*)"}, result.SelfDuplicateAllow)
+		assert.False(t, result.SuggestDelete)
+	}
+}
+
 func TestApplyDedup_DryRun(t *testing.T) {
 	tmpDir := t.TempDir()
 	settingsPath := filepath.Join(tmpDir, "settings.json")
@@ -215,6 +359,65 @@ func TestApplyDedup_RemoveDuplicates(t *testing.T) {
 	assert.Equal(t, []string{"Bash(rm:*)"}, settings.Permissions.Deny)
 }
 
+func TestApplyDedup_PreservesKeyOrderAndFormatting(t *testing.T) {
+	tmpDir := t.TempDir()
+	settingsPath := filepath.Join(tmpDir, "settings.json")
+	content := `{
+    "model": "opus",
+    "permissions": {
+        "deny": ["Bash(rm:*)"],
+        "allow": [
+            "Bash(git:*)",
+            "Bash(npm:*)",
+            "Bash(go:*)"
+        ]
+    },
+    "env": {"FOO": "bar"}
+}
+`
+	require.NoError(t, os.WriteFile(settingsPath, []byte(content), 0644))
+
+	result := &DedupResult{
+		LocalPath:      settingsPath,
+		DuplicateAllow: []string{"Bash(git:*)", "Bash(go:*)"},
+	}
+
+	require.NoError(t, ApplyDedup(result, false))
+
+	expected := `{
+    "model": "opus",
+    "permissions": {
+        "deny": ["Bash(rm:*)"],
+        "allow": [
+            "Bash(npm:*)"
+        ]
+    },
+    "env": {"FOO": "bar"}
+}
+`
+	data, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(data))
+}
+
+func TestApplyDedup_RemovesWholeList(t *testing.T) {
+	tmpDir := t.TempDir()
+	settingsPath := filepath.Join(tmpDir, "settings.json")
+	content := `{"permissions":{"allow":["Bash(git:*)"],"deny":["Bash(rm:*)"]}}`
+	require.NoError(t, os.WriteFile(settingsPath, []byte(content), 0644))
+
+	result := &DedupResult{
+		LocalPath:      settingsPath,
+		DuplicateAllow: []string{"Bash(git:*)"},
+	}
+
+	require.NoError(t, ApplyDedup(result, false))
+
+	data, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, `{"permissions":{"allow":[],"deny":["Bash(rm:*)"]}}`, string(data))
+}
+
 func TestApplyDedup_DeleteFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	settingsPath := filepath.Join(tmpDir, "settings.json")
@@ -232,6 +435,25 @@ func TestApplyDedup_DeleteFile(t *testing.T) {
 	assert.NoFileExists(t, settingsPath)
 }
 
+func TestApplyDedup_KeepsOtherSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	localPath := filepath.Join(tmpDir, "settings.local.json")
+	content := `{"env":{"FOO":"bar"},"hooks":{"Stop":[]},"permissions":{"allow":["Bash(git:*)"]}}`
+	require.NoError(t, os.WriteFile(localPath, []byte(content), 0644))
+
+	global := &claude.Settings{Permissions: claude.Permissions{Allow: []string{"Bash(git:*)"}}}
+	local, err := claude.LoadSettings(localPath)
+	require.NoError(t, err)
+
+	result := DeduplicateConfig(localPath, global, local)
+	assert.False(t, result.SuggestDelete)
+	require.NoError(t, ApplyDedup(result, false))
+
+	data, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	assert.Equal(t, `{"env":{"FOO":"bar"},"hooks":{"Stop":[]},"permissions":{"allow":[]}}`, string(data))
+}
+
 func TestApplyDedup_NonexistentFile(t *testing.T) {
 	result := &DedupResult{
 		LocalPath:     "/nonexistent/settings.json",
@@ -265,6 +487,41 @@ func TestBuildDedupPreview(t *testing.T) {
 	assert.Equal(t, ui.ActionDelete, preview.Changes[1].Action)
 }
 
+func TestBuildDedupPreview_Sizes(t *testing.T) {
+	dir := t.TempDir()
+	modified := filepath.Join(dir, "modified.json")
+	content := `{"permissions": {"allow": ["Bash(git:*)", "Bash(make:*)"]}}`
+	require.NoError(t, os.WriteFile(modified, []byte(content), 0644))
+	deleted := filepath.Join(dir, "deleted.json")
+	require.NoError(t, os.WriteFile(deleted, []byte(`{"permissions": {"allow": ["Bash(git:*)"]}}`), 0644))
+
+	results := []DedupResult{
+		{LocalPath: modified, DuplicateAllow: []string{"Bash(git:*)"}},
+		{LocalPath: deleted, DuplicateAllow: []string{"Bash(git:*)"}, SuggestDelete: true},
+	}
+
+	before, after := results[0].Sizes()
+	assert.Equal(t, int64(len(content)), before)
+	assert.Less(t, after, before)
+
+	for _, preview := range []*ui.Preview{BuildDedupPreview(results), BuildDedupPreviewVerbose(results, "/global")} {
+		require.Len(t, preview.Changes, 2)
+		assert.Equal(t, before-after, preview.Changes[0].Size)
+		assert.Contains(t, preview.Changes[0].Description,
+			fmt.Sprintf("will shrink from %s to %s", ui.FormatSize(before), ui.FormatSize(after)))
+		assert.Equal(t, int64(len(`{"permissions": {"allow": ["Bash(git:*)"]}}`)), preview.Changes[1].Size)
+	}
+}
+
+func TestDedupResult_Sizes_MissingFile(t *testing.T) {
+	r := DedupResult{LocalPath: filepath.Join(t.TempDir(), "missing.json")}
+
+	before, after := r.Sizes()
+
+	assert.Zero(t, before)
+	assert.Zero(t, after)
+}
+
 func TestBuildDedupPreview_Empty(t *testing.T) {
 	preview := BuildDedupPreview(nil)
 
@@ -472,3 +729,38 @@ func TestBuildDedupPreview_Verbose(t *testing.T) {
 	assert.Equal(t, ui.ActionDelete, preview.Changes[1].Action)
 	assert.Contains(t, preview.Changes[1].Description, "Read(**)")
 }
+
+func TestNormalizeWhitespace(t *testing.T) {
+	tests := map[string]string{
+		"Bash(git:*)":         "Bash(git:*)",
+		"Bash(git :*)":        "Bash(git:*)",
+		"Bash( git : * )":     "Bash(git:*)",
+		"Bash(git  commit:*)": "Bash(git commit:*)",
+		" Read(/a/b) ":        "Read(/a/b)",
+		"Bash(npm run\ttest)": "Bash(npm run test)",
+	}
+
+	for in, want := range tests {
+		assert.Equal(t, want, normalizeWhitespace(in), in)
+	}
+}
+
+func TestDeduplicateConfigWithOptions_Normalize(t *testing.T) {
+	global := &claude.Settings{Permissions: claude.Permissions{Allow: []string{"Bash(git:*)", "Read(/Docs)"}}}
+	local := &claude.Settings{Permissions: claude.Permissions{Allow: []string{"Bash(git :*)", "read(/docs)"}}}
+
+	strict := DeduplicateConfig("/local.json", global, local)
+	assert.False(t, strict.HasDuplicates())
+
+	normalized := DeduplicateConfigWithOptions("/local.json", global, local, &DedupOptions{Normalize: true})
+	assert.Equal(t, []string{"Bash(git :*)"}, normalized.DuplicateAllow)
+	assert.Equal(t, map[string]string{"Bash(git :*)": "Bash(git:*)"}, normalized.NormalizedMatches)
+	assert.False(t, normalized.SuggestDelete)
+
+	folded := DeduplicateConfigWithOptions("/local.json", global, local, &DedupOptions{Normalize: true, IgnoreCase: true})
+	assert.Equal(t, []string{"Bash(git :*)", "read(/docs)"}, folded.DuplicateAllow)
+	assert.True(t, folded.SuggestDelete)
+
+	description := formatVerboseDescription(*folded, "/global.json", false)
+	assert.Contains(t, description, "read(/docs) (normalized match for Read(/Docs))")
+}
@@ -0,0 +1,153 @@
+package cleaner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// RenderDedupDiff returns a unified diff between the current contents of
+// result.LocalPath and the contents ApplyDedup would write. A config that
+// would be deleted diffs against an empty file. Returns an empty string if
+// the file would not change.
+func RenderDedupDiff(result *DedupResult) (string, error) {
+	before, err := os.ReadFile(filepath.Clean(result.LocalPath))
+	if err != nil {
+		return "", err
+	}
+
+	var after []byte
+	if !result.SuggestDelete {
+		after, err = dedupedContent(result)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	toName := "b/" + result.LocalPath
+	if result.SuggestDelete {
+		toName = "/dev/null"
+	}
+
+	return unifiedDiff("a/"+result.LocalPath, toName, splitLines(string(before)), splitLines(string(after))), nil
+}
+
+// splitLines splits s into lines without their line terminators.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffOp is a single line of an edit script.
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+	a, b int // Line indices in a and b before this op is applied
+}
+
+// diffLines computes a minimal edit script turning a into b using the
+// longest common subsequence. Config files are small, so O(n*m) is fine.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	return ops
+}
+
+// unifiedDiff renders the differences between a and b in unified diff format.
+func unifiedDiff(fromName, toName string, a, b []string) string {
+	ops := diffLines(a, b)
+
+	var sb strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// Extend the hunk while changes are within 2*diffContext lines of each other
+		last := first
+		for k := first; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				last = k
+			} else if k-last > 2*diffContext {
+				break
+			}
+		}
+
+		hunkStart := max(first-diffContext, start)
+		hunkEnd := min(last+diffContext+1, len(ops))
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+		}
+
+		var aLen, bLen int
+		for _, op := range ops[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				aLen++
+			}
+			if op.kind != '-' {
+				bLen++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(ops[hunkStart].a, aLen), hunkRange(ops[hunkStart].b, bLen))
+		for _, op := range ops[hunkStart:hunkEnd] {
+			fmt.Fprintf(&sb, "%c%s\n", op.kind, op.line)
+		}
+
+		start = hunkEnd
+	}
+
+	return sb.String()
+}
+
+// hunkRange formats a 0-based start index and length as a hunk header range.
+func hunkRange(start, n int) string {
+	switch n {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, n)
+	}
+}
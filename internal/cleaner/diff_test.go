@@ -0,0 +1,89 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13"}
+	b := []string{"1", "2", "3", "4", "5", "x", "7", "8", "9", "10", "11", "12", "13", "14"}
+
+	expected := `--- a
++++ b
+@@ -3,7 +3,7 @@
+ 3
+ 4
+ 5
+-6
++x
+ 7
+ 8
+ 9
+@@ -11,3 +11,4 @@
+ 11
+ 12
+ 13
++14
+`
+	assert.Equal(t, expected, unifiedDiff("a", "b", a, b))
+}
+
+func TestUnifiedDiff_NoChanges(t *testing.T) {
+	lines := []string{"a", "b"}
+	assert.Equal(t, "", unifiedDiff("a", "b", lines, lines))
+}
+
+func TestRenderDedupDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	localPath := filepath.Join(tmpDir, "settings.local.json")
+	original := `{
+  "permissions": {
+    "allow": [
+      "Bash(git:*)",
+      "Bash(npm:*)"
+    ],
+    "deny": null,
+    "ask": null
+  }
+}`
+	require.NoError(t, os.WriteFile(localPath, []byte(original), 0644))
+
+	result := &DedupResult{
+		LocalPath:      localPath,
+		DuplicateAllow: []string{"Bash(git:*)"},
+	}
+
+	diff, err := RenderDedupDiff(result)
+	require.NoError(t, err)
+	assert.Contains(t, diff, "--- a/"+localPath)
+	assert.Contains(t, diff, "+++ b/"+localPath)
+	assert.Contains(t, diff, "-      \"Bash(git:*)\",\n")
+	assert.NotContains(t, diff, "-      \"Bash(npm:*)\"")
+
+	// The file itself must be untouched
+	data, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	assert.Equal(t, original, string(data))
+}
+
+func TestRenderDedupDiff_Delete(t *testing.T) {
+	tmpDir := t.TempDir()
+	localPath := filepath.Join(tmpDir, "settings.local.json")
+	require.NoError(t, os.WriteFile(localPath, []byte(`{"permissions":{"allow":["Bash(git:*)"]}}`), 0644))
+
+	result := &DedupResult{
+		LocalPath:      localPath,
+		DuplicateAllow: []string{"Bash(git:*)"},
+		SuggestDelete:  true,
+	}
+
+	diff, err := RenderDedupDiff(result)
+	require.NoError(t, err)
+	assert.Contains(t, diff, "+++ /dev/null")
+	assert.Contains(t, diff, "@@ -1 +0,0 @@")
+}
@@ -0,0 +1,84 @@
+package cleaner
+
+import (
+	"cmp"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+	"github.com/mkoepf/claude-code-config-cleaner/internal/ui"
+)
+
+// ErrFreeSpaceUnsupported is returned by FreeSpace on platforms where free
+// space cannot be measured.
+var ErrFreeSpaceUnsupported = errors.New("measuring free space is not supported on this platform")
+
+// GroupByDisk sums the sizes of the changes per filesystem, so it is visible
+// how much space a clean frees on each drive. The path of each change must be
+// where its data is stored. Filesystems are identified by their mount point,
+// which is determined on a best-effort basis (see mountPoint). The result is
+// ordered by size, largest first.
+func GroupByDisk(changes []ui.Change) []ui.DiskUsage {
+	byMount := make(map[string]*ui.DiskUsage)
+	mounts := make(map[string]string) // parent directory -> mount point
+	for _, c := range changes {
+		dir := filepath.Dir(c.Path)
+		mount, ok := mounts[dir]
+		if !ok {
+			mount = mountPoint(existingAncestor(dir))
+			mounts[dir] = mount
+		}
+
+		usage := byMount[mount]
+		if usage == nil {
+			usage = &ui.DiskUsage{MountPoint: mount}
+			byMount[mount] = usage
+		}
+		usage.Items++
+		usage.Size += c.Size
+	}
+
+	result := make([]ui.DiskUsage, 0, len(byMount))
+	for _, usage := range byMount {
+		result = append(result, *usage)
+	}
+	slices.SortFunc(result, func(a, b ui.DiskUsage) int {
+		if c := cmp.Compare(b.Size, a.Size); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.MountPoint, b.MountPoint)
+	})
+	return result
+}
+
+// existingAncestor returns path or its nearest ancestor that exists, since
+// items may already have been removed by the time they are grouped.
+func existingAncestor(path string) string {
+	for {
+		if _, err := os.Lstat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// StaleProjectData returns a change for the session data of each stale
+// project, located in projectsDir. Unlike the changes of BuildStalePreview,
+// which are listed by the project's cwd, these can be passed to GroupByDisk.
+func StaleProjectData(projectsDir string, stale []claude.Project) []ui.Change {
+	changes := make([]ui.Change, 0, len(stale))
+	for _, p := range stale {
+		changes = append(changes, ui.Change{
+			Action: ui.ActionDelete,
+			Path:   filepath.Join(projectsDir, p.EncodedName),
+			Size:   p.TotalSize,
+		})
+	}
+	return changes
+}
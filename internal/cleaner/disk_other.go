@@ -0,0 +1,14 @@
+//go:build !unix
+
+package cleaner
+
+import "path/filepath"
+
+// mountPoint returns the volume path lives on, e.g. C:\. Folders mounted
+// into another volume are not detected.
+func mountPoint(path string) string {
+	if vol := filepath.VolumeName(path); vol != "" {
+		return vol + string(filepath.Separator)
+	}
+	return path
+}
@@ -0,0 +1,49 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+	"github.com/mkoepf/claude-code-config-cleaner/internal/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupByDisk(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "a"), []byte("abc"), 0644))
+
+	usage := GroupByDisk([]ui.Change{
+		{Path: filepath.Join(tmpDir, "a"), Size: 3},
+		{Path: filepath.Join(tmpDir, "already", "removed"), Size: 5},
+	})
+
+	// Both paths are on the filesystem of tmpDir
+	require.Len(t, usage, 1)
+	assert.Equal(t, mountPoint(tmpDir), usage[0].MountPoint)
+	assert.Equal(t, 2, usage[0].Items)
+	assert.Equal(t, int64(8), usage[0].Size)
+
+	assert.Empty(t, GroupByDisk(nil))
+}
+
+func TestMountPoint_IsAncestor(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	mount := mountPoint(tmpDir)
+	rel, err := filepath.Rel(mount, tmpDir)
+	require.NoError(t, err)
+	assert.NotContains(t, filepath.ToSlash(rel), "..")
+}
+
+func TestStaleProjectData(t *testing.T) {
+	stale := []claude.Project{{EncodedName: "-gone", ActualPath: "/gone", TotalSize: 42}}
+
+	changes := StaleProjectData("/home/me/.claude/projects", stale)
+
+	require.Len(t, changes, 1)
+	assert.Equal(t, filepath.Join("/home/me/.claude/projects", "-gone"), changes[0].Path)
+	assert.Equal(t, int64(42), changes[0].Size)
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// mountPoint returns the mount point of the filesystem path lives on: the
+// topmost ancestor of path that is still on the same device.
+func mountPoint(path string) string {
+	dev, ok := device(path)
+	if !ok {
+		return path
+	}
+	for {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		if parentDev, ok := device(parent); !ok || parentDev != dev {
+			return path
+		}
+		path = parent
+	}
+}
+
+// device returns the ID of the device path lives on.
+func device(path string) (uint64, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Dev), true // Dev is not a uint64 on every platform
+}
@@ -0,0 +1,45 @@
+package cleaner
+
+import (
+	"sort"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+)
+
+// DuplicateSession represents a session ID that appears in more than one project.
+type DuplicateSession struct {
+	SessionID string
+	Projects  []string // EncodedNames of the projects containing the session
+}
+
+// FindDuplicateSessions returns session IDs that appear in more than one project.
+// Results are sorted by session ID; owning projects keep their scan order.
+func FindDuplicateSessions(projects []claude.Project) []DuplicateSession {
+	owners := make(map[string][]string)
+	for _, p := range projects {
+		seen := make(map[string]struct{}, len(p.SessionIDs))
+		for _, id := range p.SessionIDs {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			owners[id] = append(owners[id], p.EncodedName)
+		}
+	}
+
+	var duplicates []DuplicateSession
+	for id, names := range owners {
+		if len(names) > 1 {
+			duplicates = append(duplicates, DuplicateSession{
+				SessionID: id,
+				Projects:  names,
+			})
+		}
+	}
+
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i].SessionID < duplicates[j].SessionID
+	})
+
+	return duplicates
+}
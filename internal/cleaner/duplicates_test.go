@@ -0,0 +1,42 @@
+package cleaner
+
+import (
+	"testing"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindDuplicateSessions_NoDuplicates(t *testing.T) {
+	projects := []claude.Project{
+		{EncodedName: "-proj-a", SessionIDs: []string{"s1", "s2"}},
+		{EncodedName: "-proj-b", SessionIDs: []string{"s3"}},
+	}
+
+	assert.Empty(t, FindDuplicateSessions(projects))
+}
+
+func TestFindDuplicateSessions_SharedAcrossProjects(t *testing.T) {
+	projects := []claude.Project{
+		{EncodedName: "-proj-a", SessionIDs: []string{"s1", "s2"}},
+		{EncodedName: "-proj-b", SessionIDs: []string{"s2", "s3"}},
+		{EncodedName: "-proj-c", SessionIDs: []string{"s2", "s1"}},
+	}
+
+	duplicates := FindDuplicateSessions(projects)
+
+	require.Len(t, duplicates, 2)
+	assert.Equal(t, "s1", duplicates[0].SessionID)
+	assert.Equal(t, []string{"-proj-a", "-proj-c"}, duplicates[0].Projects)
+	assert.Equal(t, "s2", duplicates[1].SessionID)
+	assert.Equal(t, []string{"-proj-a", "-proj-b", "-proj-c"}, duplicates[1].Projects)
+}
+
+func TestFindDuplicateSessions_RepeatedWithinOneProject(t *testing.T) {
+	projects := []claude.Project{
+		{EncodedName: "-proj-a", SessionIDs: []string{"s1", "s1"}},
+	}
+
+	assert.Empty(t, FindDuplicateSessions(projects))
+}
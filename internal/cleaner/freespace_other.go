@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd
+
+package cleaner
+
+// FreeSpace returns ErrFreeSpaceUnsupported, as measuring free space is not
+// implemented on this platform.
+func FreeSpace(string) (uint64, error) {
+	return 0, ErrFreeSpaceUnsupported
+}
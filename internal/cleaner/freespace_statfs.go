@@ -0,0 +1,15 @@
+//go:build linux || darwin || freebsd
+
+package cleaner
+
+import "syscall"
+
+// FreeSpace returns the bytes available to unprivileged users on the
+// filesystem path lives on.
+func FreeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil // #nosec G115 -- counts are never negative
+}
@@ -0,0 +1,21 @@
+package cleaner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeSpace(t *testing.T) {
+	free, err := FreeSpace(t.TempDir())
+	if errors.Is(err, ErrFreeSpaceUnsupported) {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+	assert.Positive(t, free)
+
+	_, err = FreeSpace("/nonexistent/ccc-test")
+	assert.Error(t, err)
+}
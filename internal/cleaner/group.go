@@ -0,0 +1,72 @@
+package cleaner
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DedupEntry identifies a duplicated permission entry.
+type DedupEntry struct {
+	List  string // "allow", "deny" or "ask"
+	Entry string
+}
+
+// GroupDedupByEntry pivots deduplication results: for each duplicated
+// entry, it returns the local configs that contain it, in result order.
+func GroupDedupByEntry(results []DedupResult) map[DedupEntry][]string {
+	groups := make(map[DedupEntry][]string)
+	for _, r := range results {
+		for _, list := range []struct {
+			name    string
+			entries []string
+		}{
+			{"allow", r.DuplicateAllow},
+			{"deny", r.DuplicateDeny},
+			{"ask", r.DuplicateAsk},
+		} {
+			for _, e := range list.entries {
+				key := DedupEntry{List: list.name, Entry: e}
+				groups[key] = append(groups[key], r.LocalPath)
+			}
+		}
+	}
+	return groups
+}
+
+// RenderDedupByEntry writes the groups of GroupDedupByEntry, the entries
+// found in the most local configs first.
+func RenderDedupByEntry(w io.Writer, groups map[DedupEntry][]string) error {
+	entries := make([]DedupEntry, 0, len(groups))
+	for e := range groups {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if len(groups[a]) != len(groups[b]) {
+			return len(groups[a]) > len(groups[b])
+		}
+		if a.List != b.List {
+			return a.List < b.List
+		}
+		return a.Entry < b.Entry
+	})
+
+	var sb strings.Builder
+	sb.WriteString("Duplicated entries by local config:\n")
+	for _, e := range entries {
+		paths := groups[e]
+		configs := "configs"
+		if len(paths) == 1 {
+			configs = "config"
+		}
+		fmt.Fprintf(&sb, "\n  %s: %s (%d %s)\n", e.List, e.Entry, len(paths), configs)
+		for _, p := range paths {
+			fmt.Fprintf(&sb, "     %s\n", p)
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
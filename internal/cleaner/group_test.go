@@ -0,0 +1,49 @@
+package cleaner
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupDedupByEntry(t *testing.T) {
+	results := []DedupResult{
+		{LocalPath: "/a", DuplicateAllow: []string{"Bash(git:*)", "Read(**)"}},
+		{LocalPath: "/b", DuplicateAllow: []string{"Bash(git:*)"}, DuplicateDeny: []string{"Bash(git:*)"}},
+	}
+
+	groups := GroupDedupByEntry(results)
+
+	assert.Equal(t, map[DedupEntry][]string{
+		{List: "allow", Entry: "Bash(git:*)"}: {"/a", "/b"},
+		{List: "allow", Entry: "Read(**)"}:    {"/a"},
+		{List: "deny", Entry: "Bash(git:*)"}:  {"/b"},
+	}, groups)
+}
+
+func TestRenderDedupByEntry(t *testing.T) {
+	groups := map[DedupEntry][]string{
+		{List: "deny", Entry: "Bash(rm:*)"}:   {"/b"},
+		{List: "allow", Entry: "Read(**)"}:    {"/a"},
+		{List: "allow", Entry: "Bash(git:*)"}: {"/a", "/b"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderDedupByEntry(&buf, groups))
+
+	expected := `Duplicated entries by local config:
+
+  allow: Bash(git:*) (2 configs)
+     /a
+     /b
+
+  allow: Read(**) (1 config)
+     /a
+
+  deny: Bash(rm:*) (1 config)
+     /b
+`
+	assert.Equal(t, expected, buf.String())
+}
@@ -0,0 +1,120 @@
+package cleaner
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+)
+
+// LintSeverity is how serious a lint finding is.
+type LintSeverity string
+
+const (
+	// LintError is an entry that does not do what it appears to, e.g. a
+	// malformed pattern or one that is both allowed and denied.
+	LintError LintSeverity = "error"
+	// LintWarning is an entry that is redundant or unclear but harmless.
+	LintWarning LintSeverity = "warning"
+)
+
+// LintFinding is a problematic permission entry found by LintSettings.
+type LintFinding struct {
+	Severity LintSeverity
+	List     string // Permission list of the entry: "allow", "deny" or "ask"
+	Entry    string
+	Message  string
+}
+
+// LintSettings checks the permission entries of s for mistakes that
+// deduplication against the global settings does not catch: entries listed
+// more than once in the same list, entries in several lists (e.g. both
+// allowed and denied), and malformed patterns such as "Bash(git:*" with
+// unbalanced parentheses. Findings are in list and entry order.
+func LintSettings(s *claude.Settings) []LintFinding {
+	lists := []struct {
+		name    string
+		entries []string
+	}{
+		{"allow", s.Permissions.Allow},
+		{"deny", s.Permissions.Deny},
+		{"ask", s.Permissions.Ask},
+	}
+
+	var findings []LintFinding
+	for i, list := range lists {
+		counts := make(map[string]int, len(list.entries))
+		for _, entry := range list.entries {
+			counts[entry]++
+		}
+
+		reported := make(map[string]bool, len(list.entries))
+		for _, entry := range list.entries {
+			if reported[entry] {
+				continue
+			}
+			reported[entry] = true
+
+			if msg := checkPattern(entry); msg != "" {
+				findings = append(findings, LintFinding{LintError, list.name, entry, msg})
+			}
+			if counts[entry] > 1 {
+				findings = append(findings, LintFinding{LintWarning, list.name, entry,
+					fmt.Sprintf("listed %d times in %s", counts[entry], list.name)})
+			}
+			// Report each conflict once, at the list that comes first
+			for _, other := range lists[i+1:] {
+				if !slices.Contains(other.entries, entry) {
+					continue
+				}
+				severity := LintWarning
+				if list.name == "allow" && other.name == "deny" {
+					severity = LintError
+				}
+				findings = append(findings, LintFinding{severity, list.name, entry,
+					fmt.Sprintf("also listed in %s; %s takes precedence", other.name, precedence(list.name, other.name))})
+			}
+		}
+	}
+
+	return findings
+}
+
+// precedence returns which of two permission lists wins for an entry in
+// both: deny over ask over allow.
+func precedence(a, b string) string {
+	rank := map[string]int{"allow": 0, "ask": 1, "deny": 2}
+	if rank[a] > rank[b] {
+		return a
+	}
+	return b
+}
+
+// checkPattern returns why a permission entry is malformed, or "" if it
+// looks fine.
+func checkPattern(entry string) string {
+	if strings.TrimSpace(entry) == "" {
+		return "empty entry"
+	}
+
+	depth := 0
+	for i, r := range entry {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return "unbalanced parentheses"
+			}
+			if depth == 0 && i != len(entry)-1 {
+				return "unexpected text after the closing parenthesis"
+			}
+		}
+	}
+	if depth != 0 {
+		return "unbalanced parentheses"
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package cleaner
+
+import (
+	"testing"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintSettings(t *testing.T) {
+	settings := &claude.Settings{
+		Permissions: claude.Permissions{
+			Allow: []string{"Read", "Bash(git:*)", "Read", "Bash(rm:*)", "Bash(npm:*", "WebFetch"},
+			Deny:  []string{"Bash(rm:*)", "Write)"},
+			Ask:   []string{"WebFetch", "Bash(curl:*) x", " "},
+		},
+	}
+
+	assert.Equal(t, []LintFinding{
+		{LintWarning, "allow", "Read", "listed 2 times in allow"},
+		{LintError, "allow", "Bash(rm:*)", "also listed in deny; deny takes precedence"},
+		{LintError, "allow", "Bash(npm:*", "unbalanced parentheses"},
+		{LintWarning, "allow", "WebFetch", "also listed in ask; ask takes precedence"},
+		{LintError, "deny", "Write)", "unbalanced parentheses"},
+		{LintError, "ask", "Bash(curl:*) x", "unexpected text after the closing parenthesis"},
+		{LintError, "ask", " ", "empty entry"},
+	}, LintSettings(settings))
+}
+
+func TestLintSettings_Clean(t *testing.T) {
+	settings := &claude.Settings{
+		Permissions: claude.Permissions{
+			Allow: []string{"Read", "Bash(git log:*)", `Bash(echo "(nested)")`},
+			Deny:  []string{"Bash(rm -rf:*)"},
+		},
+	}
+
+	assert.Empty(t, LintSettings(settings))
+	assert.Empty(t, LintSettings(&claude.Settings{NoPermissions: true}))
+}
@@ -1,9 +1,18 @@
 package cleaner
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
 	"github.com/mkoepf/claude-code-config-cleaner/internal/ui"
@@ -17,58 +26,216 @@ const (
 	OrphanTypeTodo         OrphanType = "todo"
 	OrphanTypeFileHistory  OrphanType = "file_history"
 	OrphanTypeSessionEnv   OrphanType = "session_env"
+	// OrphanTypeEmptyProjectDir is a project directory without any session files.
+	OrphanTypeEmptyProjectDir OrphanType = "empty_project_dir"
+	// OrphanTypeUnknownTodo is a todo file whose name matches no known format,
+	// so it cannot be attributed to a session.
+	OrphanTypeUnknownTodo OrphanType = "unknown_todo"
+	// OrphanTypeLog is a log file older than OrphanOptions.LogCutoff. Logs
+	// are not tied to sessions, so they are selected by age.
+	OrphanTypeLog OrphanType = "log"
+	// OrphanTypeDuplicateTodo is a todo file of a live session that is
+	// superseded by a more recently modified todo file of the same session and
+	// agent, e.g. a copy left behind by a crash.
+	OrphanTypeDuplicateTodo OrphanType = "duplicate_todo"
+	// OrphanTypeLock is a lock or pid file left behind by a process that is no
+	// longer running, see OrphanOptions.IncludeLocks.
+	OrphanTypeLock OrphanType = "lock"
 )
 
 // OrphanResult represents an orphan item found during scanning.
 type OrphanResult struct {
-	Type      OrphanType
-	Path      string
-	SizeSaved int64
+	Type       OrphanType
+	Path       string
+	SizeSaved  int64
+	Err        error        // Set by CleanOrphans if the item could not be removed
+	Details    []FileDetail // Largest files inside, see OrphanOptions.Details
+	DetailsErr error        // Set if some files inside could not be read for Details
+	TrashedTo  string       // Trash location if the item was moved instead of deleted
+	Reason     string       // Why the item is considered orphaned, e.g. "0-byte session file"
+}
+
+// FileDetail describes a file inside an orphaned directory.
+type FileDetail struct {
+	Path string // Relative to the orphaned directory
+	Size int64
+}
+
+// MaxOrphanDetails is the number of largest files listed per orphan
+// file-history directory when OrphanOptions.Details is set.
+const MaxOrphanDetails = 5
+
+// OrphanScope restricts orphan detection to the data of a single project.
+type OrphanScope struct {
+	ProjectDir string              // Project directory under the projects dir
+	SessionIDs map[string]struct{} // Session IDs belonging to the project
+}
+
+// NewProjectScope builds an OrphanScope from the session files in projectDir.
+// Session IDs are taken from the session file names, so empty sessions still
+// count as belonging to the project.
+func NewProjectScope(projectDir string) (*OrphanScope, error) {
+	entries, err := os.ReadDir(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := &OrphanScope{
+		ProjectDir: projectDir,
+		SessionIDs: make(map[string]struct{}),
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !claude.IsSessionFile(entry.Name()) {
+			continue
+		}
+		scope.SessionIDs[claude.SessionFileID(entry.Name())] = struct{}{}
+	}
+
+	return scope, nil
+}
+
+// includesSession reports whether data for sessionID is in scope.
+// A nil scope includes everything.
+func (s *OrphanScope) includesSession(sessionID string) bool {
+	if s == nil {
+		return true
+	}
+	_, ok := s.SessionIDs[sessionID]
+	return ok
+}
+
+// includesProjectDir reports whether projectDir is in scope.
+// A nil scope includes everything.
+func (s *OrphanScope) includesProjectDir(projectDir string) bool {
+	return s == nil || filepath.Clean(s.ProjectDir) == filepath.Clean(projectDir)
 }
 
 // FindOrphans scans the Claude directories for orphan data.
 // validSessionIDs is a list of session IDs that are still valid.
 func FindOrphans(paths *claude.Paths, validSessionIDs []string) ([]OrphanResult, error) {
-	validIDs := make(map[string]struct{}, len(validSessionIDs))
-	for _, id := range validSessionIDs {
-		validIDs[id] = struct{}{}
-	}
+	return FindOrphansWithOptions(context.Background(), paths, validSessionIDs, nil)
+}
 
-	var orphans []OrphanResult
+// OrphanOptions customizes orphan detection. The zero value finds all orphans.
+type OrphanOptions struct {
+	Scope       *OrphanScope   // Only report orphans of this project (nil = all)
+	TodoPattern *regexp.Regexp // Additional todo filename format, see ParseTodoPattern
+	Types       []OrphanType   // Only report orphans of these types (empty = all)
+	AgentID     string         // Only report todos written by this agent ("" = all)
+	Details     bool           // List the largest files of orphan file-history directories
+	// ExcludeEmpty skips empty session files and session-env directories,
+	// which free no space and may belong to a session that just started.
+	ExcludeEmpty bool
+	// LogCutoff reports log files last modified before it. Logs can be
+	// valuable for debugging, so the zero value does not report any.
+	LogCutoff time.Time
+	// IncludeLocks reports stale lock and pid files anywhere in the Claude
+	// home. They are tiny, so they are only worth removing when one keeps
+	// Claude Code from starting; the zero value does not report any.
+	IncludeLocks bool
+}
 
-	// Find empty session files
-	emptyOrphans, err := findEmptySessions(paths.Projects)
-	if err != nil {
-		return nil, err
+// includesType reports whether orphans of type t are selected by o.Types
+// and o.ExcludeEmpty.
+func (o *OrphanOptions) includesType(t OrphanType) bool {
+	if o.ExcludeEmpty && (t == OrphanTypeEmptySession || t == OrphanTypeSessionEnv) {
+		return false
 	}
-	orphans = append(orphans, emptyOrphans...)
+	return len(o.Types) == 0 || slices.Contains(o.Types, t)
+}
 
-	// Find orphan todos
-	todoOrphans, err := findOrphanTodos(paths.Todos, validIDs)
+// ParseTodoPattern compiles a regular expression for an additional todo
+// filename format. The session ID is taken from the capture group named
+// "session", or from the first capture group if there is none. An optional
+// group named "agent" captures the agent ID, which enables the detection of
+// duplicate todo files.
+func ParseTodoPattern(expr string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(expr)
 	if err != nil {
 		return nil, err
 	}
-	orphans = append(orphans, todoOrphans...)
+	if re.NumSubexp() == 0 {
+		return nil, fmt.Errorf("todo pattern %q has no capture group for the session ID", expr)
+	}
+	return re, nil
+}
 
-	// Find orphan file-history
-	historyOrphans, err := findOrphanFileHistory(paths.FileHistory, validIDs)
-	if err != nil {
-		return nil, err
+// FindOrphansWithOptions is like FindOrphans but applies opts (which may be
+// nil) and stops when ctx is done, returning the orphans found so far
+// together with ctx.Err().
+func FindOrphansWithOptions(ctx context.Context, paths *claude.Paths, validSessionIDs []string, opts *OrphanOptions) ([]OrphanResult, error) {
+	if opts == nil {
+		opts = &OrphanOptions{}
 	}
-	orphans = append(orphans, historyOrphans...)
+	scope := opts.Scope
 
-	// Find empty session-env directories
-	envOrphans, err := findEmptySessionEnv(paths.SessionEnv)
-	if err != nil {
-		return nil, err
+	validIDs := make(map[string]struct{}, len(validSessionIDs))
+	for _, id := range validSessionIDs {
+		validIDs[id] = struct{}{}
+	}
+
+	finders := []struct {
+		types []OrphanType
+		find  func() ([]OrphanResult, error)
+	}{
+		// Empty session files
+		{[]OrphanType{OrphanTypeEmptySession}, func() ([]OrphanResult, error) {
+			return findEmptySessions(ctx, paths.Projects, scope)
+		}},
+		// Project directories without session files
+		{[]OrphanType{OrphanTypeEmptyProjectDir}, func() ([]OrphanResult, error) {
+			return findEmptyProjectDirs(ctx, paths.Projects, scope)
+		}},
+		// Orphan and unrecognized todos
+		{[]OrphanType{OrphanTypeTodo, OrphanTypeUnknownTodo, OrphanTypeDuplicateTodo}, func() ([]OrphanResult, error) {
+			return findOrphanTodos(ctx, paths.Todos, validIDs, opts)
+		}},
+		// Orphan file-history
+		{[]OrphanType{OrphanTypeFileHistory}, func() ([]OrphanResult, error) {
+			return findOrphanFileHistory(ctx, paths.FileHistory, validIDs, opts)
+		}},
+		// Empty session-env directories
+		{[]OrphanType{OrphanTypeSessionEnv}, func() ([]OrphanResult, error) {
+			return findEmptySessionEnv(ctx, paths.SessionEnv, scope)
+		}},
+		// Old log files, which cannot be attributed to a project
+		{[]OrphanType{OrphanTypeLog}, func() ([]OrphanResult, error) {
+			if opts.LogCutoff.IsZero() || scope != nil {
+				return nil, nil
+			}
+			return findOldLogs(ctx, paths.Logs, opts.LogCutoff)
+		}},
+		// Lock and pid files of processes that are gone
+		{[]OrphanType{OrphanTypeLock}, func() ([]OrphanResult, error) {
+			if !opts.IncludeLocks || scope != nil {
+				return nil, nil
+			}
+			return findStaleLocks(ctx, paths.Root)
+		}},
+	}
+
+	var orphans []OrphanResult
+	for _, f := range finders {
+		if !slices.ContainsFunc(f.types, opts.includesType) {
+			continue
+		}
+
+		found, err := f.find()
+		for _, o := range found {
+			if opts.includesType(o.Type) {
+				orphans = append(orphans, o)
+			}
+		}
+		if err != nil {
+			return orphans, err
+		}
 	}
-	orphans = append(orphans, envOrphans...)
 
 	return orphans, nil
 }
 
-// findEmptySessions finds 0-byte .jsonl files in the projects directory.
-func findEmptySessions(projectsDir string) ([]OrphanResult, error) {
+// findEmptySessions finds 0-byte session files in the projects directory.
+func findEmptySessions(ctx context.Context, projectsDir string, scope *OrphanScope) ([]OrphanResult, error) {
 	var orphans []OrphanResult
 
 	if _, err := os.Stat(projectsDir); os.IsNotExist(err) {
@@ -81,11 +248,17 @@ func findEmptySessions(projectsDir string) ([]OrphanResult, error) {
 	}
 
 	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return orphans, err
+		}
 		if !entry.IsDir() {
 			continue
 		}
 
 		projectPath := filepath.Join(projectsDir, entry.Name())
+		if !scope.includesProjectDir(projectPath) {
+			continue
+		}
 		sessionEntries, err := os.ReadDir(projectPath)
 		if err != nil {
 			continue
@@ -95,7 +268,7 @@ func findEmptySessions(projectsDir string) ([]OrphanResult, error) {
 			if sessionEntry.IsDir() {
 				continue
 			}
-			if filepath.Ext(sessionEntry.Name()) != ".jsonl" {
+			if !claude.IsSessionFile(sessionEntry.Name()) {
 				continue
 			}
 
@@ -110,6 +283,7 @@ func findEmptySessions(projectsDir string) ([]OrphanResult, error) {
 					Type:      OrphanTypeEmptySession,
 					Path:      sessionPath,
 					SizeSaved: 0,
+					Reason:    "0-byte session file",
 				})
 			}
 		}
@@ -118,10 +292,96 @@ func findEmptySessions(projectsDir string) ([]OrphanResult, error) {
 	return orphans, nil
 }
 
+// findEmptyProjectDirs finds project directories that contain no files at
+// all, e.g. leftovers from manual edits. Directories with other files, or
+// with sessions in subdirectories, may still hold data and are kept.
+func findEmptyProjectDirs(ctx context.Context, projectsDir string, scope *OrphanScope) ([]OrphanResult, error) {
+	var orphans []OrphanResult
+
+	if _, err := os.Stat(projectsDir); os.IsNotExist(err) {
+		return orphans, nil
+	}
+
+	entries, err := os.ReadDir(projectsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return orphans, err
+		}
+		if !entry.IsDir() {
+			continue
+		}
+
+		projectPath := filepath.Join(projectsDir, entry.Name())
+		if !scope.includesProjectDir(projectPath) || !IsEmptyProjectDir(projectPath) {
+			continue
+		}
+
+		size, err := dirSize(ctx, projectPath)
+		if err != nil {
+			continue
+		}
+
+		orphans = append(orphans, OrphanResult{
+			Type:      OrphanTypeEmptyProjectDir,
+			Path:      projectPath,
+			SizeSaved: size,
+			Reason:    "project directory contains no files",
+		})
+	}
+
+	return orphans, nil
+}
+
+// IsEmptyProjectDir reports whether projectDir is a readable directory that
+// contains no files at any depth, only (empty) subdirectories.
+func IsEmptyProjectDir(projectDir string) bool {
+	errNotEmpty := errors.New("not empty")
+	err := filepath.WalkDir(projectDir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return errNotEmpty
+		}
+		return nil
+	})
+	return err == nil
+}
+
+// HasSessionFiles reports whether projectDir directly contains session
+// files. Unreadable directories are reported as having some, so they are
+// never mistaken for leftovers.
+func HasSessionFiles(projectDir string) bool {
+	entries, err := os.ReadDir(projectDir)
+	if err != nil {
+		return true
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && claude.IsSessionFile(entry.Name()) {
+			return true
+		}
+	}
+	return false
+}
+
 // findOrphanTodos finds todo files that reference non-existent sessions.
-// Todo files are named: {sessionID}-agent-{agentID}.json
-func findOrphanTodos(todosDir string, validIDs map[string]struct{}) ([]OrphanResult, error) {
+// Todo files are named: {sessionID}-agent-{agentID}.json, or match
+// opts.TodoPattern if given. Files matching neither are reported as
+// OrphanTypeUnknownTodo, except when scoped to a project or an agent, as they
+// cannot be attributed to one. Of several todo files of a live session with
+// the same agent, all but the newest are reported as OrphanTypeDuplicateTodo.
+func findOrphanTodos(ctx context.Context, todosDir string, validIDs map[string]struct{}, opts *OrphanOptions) ([]OrphanResult, error) {
 	var orphans []OrphanResult
+	scope := opts.Scope
+
+	// Todo files of live sessions, grouped by session and agent
+	var groups [][]todoFile
+	groupIndex := make(map[[2]string]int)
 
 	if _, err := os.Stat(todosDir); os.IsNotExist(err) {
 		return orphans, nil
@@ -133,33 +393,140 @@ func findOrphanTodos(todosDir string, validIDs map[string]struct{}) ([]OrphanRes
 	}
 
 	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return orphans, err
+		}
 		if entry.IsDir() {
 			continue
 		}
 
-		sessionID := extractSessionIDFromTodoFilename(entry.Name())
-		if sessionID == "" {
+		if opts.AgentID != "" && extractAgentIDFromTodoFilename(entry.Name()) != opts.AgentID {
 			continue
 		}
 
-		if _, exists := validIDs[sessionID]; !exists {
-			todoPath := filepath.Join(todosDir, entry.Name())
+		sessionID := extractSessionIDFromTodoFilename(entry.Name())
+		if sessionID == "" {
+			sessionID = matchTodoPattern(opts.TodoPattern, entry.Name())
+		}
+		if sessionID == "" {
+			if scope != nil {
+				continue
+			}
 			info, err := entry.Info()
 			if err != nil {
 				continue
 			}
+			orphans = append(orphans, OrphanResult{
+				Type:      OrphanTypeUnknownTodo,
+				Path:      filepath.Join(todosDir, entry.Name()),
+				SizeSaved: info.Size(),
+				Reason:    "todo file name matches no known format, so its session cannot be checked",
+			})
+			continue
+		}
+		if !scope.includesSession(sessionID) {
+			continue
+		}
 
+		todoPath := filepath.Join(todosDir, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		if _, exists := validIDs[sessionID]; !exists {
 			orphans = append(orphans, OrphanResult{
 				Type:      OrphanTypeTodo,
 				Path:      todoPath,
 				SizeSaved: info.Size(),
+				Reason:    fmt.Sprintf("todo references session %s, which is not in any project", sessionID),
 			})
+			continue
 		}
+
+		// Without an agent ID, files of different agents cannot be told apart
+		agentID := todoAgentID(opts.TodoPattern, entry.Name())
+		if agentID == "" {
+			continue
+		}
+		key := [2]string{sessionID, agentID}
+		i, ok := groupIndex[key]
+		if !ok {
+			i = len(groups)
+			groupIndex[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], todoFile{todoPath, info.Size(), info.ModTime(), sessionID, agentID})
+	}
+
+	for _, group := range groups {
+		orphans = append(orphans, duplicateTodos(group)...)
 	}
 
 	return orphans, nil
 }
 
+// todoFile is a todo file of a live session.
+type todoFile struct {
+	path      string
+	size      int64
+	modTime   time.Time
+	sessionID string
+	agentID   string
+}
+
+// duplicateTodos returns all but the most recently modified of the todo
+// files of one session and agent as OrphanTypeDuplicateTodo.
+func duplicateTodos(group []todoFile) []OrphanResult {
+	if len(group) < 2 {
+		return nil
+	}
+
+	newest := 0
+	for i, f := range group {
+		if f.modTime.After(group[newest].modTime) {
+			newest = i
+		}
+	}
+
+	var duplicates []OrphanResult
+	for i, f := range group {
+		if i == newest {
+			continue
+		}
+		duplicates = append(duplicates, OrphanResult{
+			Type:      OrphanTypeDuplicateTodo,
+			Path:      f.path,
+			SizeSaved: f.size,
+			Reason: fmt.Sprintf("session %s has a newer todo file of agent %s: %s",
+				f.sessionID, f.agentID, filepath.Base(group[newest].path)),
+		})
+	}
+	return duplicates
+}
+
+// todoAgentID returns the agent ID of a todo file, from the default
+// {sessionID}-agent-{agentID}.json name or the "agent" group of pattern, or
+// "" if it is unknown.
+func todoAgentID(pattern *regexp.Regexp, filename string) string {
+	if agentID := extractAgentIDFromTodoFilename(filename); agentID != "" {
+		return agentID
+	}
+	if pattern == nil {
+		return ""
+	}
+
+	i := pattern.SubexpIndex("agent")
+	if i < 0 {
+		return ""
+	}
+	match := pattern.FindStringSubmatch(filename)
+	if match == nil {
+		return ""
+	}
+	return match[i]
+}
+
 // extractSessionIDFromTodoFilename extracts the session ID from a todo filename.
 // Format: {sessionID}-agent-{agentID}.json
 func extractSessionIDFromTodoFilename(filename string) string {
@@ -179,9 +546,44 @@ func extractSessionIDFromTodoFilename(filename string) string {
 	return name[:idx]
 }
 
+// extractAgentIDFromTodoFilename extracts the agent ID from a todo filename.
+// Format: {sessionID}-agent-{agentID}.json
+func extractAgentIDFromTodoFilename(filename string) string {
+	if !strings.HasSuffix(filename, ".json") {
+		return ""
+	}
+
+	name := strings.TrimSuffix(filename, ".json")
+	idx := strings.Index(name, "-agent-")
+	if idx == -1 {
+		return ""
+	}
+
+	return name[idx+len("-agent-"):]
+}
+
+// matchTodoPattern returns the session ID captured by pattern from filename,
+// or "" if pattern is nil or does not match.
+func matchTodoPattern(pattern *regexp.Regexp, filename string) string {
+	if pattern == nil {
+		return ""
+	}
+
+	match := pattern.FindStringSubmatch(filename)
+	if match == nil {
+		return ""
+	}
+
+	if i := pattern.SubexpIndex("session"); i > 0 {
+		return match[i]
+	}
+	return match[1]
+}
+
 // findOrphanFileHistory finds file-history directories for non-existent sessions.
-func findOrphanFileHistory(historyDir string, validIDs map[string]struct{}) ([]OrphanResult, error) {
+func findOrphanFileHistory(ctx context.Context, historyDir string, validIDs map[string]struct{}, opts *OrphanOptions) ([]OrphanResult, error) {
 	var orphans []OrphanResult
+	scope := opts.Scope
 
 	if _, err := os.Stat(historyDir); os.IsNotExist(err) {
 		return orphans, nil
@@ -193,22 +595,42 @@ func findOrphanFileHistory(historyDir string, validIDs map[string]struct{}) ([]O
 	}
 
 	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return orphans, err
+		}
 		if !entry.IsDir() {
 			continue
 		}
 
 		sessionID := entry.Name()
+		if !scope.includesSession(sessionID) {
+			continue
+		}
 		if _, exists := validIDs[sessionID]; !exists {
 			historyPath := filepath.Join(historyDir, sessionID)
-			size, err := dirSize(historyPath)
+			size, err := dirSize(ctx, historyPath)
 			if err != nil {
 				continue
 			}
 
+			// Details are informational, so files that cannot be read are
+			// only noted and do not stop the scan
+			var details []FileDetail
+			var detailsErr error
+			if opts.Details {
+				details, detailsErr = largestFiles(ctx, historyPath, MaxOrphanDetails)
+				if err := ctx.Err(); err != nil {
+					return orphans, err
+				}
+			}
+
 			orphans = append(orphans, OrphanResult{
-				Type:      OrphanTypeFileHistory,
-				Path:      historyPath,
-				SizeSaved: size,
+				Type:       OrphanTypeFileHistory,
+				Path:       historyPath,
+				SizeSaved:  size,
+				Details:    details,
+				DetailsErr: detailsErr,
+				Reason:     fmt.Sprintf("file-history directory of session %s, which is not in any project", sessionID),
 			})
 		}
 	}
@@ -216,8 +638,103 @@ func findOrphanFileHistory(historyDir string, validIDs map[string]struct{}) ([]O
 	return orphans, nil
 }
 
+// SessionData is the todo and file-history data of a project's sessions,
+// which is orphaned once the project is cleaned.
+type SessionData struct {
+	Todos           int
+	TodoSize        int64
+	FileHistory     int // Number of file-history directories
+	FileHistorySize int64
+}
+
+// IsEmpty reports whether there is no todo or file-history data.
+func (d SessionData) IsEmpty() bool {
+	return d.Todos == 0 && d.FileHistory == 0
+}
+
+// FindOrphanedByCleaning returns the todos and file history that cleaning the
+// stale projects would orphan, keyed by the encoded name of the project whose
+// session they belong to. Sessions that a project outside stale shares stay
+// valid, so their data is not counted. Todo files are attributed like in
+// FindOrphansWithOptions, using todoPattern if given.
+func FindOrphanedByCleaning(ctx context.Context, paths *claude.Paths, projects, stale []claude.Project, todoPattern *regexp.Regexp) (map[string]SessionData, error) {
+	kept := make(map[string]struct{})
+	for _, id := range SessionIDsExcludingStale(projects, stale) {
+		kept[id] = struct{}{}
+	}
+	owners := make(map[string]string)
+	for _, p := range stale {
+		for _, id := range p.SessionIDs {
+			if _, ok := kept[id]; ok {
+				continue
+			}
+			if _, ok := owners[id]; !ok {
+				owners[id] = p.EncodedName
+			}
+		}
+	}
+
+	data := make(map[string]SessionData)
+	if len(owners) == 0 {
+		return data, nil
+	}
+
+	todos, err := os.ReadDir(paths.Todos)
+	if err != nil && !os.IsNotExist(err) {
+		return data, err
+	}
+	for _, entry := range todos {
+		if err := ctx.Err(); err != nil {
+			return data, err
+		}
+		if entry.IsDir() {
+			continue
+		}
+		sessionID := extractSessionIDFromTodoFilename(entry.Name())
+		if sessionID == "" {
+			sessionID = matchTodoPattern(todoPattern, entry.Name())
+		}
+		owner, ok := owners[sessionID]
+		if !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		d := data[owner]
+		d.Todos++
+		d.TodoSize += info.Size()
+		data[owner] = d
+	}
+
+	histories, err := os.ReadDir(paths.FileHistory)
+	if err != nil && !os.IsNotExist(err) {
+		return data, err
+	}
+	for _, entry := range histories {
+		owner, ok := owners[entry.Name()]
+		if !ok || !entry.IsDir() {
+			continue
+		}
+		size, err := dirSize(ctx, filepath.Join(paths.FileHistory, entry.Name()))
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return data, ctxErr
+			}
+			continue
+		}
+		d := data[owner]
+		d.FileHistory++
+		d.FileHistorySize += size
+		data[owner] = d
+	}
+
+	return data, nil
+}
+
 // findEmptySessionEnv finds empty directories in session-env.
-func findEmptySessionEnv(sessionEnvDir string) ([]OrphanResult, error) {
+func findEmptySessionEnv(ctx context.Context, sessionEnvDir string, scope *OrphanScope) ([]OrphanResult, error) {
 	var orphans []OrphanResult
 
 	if _, err := os.Stat(sessionEnvDir); os.IsNotExist(err) {
@@ -230,10 +747,17 @@ func findEmptySessionEnv(sessionEnvDir string) ([]OrphanResult, error) {
 	}
 
 	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return orphans, err
+		}
 		if !entry.IsDir() {
 			continue
 		}
 
+		if !scope.includesSession(entry.Name()) {
+			continue
+		}
+
 		envPath := filepath.Join(sessionEnvDir, entry.Name())
 		empty, err := isDirEmpty(envPath)
 		if err != nil {
@@ -245,6 +769,7 @@ func findEmptySessionEnv(sessionEnvDir string) ([]OrphanResult, error) {
 				Type:      OrphanTypeSessionEnv,
 				Path:      envPath,
 				SizeSaved: 0,
+				Reason:    "empty session-env directory",
 			})
 		}
 	}
@@ -252,6 +777,128 @@ func findEmptySessionEnv(sessionEnvDir string) ([]OrphanResult, error) {
 	return orphans, nil
 }
 
+// findOldLogs finds files below logsDir last modified before cutoff.
+// Entries that cannot be read are skipped, so an unreadable directory only
+// hides the logs inside it.
+func findOldLogs(ctx context.Context, logsDir string, cutoff time.Time) ([]OrphanResult, error) {
+	var orphans []OrphanResult
+
+	if _, err := os.Stat(logsDir); os.IsNotExist(err) {
+		return orphans, nil
+	}
+
+	err := filepath.WalkDir(logsDir, func(path string, d os.DirEntry, err error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if info.ModTime().Before(cutoff) {
+			orphans = append(orphans, OrphanResult{
+				Type:      OrphanTypeLog,
+				Path:      path,
+				SizeSaved: info.Size(),
+				Reason: fmt.Sprintf("log last modified %s, before the cutoff %s",
+					info.ModTime().Format(time.DateOnly), cutoff.Format(time.DateOnly)),
+			})
+		}
+		return nil
+	})
+	return orphans, err
+}
+
+// maxLockDepth is the deepest directory level below the Claude home that
+// findStaleLocks looks into.
+const maxLockDepth = 3
+
+// lockSkipDirs are directories of the Claude home that findStaleLocks does
+// not descend into: cccc's own trash and archive.
+var lockSkipDirs = []string{"cccc-trash", "cccc-archive"}
+
+// findStaleLocks finds *.lock and *.pid files below root whose process is
+// no longer running. The process is taken from the pid the file contains.
+// Files without one are kept, however old: advisory locks are often empty
+// and never modified while the process holding them runs.
+func findStaleLocks(ctx context.Context, root string) ([]OrphanResult, error) {
+	var orphans []OrphanResult
+
+	root = filepath.Clean(root)
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return orphans, nil
+	}
+
+	err := walkBounded(root, maxLockDepth, lockSkipDirs, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || (!strings.HasSuffix(d.Name(), ".lock") && !strings.HasSuffix(d.Name(), ".pid")) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		reason, stale := lockStaleness(path)
+		if !stale {
+			return nil
+		}
+		orphans = append(orphans, OrphanResult{
+			Type:      OrphanTypeLock,
+			Path:      path,
+			SizeSaved: info.Size(),
+			Reason:    reason,
+		})
+		return nil
+	})
+	return orphans, err
+}
+
+// lockStaleness reports whether the lock file at path records the pid of a
+// process that is no longer running, and why.
+func lockStaleness(path string) (string, bool) {
+	pid, ok := readLockPID(path)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("process %d is no longer running", pid), !processAlive(pid)
+}
+
+// readLockPID returns the pid a lock file starts with, if any.
+func readLockPID(path string) (int, bool) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	buf := make([]byte, 32)
+	n, _ := f.Read(buf)
+	fields := strings.Fields(string(buf[:n]))
+	if len(fields) == 0 {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	return pid, true
+}
+
 // isDirEmpty returns true if the directory contains no files.
 func isDirEmpty(path string) (bool, error) {
 	entries, err := os.ReadDir(path)
@@ -262,12 +909,16 @@ func isDirEmpty(path string) (bool, error) {
 }
 
 // dirSize calculates the total size of a directory and its contents.
-func dirSize(path string) (int64, error) {
+// It stops early with ctx.Err() when ctx is done.
+func dirSize(ctx context.Context, path string) (int64, error) {
 	var size int64
 	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if !info.IsDir() {
 			size += info.Size()
 		}
@@ -276,42 +927,140 @@ func dirSize(path string) (int64, error) {
 	return size, err
 }
 
+// largestFiles returns up to n of the largest files below dir, largest
+// first. Entries that cannot be read are skipped; the first such error is
+// returned together with the files that could be listed. It stops early
+// with ctx.Err() when ctx is done.
+func largestFiles(ctx context.Context, dir string, n int) ([]FileDetail, error) {
+	var files []FileDetail
+	var readErr error
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err != nil {
+			if readErr == nil {
+				readErr = err
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				if readErr == nil {
+					readErr = err
+				}
+				return nil
+			}
+			files = append(files, FileDetail{Path: rel, Size: info.Size()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Size > files[j].Size })
+	if len(files) > n {
+		files = files[:n]
+	}
+	return files, readErr
+}
+
+// OrphanSummary aggregates the orphans of one type.
+type OrphanSummary struct {
+	Type  OrphanType
+	Count int
+	Size  int64
+}
+
+// SummarizeOrphans returns the count and total size of the orphans per type,
+// largest total size first.
+func SummarizeOrphans(orphans []OrphanResult) []OrphanSummary {
+	var summary []OrphanSummary
+	index := make(map[OrphanType]int)
+	for _, o := range orphans {
+		i, ok := index[o.Type]
+		if !ok {
+			i = len(summary)
+			index[o.Type] = i
+			summary = append(summary, OrphanSummary{Type: o.Type})
+		}
+		summary[i].Count++
+		summary[i].Size += o.SizeSaved
+	}
+
+	sort.SliceStable(summary, func(i, j int) bool { return summary[i].Size > summary[j].Size })
+	return summary
+}
+
 // CleanOrphans removes the orphan items.
 // If dryRun is true, returns what would be deleted without making changes.
+// Removal continues past individual failures: each failed result has its Err
+// field set and SizeSaved zeroed, and the returned error joins all failures.
 func CleanOrphans(orphans []OrphanResult, dryRun bool) ([]OrphanResult, error) {
+	return CleanOrphansWithOptions(orphans, CleanOrphanOptions{DryRun: dryRun})
+}
+
+// CleanOrphanOptions customizes CleanOrphansWithOptions. The zero value
+// deletes all orphans, continuing past individual failures.
+type CleanOrphanOptions struct {
+	DryRun   bool                        // Return what would be removed without removing anything
+	TrashDir string                      // Move items into this trash batch directory instead of deleting them
+	FailFast bool                        // Stop at the first failure
+	Progress func(n int, o OrphanResult) // Called with the 1-based index of each orphan before it is removed
+}
+
+// CleanOrphansWithOptions is like CleanOrphans but applies opts. With
+// opts.FailFast, the results end with the first orphan that could not be
+// removed.
+func CleanOrphansWithOptions(orphans []OrphanResult, opts CleanOrphanOptions) ([]OrphanResult, error) {
 	results := make([]OrphanResult, len(orphans))
 	copy(results, orphans)
 
-	if dryRun {
+	if opts.DryRun {
 		return results, nil
 	}
 
+	var errs []error
 	for i := range results {
-		path := results[i].Path
-
-		// Check if path exists
-		info, err := os.Stat(path)
-		if os.IsNotExist(err) {
-			results[i].SizeSaved = 0
-			continue
+		if opts.Progress != nil {
+			opts.Progress(i+1, results[i])
 		}
-		if err != nil {
-			return results, err
+		var err error
+		if opts.TrashDir != "" {
+			results[i].TrashedTo, err = TrashPath(results[i].Path, opts.TrashDir)
+		} else {
+			err = removeOrphan(results[i].Path)
 		}
-
-		// Remove file or directory
-		if info.IsDir() {
-			if err := os.RemoveAll(path); err != nil {
-				return results, err
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				results[i].SizeSaved = 0
+				continue
 			}
-		} else {
-			if err := os.Remove(path); err != nil {
-				return results, err
+			results[i].SizeSaved = 0
+			results[i].Err = err
+			errs = append(errs, fmt.Errorf("%s: %w", results[i].Path, err))
+			if opts.FailFast {
+				return results[:i+1], errors.Join(errs...)
 			}
 		}
 	}
 
-	return results, nil
+	return results, errors.Join(errs...)
+}
+
+// removeOrphan removes a single orphan file or directory.
+func removeOrphan(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return os.RemoveAll(path)
+	}
+	return os.Remove(path)
 }
 
 // BuildOrphanPreview creates a preview of orphans to be cleaned.
@@ -331,6 +1080,23 @@ func BuildOrphanPreview(orphans []OrphanResult) *ui.Preview {
 			description = "Orphan file history"
 		case OrphanTypeSessionEnv:
 			description = "Empty session env"
+		case OrphanTypeEmptyProjectDir:
+			description = "Empty project directory"
+		case OrphanTypeUnknownTodo:
+			description = "Unrecognized todo file"
+		case OrphanTypeDuplicateTodo:
+			description = "Duplicate todo (a newer file of the same session and agent is kept)"
+		case OrphanTypeLog:
+			description = fmt.Sprintf("Old log file (%s)", ui.FormatSize(o.SizeSaved))
+		case OrphanTypeLock:
+			description = "Stale lock file (its process is no longer running)"
+		}
+		for _, d := range o.Details {
+			description += fmt.Sprintf("\n     %8s  %s", ui.FormatSize(d.Size), d.Path)
+		}
+		var note string
+		if o.DetailsErr != nil {
+			note = fmt.Sprintf("not all files inside could be listed: %v", o.DetailsErr)
 		}
 
 		preview.Changes = append(preview.Changes, ui.Change{
@@ -338,6 +1104,7 @@ func BuildOrphanPreview(orphans []OrphanResult) *ui.Preview {
 			Path:        o.Path,
 			Description: description,
 			Size:        o.SizeSaved,
+			Note:        note,
 		})
 	}
 
@@ -1,9 +1,16 @@
 package cleaner
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
 	"github.com/stretchr/testify/assert"
@@ -116,6 +123,42 @@ func TestFindOrphans_OrphanFileHistory(t *testing.T) {
 	assert.Equal(t, orphanHistory, historyOrphans[0].Path)
 }
 
+func TestFindOrphanedByCleaning(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &claude.Paths{
+		Root:        tmpDir,
+		Projects:    filepath.Join(tmpDir, "projects"),
+		Todos:       filepath.Join(tmpDir, "todos"),
+		FileHistory: filepath.Join(tmpDir, "file-history"),
+		SessionEnv:  filepath.Join(tmpDir, "session-env"),
+	}
+	require.NoError(t, os.MkdirAll(paths.Todos, 0755))
+
+	for _, name := range []string{"stale1-agent-a.json", "stale1-agent-b.json", "shared-agent-a.json", "live-agent-a.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(paths.Todos, name), []byte(`{}`), 0644))
+	}
+	for _, id := range []string{"stale1", "stale2", "shared"} {
+		dir := filepath.Join(paths.FileHistory, id)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt"), []byte("content"), 0644))
+	}
+
+	staleA := claude.Project{EncodedName: "-a", SessionIDs: []string{"stale1", "shared"}}
+	staleB := claude.Project{EncodedName: "-b", SessionIDs: []string{"stale2"}}
+	staleC := claude.Project{EncodedName: "-c", SessionIDs: []string{"nothing"}}
+	live := claude.Project{EncodedName: "-live", SessionIDs: []string{"live", "shared"}}
+	stale := []claude.Project{staleA, staleB, staleC}
+
+	data, err := FindOrphanedByCleaning(context.Background(), paths, append(stale, live), stale, nil)
+	require.NoError(t, err)
+
+	// The shared session stays valid through the live project
+	assert.Equal(t, SessionData{Todos: 2, TodoSize: 4, FileHistory: 1, FileHistorySize: 7}, data["-a"])
+	assert.Equal(t, SessionData{FileHistory: 1, FileHistorySize: 7}, data["-b"])
+	assert.True(t, data["-c"].IsEmpty())
+	assert.NotContains(t, data, "-live")
+}
+
 func TestFindOrphans_EmptySessionEnv(t *testing.T) {
 	tmpDir := t.TempDir()
 	paths := &claude.Paths{
@@ -260,6 +303,41 @@ func TestCleanOrphans_NonexistentPath(t *testing.T) {
 	assert.Equal(t, int64(0), results[0].SizeSaved)
 }
 
+func TestCleanOrphans_ContinuesPastErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// A path beneath a regular file cannot be stat'ed (ENOTDIR), even as root
+	blocker := filepath.Join(tmpDir, "blocker")
+	require.NoError(t, os.WriteFile(blocker, []byte("x"), 0644))
+	failing := filepath.Join(blocker, "child")
+
+	first := filepath.Join(tmpDir, "first.json")
+	last := filepath.Join(tmpDir, "last.json")
+	require.NoError(t, os.WriteFile(first, []byte(`{}`), 0644))
+	require.NoError(t, os.WriteFile(last, []byte(`{}`), 0644))
+
+	orphans := []OrphanResult{
+		{Type: OrphanTypeTodo, Path: first, SizeSaved: 2},
+		{Type: OrphanTypeTodo, Path: failing, SizeSaved: 5},
+		{Type: OrphanTypeTodo, Path: last, SizeSaved: 2},
+	}
+
+	results, err := CleanOrphans(orphans, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), failing)
+
+	// Items before and after the failure are still removed
+	assert.NoFileExists(t, first)
+	assert.NoFileExists(t, last)
+
+	require.Len(t, results, 3)
+	assert.NoError(t, results[0].Err)
+	assert.Error(t, results[1].Err)
+	assert.Equal(t, int64(0), results[1].SizeSaved)
+	assert.NoError(t, results[2].Err)
+	assert.Equal(t, int64(2), results[2].SizeSaved)
+}
+
 func TestBuildOrphanPreview(t *testing.T) {
 	orphans := []OrphanResult{
 		{
@@ -300,6 +378,39 @@ func TestBuildOrphanPreview(t *testing.T) {
 	assert.True(t, types["Empty session env"])
 }
 
+func TestBuildOrphanPreview_DetailsErrNote(t *testing.T) {
+	orphans := []OrphanResult{{
+		Type:       OrphanTypeFileHistory,
+		Path:       "/home/.claude/file-history/gone",
+		Details:    []FileDetail{{Path: "a.txt", Size: 10}},
+		DetailsErr: os.ErrPermission,
+	}}
+
+	preview := BuildOrphanPreview(orphans)
+
+	require.Len(t, preview.Changes, 1)
+	assert.Contains(t, preview.Changes[0].Description, "a.txt")
+	assert.Equal(t, "not all files inside could be listed: permission denied", preview.Changes[0].Note)
+}
+
+func TestLargestFiles_SkipsUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big.txt"), make([]byte, 100), 0644))
+	locked := filepath.Join(dir, "locked")
+	require.NoError(t, os.MkdirAll(locked, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(locked, "hidden.txt"), nil, 0644))
+	require.NoError(t, os.Chmod(locked, 0))
+	defer os.Chmod(locked, 0755)
+	if _, err := os.ReadDir(locked); err == nil {
+		t.Skip("directory permissions are not enforced (e.g. running as root)")
+	}
+
+	files, err := largestFiles(context.Background(), dir, MaxOrphanDetails)
+
+	assert.ErrorIs(t, err, os.ErrPermission)
+	assert.Equal(t, []FileDetail{{Path: "big.txt", Size: 100}}, files)
+}
+
 func TestBuildOrphanPreview_Empty(t *testing.T) {
 	preview := BuildOrphanPreview(nil)
 
@@ -340,3 +451,536 @@ func TestExtractSessionIDFromTodoFilename(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractAgentIDFromTodoFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		expected string
+	}{
+		{"sess1-agent-abc.json", "abc"},
+		{"session-uuid-agent-agent-uuid.json", "agent-uuid"},
+		{"noagentsuffix.json", ""},
+		{"sess1-agent-abc.txt", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.filename, func(t *testing.T) {
+			assert.Equal(t, tc.expected, extractAgentIDFromTodoFilename(tc.filename))
+		})
+	}
+}
+
+func TestFindOrphans_AgentID(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &claude.Paths{
+		Root:        tmpDir,
+		Projects:    filepath.Join(tmpDir, "projects"),
+		Todos:       filepath.Join(tmpDir, "todos"),
+		FileHistory: filepath.Join(tmpDir, "file-history"),
+		SessionEnv:  filepath.Join(tmpDir, "session-env"),
+	}
+	require.NoError(t, os.MkdirAll(paths.Todos, 0755))
+
+	junk := filepath.Join(paths.Todos, "gone-agent-junk.json")
+	for _, name := range []string{"gone-agent-junk.json", "gone-agent-other.json", "valid-agent-junk.json", "notes.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(paths.Todos, name), []byte(`{}`), 0644))
+	}
+
+	orphans, err := FindOrphansWithOptions(context.Background(), paths, []string{"valid"}, &OrphanOptions{AgentID: "junk"})
+	require.NoError(t, err)
+
+	require.Len(t, orphans, 1)
+	assert.Equal(t, junk, orphans[0].Path)
+	assert.Equal(t, OrphanTypeTodo, orphans[0].Type)
+}
+
+func TestFindOrphansWithOptions_Scope(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &claude.Paths{
+		Root:        tmpDir,
+		Projects:    filepath.Join(tmpDir, "projects"),
+		Todos:       filepath.Join(tmpDir, "todos"),
+		FileHistory: filepath.Join(tmpDir, "file-history"),
+		SessionEnv:  filepath.Join(tmpDir, "session-env"),
+	}
+
+	// Target project with an empty session, other project with an empty session
+	targetDir := filepath.Join(paths.Projects, "-target")
+	otherDir := filepath.Join(paths.Projects, "-other")
+	require.NoError(t, os.MkdirAll(targetDir, 0755))
+	require.NoError(t, os.MkdirAll(otherDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(targetDir, "target-sess.jsonl"), []byte{}, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(otherDir, "other-sess.jsonl"), []byte{}, 0644))
+
+	// Orphan todos and file history for both sessions
+	require.NoError(t, os.MkdirAll(paths.Todos, 0755))
+	targetTodo := filepath.Join(paths.Todos, "target-sess-agent-a.json")
+	require.NoError(t, os.WriteFile(targetTodo, []byte(`{}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(paths.Todos, "other-sess-agent-a.json"), []byte(`{}`), 0644))
+	targetHistory := filepath.Join(paths.FileHistory, "target-sess")
+	require.NoError(t, os.MkdirAll(targetHistory, 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(paths.FileHistory, "other-sess"), 0755))
+
+	scope, err := NewProjectScope(targetDir)
+	require.NoError(t, err)
+
+	orphans, err := FindOrphansWithOptions(context.Background(), paths, nil, &OrphanOptions{Scope: scope})
+	require.NoError(t, err)
+
+	var found []string
+	for _, o := range orphans {
+		found = append(found, o.Path)
+	}
+	assert.ElementsMatch(t, []string{
+		filepath.Join(targetDir, "target-sess.jsonl"),
+		targetTodo,
+		targetHistory,
+	}, found)
+}
+
+func TestFindOrphans_EmptyProjectDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &claude.Paths{
+		Root:        tmpDir,
+		Projects:    filepath.Join(tmpDir, "projects"),
+		Todos:       filepath.Join(tmpDir, "todos"),
+		FileHistory: filepath.Join(tmpDir, "file-history"),
+		SessionEnv:  filepath.Join(tmpDir, "session-env"),
+	}
+
+	// Directory with nothing but empty subdirectories
+	emptyProject := filepath.Join(paths.Projects, "-leftover")
+	require.NoError(t, os.MkdirAll(filepath.Join(emptyProject, "subdir"), 0755))
+
+	// Directories with a session file, another file, or sessions in a
+	// subdirectory may hold data and are kept
+	projectDir := filepath.Join(paths.Projects, "-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "s.jsonl"), []byte(`{"sessionId":"s","cwd":"/test"}`), 0644))
+	notesProject := filepath.Join(paths.Projects, "-notes")
+	require.NoError(t, os.MkdirAll(notesProject, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(notesProject, "notes.txt"), []byte("abc"), 0644))
+	subagentProject := filepath.Join(paths.Projects, "-subagents")
+	require.NoError(t, os.MkdirAll(filepath.Join(subagentProject, "s2", "subagents"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(subagentProject, "s2", "subagents", "agent.jsonl"), []byte(`{}`), 0644))
+
+	orphans, err := FindOrphans(paths, []string{"s"})
+	require.NoError(t, err)
+
+	require.Len(t, orphans, 1)
+	assert.Equal(t, OrphanTypeEmptyProjectDir, orphans[0].Type)
+	assert.Equal(t, emptyProject, orphans[0].Path)
+	assert.Equal(t, int64(0), orphans[0].SizeSaved)
+
+	preview := BuildOrphanPreview(orphans)
+	assert.Equal(t, "Empty project directory", preview.Changes[0].Description)
+}
+
+func TestFindOrphansWithOptions_Cancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &claude.Paths{
+		Root:        tmpDir,
+		Projects:    filepath.Join(tmpDir, "projects"),
+		Todos:       filepath.Join(tmpDir, "todos"),
+		FileHistory: filepath.Join(tmpDir, "file-history"),
+		SessionEnv:  filepath.Join(tmpDir, "session-env"),
+	}
+	require.NoError(t, os.MkdirAll(paths.Todos, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(paths.Todos, "orphan-agent-a.json"), []byte(`{}`), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	orphans, err := FindOrphansWithOptions(ctx, paths, nil, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, orphans)
+}
+
+func TestDirSize_Cancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "file"), []byte("abc"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := dirSize(ctx, tmpDir)
+	require.ErrorIs(t, err, context.Canceled)
+
+	size, err := dirSize(context.Background(), tmpDir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), size)
+}
+
+func TestDirSize_SymlinkLoop(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "file"), []byte("abc"), 0644))
+	if err := os.Symlink(tmpDir, filepath.Join(tmpDir, "loop")); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+
+	// Symlinks are not followed, so the walk terminates
+	size, err := dirSize(context.Background(), tmpDir)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, size, int64(3))
+}
+
+func TestFindOrphans_UnknownTodos(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &claude.Paths{
+		Root:        tmpDir,
+		Projects:    filepath.Join(tmpDir, "projects"),
+		Todos:       filepath.Join(tmpDir, "todos"),
+		FileHistory: filepath.Join(tmpDir, "file-history"),
+		SessionEnv:  filepath.Join(tmpDir, "session-env"),
+	}
+	require.NoError(t, os.MkdirAll(paths.Todos, 0755))
+
+	customValid := filepath.Join(paths.Todos, "todo_sess1.json")
+	customOrphan := filepath.Join(paths.Todos, "todo_gone.json")
+	unknown := filepath.Join(paths.Todos, "notes.txt")
+	for _, f := range []string{customValid, customOrphan, unknown} {
+		require.NoError(t, os.WriteFile(f, []byte(`{}`), 0644))
+	}
+
+	// Without a pattern, all three are unrecognized
+	orphans, err := FindOrphans(paths, []string{"sess1"})
+	require.NoError(t, err)
+	require.Len(t, orphans, 3)
+	for _, o := range orphans {
+		assert.Equal(t, OrphanTypeUnknownTodo, o.Type)
+	}
+
+	pattern, err := ParseTodoPattern(`^todo_(?P<session>.+)\.json$`)
+	require.NoError(t, err)
+
+	orphans, err = FindOrphansWithOptions(context.Background(), paths, []string{"sess1"}, &OrphanOptions{TodoPattern: pattern})
+	require.NoError(t, err)
+
+	types := make(map[string]OrphanType)
+	for _, o := range orphans {
+		types[o.Path] = o.Type
+	}
+	assert.Equal(t, map[string]OrphanType{
+		customOrphan: OrphanTypeTodo,
+		unknown:      OrphanTypeUnknownTodo,
+	}, types)
+}
+
+func TestParseTodoPattern_RequiresGroup(t *testing.T) {
+	_, err := ParseTodoPattern(`^todo_.+\.json$`)
+	assert.Error(t, err)
+
+	re, err := ParseTodoPattern(`^todo_(.+)\.json$`)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", matchTodoPattern(re, "todo_abc.json"))
+}
+
+func TestFindOrphansWithOptions_FileHistoryDetails(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &claude.Paths{
+		Root:        tmpDir,
+		Projects:    filepath.Join(tmpDir, "projects"),
+		Todos:       filepath.Join(tmpDir, "todos"),
+		FileHistory: filepath.Join(tmpDir, "file-history"),
+		SessionEnv:  filepath.Join(tmpDir, "session-env"),
+	}
+
+	orphanHistory := filepath.Join(paths.FileHistory, "orphan-sess")
+	require.NoError(t, os.MkdirAll(filepath.Join(orphanHistory, "nested"), 0755))
+	for i := range MaxOrphanDetails + 2 {
+		name := fmt.Sprintf("file%d.txt", i)
+		require.NoError(t, os.WriteFile(filepath.Join(orphanHistory, name), bytes.Repeat([]byte("x"), i+1), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(orphanHistory, "nested", "big.bin"), bytes.Repeat([]byte("x"), 100), 0644))
+
+	opts := &OrphanOptions{Types: []OrphanType{OrphanTypeFileHistory}, Details: true}
+	orphans, err := FindOrphansWithOptions(context.Background(), paths, nil, opts)
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+
+	details := orphans[0].Details
+	require.Len(t, details, MaxOrphanDetails)
+	assert.Equal(t, FileDetail{Path: filepath.Join("nested", "big.bin"), Size: 100}, details[0])
+	assert.Equal(t, "file6.txt", details[1].Path)
+
+	preview := BuildOrphanPreview(orphans)
+	assert.Contains(t, preview.Changes[0].Description, "Orphan file history\n")
+	assert.Contains(t, preview.Changes[0].Description, "100 B  "+filepath.Join("nested", "big.bin"))
+
+	// Without Details, no breakdown is collected
+	orphans, err = FindOrphansWithOptions(context.Background(), paths, nil, &OrphanOptions{Types: opts.Types})
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+	assert.Empty(t, orphans[0].Details)
+}
+
+func TestSummarizeOrphans(t *testing.T) {
+	orphans := []OrphanResult{
+		{Type: OrphanTypeTodo, SizeSaved: 10},
+		{Type: OrphanTypeFileHistory, SizeSaved: 500},
+		{Type: OrphanTypeTodo, SizeSaved: 20},
+		{Type: OrphanTypeEmptySession},
+	}
+
+	summary := SummarizeOrphans(orphans)
+
+	assert.Equal(t, []OrphanSummary{
+		{Type: OrphanTypeFileHistory, Count: 1, Size: 500},
+		{Type: OrphanTypeTodo, Count: 2, Size: 30},
+		{Type: OrphanTypeEmptySession, Count: 1, Size: 0},
+	}, summary)
+}
+
+func TestFindOrphansWithOptions_ExcludeEmpty(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &claude.Paths{
+		Root:        tmpDir,
+		Projects:    filepath.Join(tmpDir, "projects"),
+		Todos:       filepath.Join(tmpDir, "todos"),
+		FileHistory: filepath.Join(tmpDir, "file-history"),
+		SessionEnv:  filepath.Join(tmpDir, "session-env"),
+	}
+
+	projectDir := filepath.Join(paths.Projects, "-test-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "empty.jsonl"), []byte{}, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "valid.jsonl"), []byte(`{"sessionId":"sess1","cwd":"/test"}`), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(paths.SessionEnv, "gone"), 0755))
+	require.NoError(t, os.MkdirAll(paths.Todos, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(paths.Todos, "gone-agent-x.json"), []byte(`{}`), 0644))
+
+	orphans, err := FindOrphansWithOptions(context.Background(), paths, []string{"sess1"}, &OrphanOptions{})
+	require.NoError(t, err)
+	assert.Len(t, orphans, 3)
+
+	orphans, err = FindOrphansWithOptions(context.Background(), paths, []string{"sess1"}, &OrphanOptions{ExcludeEmpty: true})
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+	assert.Equal(t, OrphanTypeTodo, orphans[0].Type)
+}
+
+func TestFindOrphansWithOptions_OldLogs(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &claude.Paths{
+		Root:     tmpDir,
+		Projects: filepath.Join(tmpDir, "projects"),
+		Logs:     filepath.Join(tmpDir, "logs"),
+	}
+
+	oldLog := filepath.Join(paths.Logs, "mcp", "old.log")
+	newLog := filepath.Join(paths.Logs, "new.log")
+	require.NoError(t, os.MkdirAll(filepath.Dir(oldLog), 0755))
+	require.NoError(t, os.WriteFile(oldLog, []byte("old entries"), 0644))
+	require.NoError(t, os.WriteFile(newLog, []byte("new"), 0644))
+	cutoff := time.Now().Add(-24 * time.Hour)
+	require.NoError(t, os.Chtimes(oldLog, cutoff.Add(-time.Hour), cutoff.Add(-time.Hour)))
+
+	// Logs are only reported with a cutoff
+	orphans, err := FindOrphansWithOptions(context.Background(), paths, nil, &OrphanOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, orphans)
+
+	orphans, err = FindOrphansWithOptions(context.Background(), paths, nil, &OrphanOptions{LogCutoff: cutoff})
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+	assert.Equal(t, OrphanTypeLog, orphans[0].Type)
+	assert.Equal(t, oldLog, orphans[0].Path)
+	assert.Equal(t, int64(len("old entries")), orphans[0].SizeSaved)
+}
+
+func TestFindOrphansWithOptions_OldLogsSkipsUnreadable(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &claude.Paths{
+		Root:     tmpDir,
+		Projects: filepath.Join(tmpDir, "projects"),
+		Logs:     filepath.Join(tmpDir, "logs"),
+	}
+
+	oldLog := filepath.Join(paths.Logs, "old.log")
+	require.NoError(t, os.MkdirAll(paths.Logs, 0755))
+	require.NoError(t, os.WriteFile(oldLog, []byte("old entries"), 0644))
+	cutoff := time.Now().Add(-24 * time.Hour)
+	require.NoError(t, os.Chtimes(oldLog, cutoff.Add(-time.Hour), cutoff.Add(-time.Hour)))
+
+	locked := filepath.Join(paths.Logs, "locked")
+	require.NoError(t, os.MkdirAll(locked, 0755))
+	require.NoError(t, os.Chmod(locked, 0))
+	defer os.Chmod(locked, 0755)
+	if _, err := os.ReadDir(locked); err == nil {
+		t.Skip("directory permissions are not enforced (e.g. running as root)")
+	}
+
+	orphans, err := FindOrphansWithOptions(context.Background(), paths, nil, &OrphanOptions{LogCutoff: cutoff})
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+	assert.Equal(t, oldLog, orphans[0].Path)
+}
+
+func TestFindOrphansWithOptions_StaleLocks(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &claude.Paths{
+		Root:     tmpDir,
+		Projects: filepath.Join(tmpDir, "projects"),
+	}
+
+	// A process that has exited
+	exited := exec.Command(os.Args[0], "-test.run=^$")
+	require.NoError(t, exited.Run())
+
+	write := func(rel, content string) string {
+		path := filepath.Join(tmpDir, rel)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+	deadLock := write("ide/claude.lock", strconv.Itoa(exited.Process.Pid)+"\n")
+	write("running.pid", strconv.Itoa(os.Getpid()))
+	write("fresh.lock", "")
+	// Without a pid, a lock cannot be verified as stale, however old
+	oldLock := write("statsig/old.lock", "")
+	old := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(oldLock, old, old))
+	write("cccc-trash/batch/trashed.lock", "")
+	write("a/b/c/d/too-deep.lock", "")
+	write("settings.json", strconv.Itoa(exited.Process.Pid))
+
+	// Locks are only reported with IncludeLocks
+	orphans, err := FindOrphansWithOptions(context.Background(), paths, nil, &OrphanOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, orphans)
+
+	orphans, err = FindOrphansWithOptions(context.Background(), paths, nil, &OrphanOptions{IncludeLocks: true})
+	require.NoError(t, err)
+	var found []string
+	for _, o := range orphans {
+		assert.Equal(t, OrphanTypeLock, o.Type)
+		found = append(found, o.Path)
+	}
+	assert.ElementsMatch(t, []string{deadLock}, found)
+}
+
+func TestFindOrphans_Reasons(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &claude.Paths{
+		Root:        tmpDir,
+		Projects:    filepath.Join(tmpDir, "projects"),
+		Todos:       filepath.Join(tmpDir, "todos"),
+		FileHistory: filepath.Join(tmpDir, "file-history"),
+		SessionEnv:  filepath.Join(tmpDir, "session-env"),
+	}
+	projectDir := filepath.Join(paths.Projects, "-test-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	require.NoError(t, os.MkdirAll(paths.Todos, 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(paths.FileHistory, "xyz"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(paths.SessionEnv, "sess1"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "empty.jsonl"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(paths.Todos, "abc123-agent-a.json"), []byte(`{}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(paths.Todos, "notes.json"), []byte(`{}`), 0644))
+
+	older := filepath.Join(paths.Todos, "sess1-agent-b.json")
+	newer := filepath.Join(paths.Todos, "sess1.b.json")
+	require.NoError(t, os.WriteFile(older, []byte(`{}`), 0644))
+	require.NoError(t, os.WriteFile(newer, []byte(`{}`), 0644))
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(older, past, past))
+
+	orphans, err := FindOrphansWithOptions(context.Background(), paths, []string{"sess1"},
+		&OrphanOptions{TodoPattern: regexp.MustCompile(`^(?P<session>\w+)\.(?P<agent>\w+)\.json$`)})
+	require.NoError(t, err)
+
+	reasons := make(map[OrphanType]string)
+	for _, o := range orphans {
+		reasons[o.Type] = o.Reason
+	}
+	assert.Equal(t, map[OrphanType]string{
+		OrphanTypeEmptySession:  "0-byte session file",
+		OrphanTypeTodo:          "todo references session abc123, which is not in any project",
+		OrphanTypeUnknownTodo:   "todo file name matches no known format, so its session cannot be checked",
+		OrphanTypeDuplicateTodo: "session sess1 has a newer todo file of agent b: sess1.b.json",
+		OrphanTypeFileHistory:   "file-history directory of session xyz, which is not in any project",
+		OrphanTypeSessionEnv:    "empty session-env directory",
+	}, reasons)
+}
+
+func TestCleanOrphansWithOptions_FailFast(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	blocker := filepath.Join(tmpDir, "blocker")
+	require.NoError(t, os.WriteFile(blocker, []byte("x"), 0644))
+	failing := filepath.Join(blocker, "child")
+
+	first := filepath.Join(tmpDir, "first.json")
+	last := filepath.Join(tmpDir, "last.json")
+	require.NoError(t, os.WriteFile(first, []byte(`{}`), 0644))
+	require.NoError(t, os.WriteFile(last, []byte(`{}`), 0644))
+
+	orphans := []OrphanResult{
+		{Type: OrphanTypeTodo, Path: first, SizeSaved: 2},
+		{Type: OrphanTypeTodo, Path: failing, SizeSaved: 5},
+		{Type: OrphanTypeTodo, Path: last, SizeSaved: 2},
+	}
+
+	results, err := CleanOrphansWithOptions(orphans, CleanOrphanOptions{FailFast: true})
+	require.Error(t, err)
+
+	// Cleaning stops at the failure
+	require.Len(t, results, 2)
+	assert.NoFileExists(t, first)
+	assert.Error(t, results[1].Err)
+	assert.FileExists(t, last)
+}
+
+func TestFindOrphans_DuplicateTodos(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := &claude.Paths{
+		Root:        tmpDir,
+		Projects:    filepath.Join(tmpDir, "projects"),
+		Todos:       filepath.Join(tmpDir, "todos"),
+		FileHistory: filepath.Join(tmpDir, "file-history"),
+		SessionEnv:  filepath.Join(tmpDir, "session-env"),
+	}
+	require.NoError(t, os.MkdirAll(paths.Todos, 0755))
+
+	// Three copies for sess1 and agent a1, and one for another agent
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	files := []struct {
+		name  string
+		mtime time.Time
+	}{
+		{"todo_sess1_a1_0aa.json", base},
+		{"todo_sess1_a1_1bb.json", base.Add(2 * time.Hour)},
+		{"todo_sess1_a1_2cc.json", base.Add(time.Hour)},
+		{"todo_sess1_a2_3dd.json", base},
+	}
+	for _, f := range files {
+		path := filepath.Join(paths.Todos, f.name)
+		require.NoError(t, os.WriteFile(path, []byte(`{}`), 0644))
+		require.NoError(t, os.Chtimes(path, f.mtime, f.mtime))
+	}
+
+	pattern, err := ParseTodoPattern(`^todo_(?P<session>[^_]+)_(?P<agent>[^_]+)_[0-9a-f]+\.json$`)
+	require.NoError(t, err)
+
+	orphans, err := FindOrphansWithOptions(context.Background(), paths, []string{"sess1"}, &OrphanOptions{TodoPattern: pattern})
+	require.NoError(t, err)
+
+	var duplicates []string
+	for _, o := range orphans {
+		assert.Equal(t, OrphanTypeDuplicateTodo, o.Type)
+		duplicates = append(duplicates, filepath.Base(o.Path))
+	}
+	assert.Equal(t, []string{"todo_sess1_a1_0aa.json", "todo_sess1_a1_2cc.json"}, duplicates)
+
+	preview := BuildOrphanPreview(orphans)
+	require.Len(t, preview.Changes, 2)
+	assert.Contains(t, preview.Changes[0].Description, "Duplicate todo")
+
+	// Without an agent group, files of one session may belong to different
+	// agents and are never reported as duplicates
+	pattern, err = ParseTodoPattern(`^todo_(?P<session>[^_]+)_[^_]+_[0-9a-f]+\.json$`)
+	require.NoError(t, err)
+
+	orphans, err = FindOrphansWithOptions(context.Background(), paths, []string{"sess1"}, &OrphanOptions{TodoPattern: pattern})
+	require.NoError(t, err)
+	assert.Empty(t, orphans)
+}
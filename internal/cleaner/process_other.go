@@ -0,0 +1,16 @@
+//go:build !unix
+
+package cleaner
+
+import "os"
+
+// processAlive reports whether a process with the given pid is running.
+// On Windows, finding a process fails if it does not exist.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package cleaner
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive reports whether a process with the given pid is running.
+// Processes of other users count as running.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
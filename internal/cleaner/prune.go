@@ -0,0 +1,154 @@
+package cleaner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+	"github.com/mkoepf/claude-code-config-cleaner/internal/ui"
+)
+
+// OldSession represents a session file selected for pruning by age.
+type OldSession struct {
+	Path        string    // Session file path
+	ProjectPath string    // ActualPath of the project the session belongs to
+	Timestamp   time.Time // When the session started, or ModTime if no line records a timestamp
+	ModTime     time.Time // Modification time of the session file
+	Size        int64
+	Err         error // Set if removing the file failed
+}
+
+// SessionAge returns the time the age of a session is measured from.
+type SessionAge func(OldSession) time.Time
+
+// SessionStarted measures the age of a session from when it started.
+func SessionStarted(s OldSession) time.Time { return s.Timestamp }
+
+// SessionModified measures the age of a session from when its file was
+// last modified.
+func SessionModified(s OldSession) time.Time { return s.ModTime }
+
+// FindOldSessions returns the session files of the given projects whose age
+// time is before cutoff. The keepLatest most recent sessions of each project,
+// by the same age time, are always kept, so a session must both be old and
+// beyond the keep window to be returned. A zero cutoff returns all sessions
+// beyond the keep window. Empty session files are left to orphan cleanup.
+func FindOldSessions(projectsDir string, projects []claude.Project, cutoff time.Time, keepLatest int, age SessionAge) ([]OldSession, error) {
+	var old []OldSession
+	for _, p := range projects {
+		sessions, err := projectSessions(filepath.Join(projectsDir, p.EncodedName), p.ActualPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		// Newest first, so the first keepLatest sessions are the ones to keep
+		sort.SliceStable(sessions, func(i, j int) bool {
+			return age(sessions[i]).After(age(sessions[j]))
+		})
+
+		for i, s := range sessions {
+			if i < keepLatest {
+				continue
+			}
+			if cutoff.IsZero() || age(s).Before(cutoff) {
+				old = append(old, s)
+			}
+		}
+	}
+
+	return old, nil
+}
+
+// projectSessions parses the non-empty session files in a project directory.
+// Files that cannot be parsed are skipped. Sessions without a timestamp get
+// the file's modification time, so they are not treated as the oldest.
+func projectSessions(projectDir, projectPath string) ([]OldSession, error) {
+	entries, err := os.ReadDir(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []OldSession
+	for _, entry := range entries {
+		if entry.IsDir() || !claude.IsSessionFile(entry.Name()) {
+			continue
+		}
+
+		info, err := claude.ParseSessionFile(filepath.Join(projectDir, entry.Name()))
+		if err != nil || info.IsEmpty {
+			continue
+		}
+
+		started := info.Timestamp
+		if started.IsZero() {
+			started = info.ModTime
+		}
+		sessions = append(sessions, OldSession{
+			Path:        info.FilePath,
+			ProjectPath: projectPath,
+			Timestamp:   started,
+			ModTime:     info.ModTime,
+			Size:        info.Size,
+		})
+	}
+
+	return sessions, nil
+}
+
+// CleanOldSessions removes the given session files, continuing past
+// individual failures. It calls progress (if not nil) with the 1-based index
+// of each session before it is removed. If dryRun is true, nothing is removed.
+func CleanOldSessions(sessions []OldSession, dryRun bool, progress func(n int, s OldSession)) ([]OldSession, error) {
+	results := make([]OldSession, len(sessions))
+	copy(results, sessions)
+
+	if dryRun {
+		return results, nil
+	}
+
+	var errs []error
+	for i := range results {
+		if progress != nil {
+			progress(i+1, results[i])
+		}
+		if err := os.Remove(results[i].Path); err != nil {
+			results[i].Size = 0
+			if os.IsNotExist(err) {
+				continue
+			}
+			results[i].Err = err
+			errs = append(errs, fmt.Errorf("%s: %w", results[i].Path, err))
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// BuildOldSessionPreview creates a preview of session files to be pruned.
+func BuildOldSessionPreview(sessions []OldSession) *ui.Preview {
+	preview := &ui.Preview{
+		Title: "Old Session Pruning",
+	}
+
+	for _, s := range sessions {
+		project := s.ProjectPath
+		if project == "" {
+			project = "unknown project"
+		}
+		preview.Changes = append(preview.Changes, ui.Change{
+			Action:      ui.ActionDelete,
+			Path:        s.Path,
+			Description: fmt.Sprintf("Session of %s, started %s", project, s.Timestamp.Format("2006-01-02")),
+			Size:        s.Size,
+		})
+	}
+
+	return preview
+}
@@ -0,0 +1,140 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSession writes a session file that started at the given time.
+func writeSession(t *testing.T, dir, id string, started time.Time) string {
+	t.Helper()
+	path := filepath.Join(dir, id+".jsonl")
+	data := `{"sessionId":"` + id + `","cwd":"/project","timestamp":"` + started.UTC().Format(time.RFC3339) + `"}`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+	return path
+}
+
+func TestFindOldSessions_KeepLatest(t *testing.T) {
+	projectsDir := t.TempDir()
+	projectDir := filepath.Join(projectsDir, "-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	oldest := writeSession(t, projectDir, "oldest", now.AddDate(0, 0, -300))
+	older := writeSession(t, projectDir, "older", now.AddDate(0, 0, -200))
+	writeSession(t, projectDir, "old", now.AddDate(0, 0, -100))
+	writeSession(t, projectDir, "recent", now.AddDate(0, 0, -1))
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "empty.jsonl"), nil, 0644))
+
+	projects := []claude.Project{{EncodedName: "-project", ActualPath: "/project"}}
+	cutoff := now.AddDate(0, 0, -90)
+
+	sessions, err := FindOldSessions(projectsDir, projects, cutoff, 0, SessionStarted)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 3)
+
+	// "recent" and "old" fill the keep window; only older ones are pruned
+	sessions, err = FindOldSessions(projectsDir, projects, cutoff, 2, SessionStarted)
+	require.NoError(t, err)
+	var got []string
+	for _, s := range sessions {
+		got = append(got, s.Path)
+		assert.Equal(t, "/project", s.ProjectPath)
+	}
+	assert.ElementsMatch(t, []string{oldest, older}, got)
+
+	sessions, err = FindOldSessions(projectsDir, projects, cutoff, 10, SessionStarted)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestFindOldSessions_NoTimestampUsesModTime(t *testing.T) {
+	projectsDir := t.TempDir()
+	projectDir := filepath.Join(projectsDir, "-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+
+	recent := filepath.Join(projectDir, "recent.jsonl")
+	require.NoError(t, os.WriteFile(recent, []byte(`{"sessionId":"recent","cwd":"/project"}`), 0644))
+	stale := filepath.Join(projectDir, "stale.jsonl")
+	require.NoError(t, os.WriteFile(stale, []byte(`{"sessionId":"stale","cwd":"/project"}`), 0644))
+	longAgo := time.Now().AddDate(0, 0, -200)
+	require.NoError(t, os.Chtimes(stale, longAgo, longAgo))
+
+	projects := []claude.Project{{EncodedName: "-project", ActualPath: "/project"}}
+	sessions, err := FindOldSessions(projectsDir, projects, time.Now().AddDate(0, 0, -90), 0, SessionStarted)
+	require.NoError(t, err)
+
+	require.Len(t, sessions, 1)
+	assert.Equal(t, stale, sessions[0].Path)
+	assert.WithinDuration(t, longAgo, sessions[0].Timestamp, time.Second)
+}
+
+func TestFindOldSessions_KeepLatestByModTime(t *testing.T) {
+	projectsDir := t.TempDir()
+	projectDir := filepath.Join(projectsDir, "-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+
+	// "resumed" started long ago but was written to recently, "abandoned"
+	// started later but has not been touched since
+	now := time.Now()
+	resumed := writeSession(t, projectDir, "resumed", now.AddDate(0, 0, -300))
+	abandoned := writeSession(t, projectDir, "abandoned", now.AddDate(0, 0, -200))
+	longAgo := now.AddDate(0, 0, -150)
+	require.NoError(t, os.Chtimes(abandoned, longAgo, longAgo))
+
+	projects := []claude.Project{{EncodedName: "-project", ActualPath: "/project"}}
+	sessions, err := FindOldSessions(projectsDir, projects, time.Time{}, 1, SessionModified)
+	require.NoError(t, err)
+
+	require.Len(t, sessions, 1)
+	assert.Equal(t, abandoned, sessions[0].Path)
+
+	sessions, err = FindOldSessions(projectsDir, projects, time.Time{}, 1, SessionStarted)
+	require.NoError(t, err)
+
+	require.Len(t, sessions, 1)
+	assert.Equal(t, resumed, sessions[0].Path)
+}
+
+func TestFindOldSessions_MissingProjectDir(t *testing.T) {
+	projects := []claude.Project{{EncodedName: "-gone"}}
+
+	sessions, err := FindOldSessions(t.TempDir(), projects, time.Now(), 0, SessionStarted)
+
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestCleanOldSessions(t *testing.T) {
+	dir := t.TempDir()
+	path := writeSession(t, dir, "s", time.Now())
+	sessions := []OldSession{{Path: path, Size: 10}}
+
+	results, err := CleanOldSessions(sessions, true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), results[0].Size)
+	assert.FileExists(t, path)
+
+	var steps int
+	results, err = CleanOldSessions(sessions, false, func(n int, s OldSession) { steps = n })
+	require.NoError(t, err)
+	assert.Equal(t, 1, steps)
+	assert.Equal(t, int64(10), results[0].Size)
+	assert.NoFileExists(t, path)
+}
+
+func TestBuildOldSessionPreview(t *testing.T) {
+	started := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	preview := BuildOldSessionPreview([]OldSession{{Path: "/p/s.jsonl", ProjectPath: "/code/app", Timestamp: started, Size: 5}})
+
+	require.Len(t, preview.Changes, 1)
+	assert.Equal(t, "/p/s.jsonl", preview.Changes[0].Path)
+	assert.Contains(t, preview.Changes[0].Description, "/code/app")
+	assert.Contains(t, preview.Changes[0].Description, "2024-03-01")
+}
@@ -1,9 +1,12 @@
 package cleaner
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/mkoepf/claude-code-config-cleaner/internal/claude"
 	"github.com/mkoepf/claude-code-config-cleaner/internal/ui"
@@ -14,22 +17,155 @@ type StaleResult struct {
 	Project      claude.Project
 	SizeSaved    int64
 	FilesRemoved int
+	TrashedTo    string // Trash location if the project was moved instead of deleted
+	ArchivedTo   string // Archive location of the newest session, see StaleOptions.ArchiveDir
 }
 
-// FindStaleProjects returns projects whose ActualPath no longer exists on disk.
+// ErrProjectActive is returned by CleanStaleProjectWithOptions if the newest
+// session of a project records a cwd that exists by the time the project is
+// removed, e.g. because Claude Code was started in a recreated directory
+// after the scan.
+var ErrProjectActive = errors.New("project became active since the scan")
+
+// StaleOptions configures CleanStaleProjectWithOptions.
+type StaleOptions struct {
+	// TrashDir is the trash batch directory the project directory is moved
+	// into instead of deleting it. Empty deletes the project directory.
+	TrashDir string
+	// ArchiveDir, if set, keeps the newest session file of the project as a
+	// record of the last work done: it is moved to
+	// ArchiveDir/<encoded name>/ before the rest of the project is removed.
+	ArchiveDir string
+	// AllowActive removes the project even if its cwd exists, for projects
+	// that were selected on purpose although their cwd exists, e.g. with
+	// --assume-missing.
+	AllowActive bool
+}
+
+// DefaultArchiveRoot returns the location for sessions kept from cleaned
+// projects of a Claude home directory.
+func DefaultArchiveRoot(claudeHome string) string {
+	return filepath.Join(claudeHome, "cccc-archive")
+}
+
+// FindStaleProjects returns projects whose ActualPath no longer exists on
+// disk or is unknown. Projects on currently unavailable filesystems are not
+// considered stale.
 func FindStaleProjects(projects []claude.Project) []claude.Project {
-	var stale []claude.Project
+	return findProjects(projects, claude.ProjectStatus.IsStale)
+}
+
+// FindUnavailableProjects returns projects whose ActualPath is on a
+// filesystem that is not currently reachable.
+func FindUnavailableProjects(projects []claude.Project) []claude.Project {
+	return findProjects(projects, func(s claude.ProjectStatus) bool { return s == claude.StatusUnavailable })
+}
+
+// FindAssumedMissingProjects returns the projects whose ActualPath is one of
+// the given paths, whether or not it exists on disk. It lets users force
+// projects stale that automatic detection cannot see, e.g. leftovers under a
+// mount path that has been reused by another drive.
+func FindAssumedMissingProjects(projects []claude.Project, missing []string) []claude.Project {
+	missingSet := make(map[string]struct{}, len(missing))
+	for _, m := range missing {
+		missingSet[filepath.Clean(m)] = struct{}{}
+	}
+
+	var matched []claude.Project
+	for _, p := range projects {
+		if p.ActualPath == "" {
+			continue
+		}
+		if _, ok := missingSet[filepath.Clean(p.ActualPath)]; ok {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
+// FindUnmarkedProjects returns the existing projects whose directory contains
+// none of the marker files or directories (e.g. ".git"). Such a path may have
+// been reused for something unrelated after the original project was deleted.
+func FindUnmarkedProjects(projects []claude.Project, markers []string) []claude.Project {
+	var unmarked []claude.Project
+	for _, p := range projects {
+		if p.Status() == claude.StatusActive && !HasMarker(p.ActualPath, markers) {
+			unmarked = append(unmarked, p)
+		}
+	}
+	return unmarked
+}
+
+// FindProjectsInside returns the projects whose ActualPath is dir or a
+// directory below it, e.g. sessions started from within the Claude home.
+func FindProjectsInside(projects []claude.Project, dir string) []claude.Project {
+	var inside []claude.Project
+	for _, p := range projects {
+		if p.ActualPath != "" && IsInside(p.ActualPath, dir) {
+			inside = append(inside, p)
+		}
+	}
+	return inside
+}
+
+// IsInside reports whether path is dir or below it. Both are compared as
+// cleaned paths, without resolving symlinks.
+func IsInside(path, dir string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// HasMarker reports whether dir contains any of the markers.
+func HasMarker(dir string, markers []string) bool {
+	for _, m := range markers {
+		if _, err := os.Stat(filepath.Join(dir, m)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// findProjects returns the projects whose status matches.
+func findProjects(projects []claude.Project, match func(claude.ProjectStatus) bool) []claude.Project {
+	var matched []claude.Project
 	for _, p := range projects {
-		if !p.Exists() {
-			stale = append(stale, p)
+		if match(p.Status()) {
+			matched = append(matched, p)
 		}
 	}
-	return stale
+	return matched
+}
+
+// SessionIDsExcludingStale returns the session IDs of all projects except
+// the given stale ones. A session ID shared with a kept project stays valid.
+func SessionIDsExcludingStale(projects, stale []claude.Project) []string {
+	staleSet := make(map[string]struct{}, len(stale))
+	for _, p := range stale {
+		staleSet[p.EncodedName] = struct{}{}
+	}
+
+	var ids []string
+	for _, p := range projects {
+		if _, isStale := staleSet[p.EncodedName]; isStale {
+			continue
+		}
+		ids = append(ids, p.SessionIDs...)
+	}
+	return ids
 }
 
 // CleanStaleProject removes the session data directory for a stale project.
 // If dryRun is true, it returns what would be deleted without making changes.
 func CleanStaleProject(projectsDir string, project claude.Project, dryRun bool) (*StaleResult, error) {
+	return CleanStaleProjectWithOptions(projectsDir, project, dryRun, StaleOptions{})
+}
+
+// CleanStaleProjectWithOptions is like CleanStaleProject but configured by
+// opts.
+func CleanStaleProjectWithOptions(projectsDir string, project claude.Project, dryRun bool, opts StaleOptions) (*StaleResult, error) {
 	result := &StaleResult{
 		Project:      project,
 		SizeSaved:    project.TotalSize,
@@ -45,10 +181,51 @@ func CleanStaleProject(projectsDir string, project claude.Project, dryRun bool)
 		return result, nil
 	}
 
+	var newest string
+	if opts.ArchiveDir != "" {
+		var size int64
+		var err error
+		newest, size, err = newestSession(projectPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find newest session of %s: %w", projectPath, err)
+		}
+		if newest != "" {
+			result.SizeSaved = max(result.SizeSaved-size, 0)
+			result.FilesRemoved = max(result.FilesRemoved-1, 0)
+		}
+	}
+
 	if dryRun {
 		return result, nil
 	}
 
+	// Close the gap between scan and removal: a session may have been
+	// written into the directory in the meantime
+	if !opts.AllowActive {
+		if cwd, err := claude.ExtractCWD(projectPath); err == nil {
+			if _, err := os.Stat(cwd); err == nil {
+				return nil, fmt.Errorf("%w: %s exists", ErrProjectActive, cwd)
+			}
+		}
+	}
+
+	if newest != "" {
+		dest, err := TrashPath(newest, filepath.Join(opts.ArchiveDir, project.EncodedName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to archive session %s: %w", newest, err)
+		}
+		result.ArchivedTo = dest
+	}
+
+	if opts.TrashDir != "" {
+		dest, err := TrashPath(projectPath, opts.TrashDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to move project directory %s to trash: %w", projectPath, err)
+		}
+		result.TrashedTo = dest
+		return result, nil
+	}
+
 	// Actually delete the directory
 	if err := os.RemoveAll(projectPath); err != nil {
 		return nil, fmt.Errorf("failed to remove project directory %s: %w", projectPath, err)
@@ -57,6 +234,38 @@ func CleanStaleProject(projectsDir string, project claude.Project, dryRun bool)
 	return result, nil
 }
 
+// newestSession returns the path and size of the session file in projectPath
+// with the latest timestamp, or "" if there is none. Sessions without a
+// timestamp are compared by modification time.
+func newestSession(projectPath string) (string, int64, error) {
+	entries, err := os.ReadDir(projectPath)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var newest string
+	var newestSize int64
+	var newestTime time.Time
+	for _, entry := range entries {
+		if entry.IsDir() || !claude.IsSessionFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(projectPath, entry.Name())
+		info, err := claude.ParseSessionFile(path)
+		if err != nil {
+			continue
+		}
+		t := info.Timestamp
+		if t.IsZero() {
+			t = info.ModTime
+		}
+		if newest == "" || t.After(newestTime) {
+			newest, newestSize, newestTime = path, info.Size, t
+		}
+	}
+	return newest, newestSize, nil
+}
+
 // BuildStalePreview creates a preview of stale projects to be cleaned.
 func BuildStalePreview(staleProjects, keptProjects []claude.Project) *ui.Preview {
 	preview := &ui.Preview{
@@ -65,9 +274,15 @@ func BuildStalePreview(staleProjects, keptProjects []claude.Project) *ui.Preview
 
 	for _, p := range staleProjects {
 		description := fmt.Sprintf("%d files, last used: %s", p.FileCount, p.LastUsed.Format("2006-01-02"))
-		if p.ActualPath == "" {
+		switch {
+		case p.HasOnlyEmptySessions():
+			description = fmt.Sprintf("%d files (only empty sessions, nothing of value is lost)", p.FileCount)
+		case p.ActualPath == "":
 			description = fmt.Sprintf("%d files (no cwd found)", p.FileCount)
 		}
+		if p.GitBranch != "" {
+			description += fmt.Sprintf(", git branch: %s", p.GitBranch)
+		}
 
 		preview.Changes = append(preview.Changes, ui.Change{
 			Action:      ui.ActionDelete,
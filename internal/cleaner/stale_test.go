@@ -62,6 +62,21 @@ func TestFindStaleProjects_EmptyActualPath(t *testing.T) {
 	assert.Len(t, stale, 1)
 }
 
+func TestFindStaleProjects_ExcludesUnavailable(t *testing.T) {
+	projects := []claude.Project{
+		{EncodedName: "deleted", ActualPath: "/nonexistent/deleted"},
+		{EncodedName: "unmounted", ActualPath: "/Volumes/ccc-test-unmounted-volume/project"},
+	}
+
+	stale := FindStaleProjects(projects)
+	require.Len(t, stale, 1)
+	assert.Equal(t, "deleted", stale[0].EncodedName)
+
+	unavailable := FindUnavailableProjects(projects)
+	require.Len(t, unavailable, 1)
+	assert.Equal(t, "unmounted", unavailable[0].EncodedName)
+}
+
 func TestCleanStaleProject_DryRun(t *testing.T) {
 	tmpDir := t.TempDir()
 	projectsDir := filepath.Join(tmpDir, "projects")
@@ -117,6 +132,67 @@ func TestCleanStaleProject_ActualDelete(t *testing.T) {
 	assert.Equal(t, 2, result.FilesRemoved)
 }
 
+func TestCleanStaleProject_KeepNewestSession(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectsDir := filepath.Join(tmpDir, "projects")
+	projectDir := filepath.Join(projectsDir, "-test-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+
+	older := `{"cwd":"/nonexistent","timestamp":"2025-03-01T00:00:00Z"}`
+	newer := `{"cwd":"/nonexistent","timestamp":"2025-04-01T00:00:00Z"}`
+	// The newer session sorts first, so it is picked by timestamp, not by name
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(newer), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "b.jsonl"), []byte(older), 0644))
+
+	project := claude.Project{
+		EncodedName: "-test-project",
+		ActualPath:  "/nonexistent",
+		TotalSize:   int64(len(older) + len(newer)),
+		FileCount:   2,
+	}
+	archiveDir := filepath.Join(tmpDir, "archive")
+
+	result, err := CleanStaleProjectWithOptions(projectsDir, project, false, StaleOptions{ArchiveDir: archiveDir})
+	require.NoError(t, err)
+
+	archived := filepath.Join(archiveDir, "-test-project", "a.jsonl")
+	assert.NoDirExists(t, projectDir)
+	assert.Equal(t, archived, result.ArchivedTo)
+	content, err := os.ReadFile(archived)
+	require.NoError(t, err)
+	assert.Equal(t, newer, string(content))
+	assert.Equal(t, int64(len(older)), result.SizeSaved)
+	assert.Equal(t, 1, result.FilesRemoved)
+}
+
+func TestCleanStaleProject_BecameActive(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectsDir := filepath.Join(tmpDir, "projects")
+	projectDir := filepath.Join(projectsDir, "-test-project")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "old.jsonl"), []byte(`{"cwd":"/nonexistent"}`), 0644))
+
+	// Scanned while the cwd was missing
+	project := claude.Project{EncodedName: "-test-project", ActualPath: "/nonexistent", FileCount: 1}
+
+	// Claude Code is started in a recreated directory before the removal
+	recreated := filepath.Join(tmpDir, "recreated")
+	require.NoError(t, os.MkdirAll(recreated, 0755))
+	session := filepath.Join(projectDir, "new.jsonl")
+	require.NoError(t, os.WriteFile(session, []byte(`{"cwd":"`+filepath.ToSlash(recreated)+`"}`), 0644))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(session, future, future))
+
+	_, err := CleanStaleProjectWithOptions(projectsDir, project, false, StaleOptions{})
+	require.ErrorIs(t, err, ErrProjectActive)
+	assert.DirExists(t, projectDir)
+
+	// Projects selected on purpose are removed anyway
+	_, err = CleanStaleProjectWithOptions(projectsDir, project, false, StaleOptions{AllowActive: true})
+	require.NoError(t, err)
+	assert.NoDirExists(t, projectDir)
+}
+
 func TestCleanStaleProject_NonexistentProject(t *testing.T) {
 	tmpDir := t.TempDir()
 	projectsDir := filepath.Join(tmpDir, "projects")
@@ -182,3 +258,84 @@ func TestBuildStalePreview_NoStale(t *testing.T) {
 	assert.Len(t, preview.Changes, 0)
 	assert.Len(t, preview.Kept, 0)
 }
+
+func TestSessionIDsExcludingStale(t *testing.T) {
+	projects := []claude.Project{
+		{EncodedName: "kept", SessionIDs: []string{"a", "shared"}},
+		{EncodedName: "stale", SessionIDs: []string{"b", "shared"}},
+	}
+
+	ids := SessionIDsExcludingStale(projects, projects[1:])
+	assert.ElementsMatch(t, []string{"a", "shared"}, ids)
+
+	ids = SessionIDsExcludingStale(projects, nil)
+	assert.ElementsMatch(t, []string{"a", "shared", "b", "shared"}, ids)
+}
+
+func TestFindAssumedMissingProjects(t *testing.T) {
+	existing := t.TempDir()
+	projects := []claude.Project{
+		{EncodedName: "a", ActualPath: existing},
+		{EncodedName: "b", ActualPath: "/other"},
+		{EncodedName: "c"},
+	}
+
+	assumed := FindAssumedMissingProjects(projects, []string{existing + string(filepath.Separator)})
+
+	require.Len(t, assumed, 1)
+	assert.Equal(t, "a", assumed[0].EncodedName)
+	assert.Empty(t, FindAssumedMissingProjects(projects, nil))
+}
+
+func TestFindUnmarkedProjects(t *testing.T) {
+	marked := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(marked, ".git"), 0755))
+	reused := t.TempDir()
+
+	projects := []claude.Project{
+		{EncodedName: "marked", ActualPath: marked},
+		{EncodedName: "reused", ActualPath: reused},
+		{EncodedName: "deleted", ActualPath: filepath.Join(reused, "deleted")},
+	}
+
+	unmarked := FindUnmarkedProjects(projects, []string{".git", "go.mod"})
+
+	require.Len(t, unmarked, 1)
+	assert.Equal(t, "reused", unmarked[0].EncodedName)
+}
+
+func TestFindProjectsInside(t *testing.T) {
+	home := filepath.Join(string(filepath.Separator), "home", "me", ".claude")
+	projects := []claude.Project{
+		{EncodedName: "home", ActualPath: home},
+		{EncodedName: "nested", ActualPath: filepath.Join(home, "plugins", "x")},
+		{EncodedName: "sibling", ActualPath: home + "-backup"},
+		{EncodedName: "outside", ActualPath: filepath.Join(string(filepath.Separator), "home", "me", "code")},
+		{EncodedName: "unknown"},
+	}
+
+	inside := FindProjectsInside(projects, home)
+
+	require.Len(t, inside, 2)
+	assert.Equal(t, "home", inside[0].EncodedName)
+	assert.Equal(t, "nested", inside[1].EncodedName)
+}
+
+func TestBuildStalePreview_OnlyEmptySessions(t *testing.T) {
+	preview := BuildStalePreview([]claude.Project{{EncodedName: "-empty", FileCount: 2}}, nil)
+
+	require.Len(t, preview.Changes, 1)
+	assert.Equal(t, "2 files (only empty sessions, nothing of value is lost)", preview.Changes[0].Description)
+}
+
+func TestBuildStalePreview_GitBranch(t *testing.T) {
+	lastUsed := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	preview := BuildStalePreview([]claude.Project{
+		{EncodedName: "-gone", ActualPath: "/gone", FileCount: 3, TotalSize: 100, LastUsed: lastUsed, GitBranch: "feature/login"},
+		{EncodedName: "-other", ActualPath: "/other", FileCount: 1, TotalSize: 100, LastUsed: lastUsed},
+	}, nil)
+
+	require.Len(t, preview.Changes, 2)
+	assert.Equal(t, "3 files, last used: 2025-03-01, git branch: feature/login", preview.Changes[0].Description)
+	assert.Equal(t, "1 files, last used: 2025-03-01", preview.Changes[1].Description)
+}
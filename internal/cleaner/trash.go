@@ -0,0 +1,134 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mkoepf/claude-code-config-cleaner/internal/ui"
+)
+
+// trashBatchLayout names the per-run batch directories inside the trash.
+const trashBatchLayout = "20060102-150405"
+
+// DefaultTrashRoot returns the trash location for a Claude home directory.
+func DefaultTrashRoot(claudeHome string) string {
+	return filepath.Join(claudeHome, "cccc-trash")
+}
+
+// TrashBatchDir returns the batch directory inside trashRoot for a cleanup
+// started at t. Each run moves its items into its own batch.
+func TrashBatchDir(trashRoot string, t time.Time) string {
+	return filepath.Join(trashRoot, t.UTC().Format(trashBatchLayout))
+}
+
+// TrashPath moves src into the trash batch directory trashDir, creating it if
+// needed, and returns the new location. Name clashes within the batch are
+// resolved with a numeric suffix.
+func TrashPath(src, trashDir string) (string, error) {
+	if _, err := os.Lstat(src); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(trashDir, 0700); err != nil {
+		return "", err
+	}
+
+	base := filepath.Base(src)
+	dest := filepath.Join(trashDir, base)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(dest); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		dest = filepath.Join(trashDir, fmt.Sprintf("%s-%d", base, i))
+	}
+
+	if err := os.Rename(src, dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+// TrashBatch is a batch directory in the trash.
+type TrashBatch struct {
+	Path string
+	Size int64
+	Time time.Time // When the batch was trashed
+}
+
+// FindTrashBatches returns the batch directories in trashRoot, oldest first.
+// The time of a batch is taken from its name, or from its modification time
+// if the name is not a batch timestamp. A missing trash has no batches.
+func FindTrashBatches(trashRoot string) ([]TrashBatch, error) {
+	entries, err := os.ReadDir(trashRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var batches []TrashBatch
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(trashRoot, entry.Name())
+		size, err := dirSize(context.Background(), path)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, TrashBatch{Path: path, Size: size, Time: batchTime(entry)})
+	}
+
+	sort.SliceStable(batches, func(i, j int) bool { return batches[i].Time.Before(batches[j].Time) })
+	return batches, nil
+}
+
+// batchTime returns the time a trash batch directory was created.
+func batchTime(entry os.DirEntry) time.Time {
+	if t, err := time.Parse(trashBatchLayout, entry.Name()); err == nil {
+		return t
+	}
+	info, err := entry.Info()
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// EmptyTrash permanently removes the given batches, continuing past
+// individual failures. It returns the batches that were removed.
+func EmptyTrash(batches []TrashBatch) ([]TrashBatch, error) {
+	var removed []TrashBatch
+	var errs []error
+	for _, b := range batches {
+		if err := os.RemoveAll(b.Path); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", b.Path, err))
+			continue
+		}
+		removed = append(removed, b)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// BuildTrashPreview creates a preview of trash batches to be removed.
+func BuildTrashPreview(batches []TrashBatch) *ui.Preview {
+	preview := &ui.Preview{
+		Title: "Empty Trash",
+	}
+
+	for _, b := range batches {
+		preview.Changes = append(preview.Changes, ui.Change{
+			Action:      ui.ActionDelete,
+			Path:        b.Path,
+			Description: "Trashed " + b.Time.Local().Format("2006-01-02 15:04:05"),
+			Size:        b.Size,
+		})
+	}
+
+	return preview
+}
@@ -0,0 +1,87 @@
+package cleaner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashPath_ResolvesNameClash(t *testing.T) {
+	tmpDir := t.TempDir()
+	trashDir := TrashBatchDir(DefaultTrashRoot(tmpDir), time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	assert.Equal(t, filepath.Join(tmpDir, "cccc-trash", "20250102-030405"), trashDir)
+
+	first := filepath.Join(tmpDir, "a", "item")
+	second := filepath.Join(tmpDir, "b", "item")
+	require.NoError(t, os.MkdirAll(first, 0755))
+	require.NoError(t, os.MkdirAll(filepath.Dir(second), 0755))
+	require.NoError(t, os.WriteFile(second, []byte("data"), 0644))
+
+	dest1, err := TrashPath(first, trashDir)
+	require.NoError(t, err)
+	dest2, err := TrashPath(second, trashDir)
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(trashDir, "item"), dest1)
+	assert.Equal(t, filepath.Join(trashDir, "item-1"), dest2)
+	assert.NoDirExists(t, first)
+	assert.NoFileExists(t, second)
+	assert.DirExists(t, dest1)
+	assert.FileExists(t, dest2)
+}
+
+func TestFindTrashBatches_AndEmptyTrash(t *testing.T) {
+	trashRoot := filepath.Join(t.TempDir(), "cccc-trash")
+
+	batches, err := FindTrashBatches(trashRoot)
+	require.NoError(t, err)
+	assert.Empty(t, batches)
+
+	batch := filepath.Join(trashRoot, "20250102-030405")
+	require.NoError(t, os.MkdirAll(batch, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(batch, "file"), []byte("12345"), 0644))
+
+	batches, err = FindTrashBatches(trashRoot)
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	assert.Equal(t, batch, batches[0].Path)
+	assert.Equal(t, int64(5), batches[0].Size)
+
+	removed, err := EmptyTrash(batches)
+	require.NoError(t, err)
+	assert.Equal(t, batches, removed)
+	assert.NoDirExists(t, batch)
+}
+
+func TestEmptyTrash_ContinuesPastFailures(t *testing.T) {
+	trashRoot := t.TempDir()
+	good := filepath.Join(trashRoot, "20250102-030405")
+	require.NoError(t, os.MkdirAll(good, 0755))
+
+	// RemoveAll rejects paths ending in "."
+	batches := []TrashBatch{
+		{Path: filepath.Join(trashRoot, "20250101-000000") + string(filepath.Separator) + "."},
+		{Path: good, Size: 5},
+	}
+
+	removed, err := EmptyTrash(batches)
+	assert.ErrorContains(t, err, "20250101-000000")
+	assert.Equal(t, batches[1:], removed)
+	assert.NoDirExists(t, good)
+}
+
+func TestFindTrashBatches_TimeFromName(t *testing.T) {
+	trashRoot := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(trashRoot, "20250301-000000"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(trashRoot, "20250101-120000"), 0755))
+
+	batches, err := FindTrashBatches(trashRoot)
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+	assert.Equal(t, time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), batches[0].Time)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), batches[1].Time)
+}
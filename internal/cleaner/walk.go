@@ -0,0 +1,33 @@
+package cleaner
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// walkBounded walks the file tree at root like filepath.WalkDir, but only
+// enters directories at most maxDepth levels below root (its subdirectories
+// are level 1) and none of the directories named in skip. Errors are passed
+// to fn as by filepath.WalkDir.
+func walkBounded(root string, maxDepth int, skip []string, fn fs.WalkDirFunc) error {
+	root = filepath.Clean(root)
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() && path != root &&
+			(slices.Contains(skip, d.Name()) || dirDepth(root, path) > maxDepth) {
+			return filepath.SkipDir
+		}
+		return fn(path, d, err)
+	})
+}
+
+// dirDepth returns the number of levels path is below root, which must
+// contain it.
+func dirDepth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
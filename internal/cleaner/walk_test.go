@@ -0,0 +1,38 @@
+package cleaner
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalkBounded(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a/b/c", "skipped/d"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), 0755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "b", "file"), nil, 0644))
+
+	var visited []string
+	err := walkBounded(root, 2, []string{"skipped"}, func(path string, _ fs.DirEntry, err error) error {
+		require.NoError(t, err)
+		rel, _ := filepath.Rel(root, path)
+		visited = append(visited, filepath.ToSlash(rel))
+		return nil
+	})
+
+	require.NoError(t, err)
+	// a/b is entered, as it is 2 levels below root, but a/b/c is not
+	assert.Equal(t, []string{".", "a", "a/b", "a/b/file"}, visited)
+}
+
+func TestDirDepth(t *testing.T) {
+	root := string(filepath.Separator)
+	assert.Equal(t, 0, dirDepth(root, root))
+	assert.Equal(t, 1, dirDepth(root, filepath.Join(root, "a")))
+	assert.Equal(t, 2, dirDepth(filepath.Join(root, "a"), filepath.Join(root, "a", "b", "c")))
+}
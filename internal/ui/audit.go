@@ -1,22 +1,55 @@
 package ui
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// AuditFormat selects how audit entries are written.
+type AuditFormat string
+
+const (
+	// AuditFormatText writes human-readable lines (the default).
+	AuditFormatText AuditFormat = "text"
+	// AuditFormatJSONL writes one JSON object per line.
+	AuditFormatJSONL AuditFormat = "jsonl"
+)
+
 // AuditLogger handles audit trail logging for cleanup operations.
 type AuditLogger struct {
 	file   *os.File
 	now    func() time.Time
 	closed bool
+	format AuditFormat
 }
 
-// NewAuditLogger creates a new audit logger that writes to the specified path.
+// auditEntry is the JSON representation of an audit entry.
+type auditEntry struct {
+	Time    string `json:"time"`
+	Action  Action `json:"action"`
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	Details string `json:"details,omitempty"`
+}
+
+// NewAuditLogger creates a new audit logger that writes text entries to the specified path.
 // Creates parent directories if they don't exist.
 func NewAuditLogger(path string) (*AuditLogger, error) {
+	return NewAuditLoggerWithFormat(path, AuditFormatText)
+}
+
+// NewAuditLoggerWithFormat creates a new audit logger that writes entries in
+// the given format to the specified path.
+func NewAuditLoggerWithFormat(path string, format AuditFormat) (*AuditLogger, error) {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return nil, err
@@ -29,8 +62,9 @@ func NewAuditLogger(path string) (*AuditLogger, error) {
 	}
 
 	return &AuditLogger{
-		file: file,
-		now:  time.Now,
+		file:   file,
+		now:    time.Now,
+		format: format,
 	}, nil
 }
 
@@ -42,11 +76,11 @@ func (l *AuditLogger) Log(action Action, path string, size int64) error {
 	}
 
 	timestamp := l.now().UTC().Format(time.RFC3339)
-	sizeStr := FormatSize(size)
-
-	entry := fmt.Sprintf("%s %s %s (%s)\n", timestamp, action, path, sizeStr)
+	if l.format == AuditFormatJSONL {
+		return l.writeJSON(auditEntry{Time: timestamp, Action: action, Path: path, Size: size})
+	}
 
-	_, err := l.file.WriteString(entry)
+	_, err := l.file.WriteString(timestamp + " " + FormatAuditEntry(action, path, size, "") + "\n")
 	return err
 }
 
@@ -58,10 +92,42 @@ func (l *AuditLogger) LogWithDetails(action Action, path string, details string)
 	}
 
 	timestamp := l.now().UTC().Format(time.RFC3339)
+	if l.format == AuditFormatJSONL {
+		return l.writeJSON(auditEntry{Time: timestamp, Action: action, Path: path, Details: details})
+	}
+
+	_, err := l.file.WriteString(timestamp + " " + FormatAuditEntry(action, path, 0, details) + "\n")
+	return err
+}
+
+// FormatAuditEntry formats a text audit entry without its timestamp, exactly
+// as Log (if details is empty) or LogWithDetails write it. Dry runs use it to
+// show the entries a real run would write.
+func FormatAuditEntry(action Action, path string, size int64, details string) string {
+	path = auditPath(path)
+	if details != "" {
+		return fmt.Sprintf("%s %s: %s", action, path, details)
+	}
+	return fmt.Sprintf("%s %s (%s)", action, path, FormatSize(size))
+}
 
-	entry := fmt.Sprintf("%s %s %s: %s\n", timestamp, action, path, details)
+// auditPath returns path as written in a text entry. Paths that contain the
+// ": " separating the details, or that could otherwise not be read back,
+// are quoted, so the layout of the entry stays unambiguous.
+func auditPath(path string) string {
+	if strings.Contains(path, ": ") || strings.HasPrefix(path, `"`) || strings.ContainsAny(path, "\r\n") {
+		return strconv.Quote(path)
+	}
+	return path
+}
 
-	_, err := l.file.WriteString(entry)
+// writeJSON writes a single JSON Lines entry.
+func (l *AuditLogger) writeJSON(entry auditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = l.file.Write(append(data, '\n'))
 	return err
 }
 
@@ -75,3 +141,127 @@ func (l *AuditLogger) Close() error {
 func DefaultAuditLogPath(claudeHome string) string {
 	return filepath.Join(claudeHome, "cccc-audit.log")
 }
+
+// AuditEntry is an entry read back from an audit log.
+type AuditEntry struct {
+	Time    time.Time
+	Action  Action
+	Path    string
+	Size    int64 // Approximate for text entries, which store a formatted size
+	Details string
+}
+
+// auditSizePattern matches the formatted size at the end of a text entry,
+// and auditSizeSuffix the formatted size alone after a quoted path.
+var (
+	auditSizePattern = regexp.MustCompile(`^(.*) \((\d+(?:\.\d+)?) (B|KB|MB|GB)\)$`)
+	auditSizeSuffix  = regexp.MustCompile(`^ \((\d+(?:\.\d+)?) (B|KB|MB|GB)\)$`)
+)
+
+// ParseAuditLog reads the entries of an audit log written in either format.
+// Lines that are not audit entries are skipped, so a log that was switched
+// between formats or cut short by a crash can still be read.
+func ParseAuditLog(r io.Reader) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line = strings.TrimSpace(line); line != "" {
+			if entry, ok := parseAuditLine(line); ok {
+				entries = append(entries, entry)
+			}
+		}
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+	}
+}
+
+// parseAuditLine parses a single text or JSON Lines audit entry.
+func parseAuditLine(line string) (AuditEntry, bool) {
+	if strings.HasPrefix(line, "{") {
+		var raw auditEntry
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			return AuditEntry{}, false
+		}
+		t, err := time.Parse(time.RFC3339, raw.Time)
+		if err != nil {
+			return AuditEntry{}, false
+		}
+		return AuditEntry{Time: t, Action: raw.Action, Path: raw.Path, Size: raw.Size, Details: raw.Details}, true
+	}
+
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) != 3 {
+		return AuditEntry{}, false
+	}
+	t, err := time.Parse(time.RFC3339, fields[0])
+	if err != nil {
+		return AuditEntry{}, false
+	}
+	entry := AuditEntry{Time: t, Action: Action(fields[1])}
+
+	// A quoted path may contain anything, so what follows it is read by the
+	// fixed layout: ": details", " (size)" or nothing
+	rest := fields[2]
+	if quoted, err := strconv.QuotedPrefix(rest); err == nil {
+		entry.Path, _ = strconv.Unquote(quoted)
+		tail := rest[len(quoted):]
+		if details, ok := strings.CutPrefix(tail, ": "); ok {
+			entry.Details = details
+		} else if m := auditSizeSuffix.FindStringSubmatch(tail); m != nil {
+			entry.Size = parseFormattedSize(m[1], m[2])
+		}
+		return entry, true
+	}
+
+	// Otherwise the path contains no ": ", so the details are separated at
+	// the first one. This is checked first as the details may end in a
+	// size themselves.
+	if path, details, ok := strings.Cut(rest, ": "); ok {
+		entry.Path, entry.Details = path, details
+		return entry, true
+	}
+	if m := auditSizePattern.FindStringSubmatch(rest); m != nil {
+		entry.Path, entry.Size = m[1], parseFormattedSize(m[2], m[3])
+		return entry, true
+	}
+	entry.Path = rest
+	return entry, true
+}
+
+// parseFormattedSize reverses FormatSize as closely as its rounding allows.
+func parseFormattedSize(value, unit string) int64 {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	switch unit {
+	case "KB":
+		n *= 1024
+	case "MB":
+		n *= 1024 * 1024
+	case "GB":
+		n *= 1024 * 1024 * 1024
+	}
+	return int64(n)
+}
+
+// FilterAuditEntries returns the entries logged at or after since (zero =
+// no lower bound) with one of the given actions (empty = all).
+func FilterAuditEntries(entries []AuditEntry, since time.Time, actions []Action) []AuditEntry {
+	var filtered []AuditEntry
+	for _, e := range entries {
+		if e.Time.Before(since) {
+			continue
+		}
+		if len(actions) > 0 && !slices.Contains(actions, e.Action) {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+	return filtered
+}
@@ -208,3 +208,103 @@ func TestAuditLogger_LogWithDetails_MultipleEntries(t *testing.T) {
 	assert.Contains(t, lines[1], "DELETE")
 	assert.Contains(t, lines[1], "file empty after removing duplicates")
 }
+
+func TestAuditLogger_JSONLFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "audit.log")
+
+	logger, err := NewAuditLoggerWithFormat(logPath, AuditFormatJSONL)
+	require.NoError(t, err)
+	defer logger.Close()
+
+	fixedTime := time.Date(2025, 12, 6, 16, 0, 0, 0, time.UTC)
+	logger.now = func() time.Time { return fixedTime }
+
+	require.NoError(t, logger.Log(ActionDelete, "/path/to/file", 1024))
+	require.NoError(t, logger.LogWithDetails(ActionModify, "/path/to/config", "removed allow: Bash(git:*)"))
+
+	content, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+
+	expected := `{"time":"2025-12-06T16:00:00Z","action":"DELETE","path":"/path/to/file","size":1024}` + "\n" +
+		`{"time":"2025-12-06T16:00:00Z","action":"MODIFY","path":"/path/to/config","size":0,"details":"removed allow: Bash(git:*)"}` + "\n"
+	assert.Equal(t, expected, string(content))
+}
+
+func TestParseAuditLog_BothFormats(t *testing.T) {
+	log := strings.Join([]string{
+		"2025-12-06T16:00:00Z DELETE /path/to/project (1.5 KB)",
+		"2025-12-06T16:00:01Z MODIFY /path/settings.local.json: removed 2 duplicate entries",
+		"not an audit entry",
+		`{"time":"2025-12-07T10:00:00Z","action":"DELETE","path":"/path/todo.json","size":42}`,
+		`{"time":"broken"`,
+		"2025-12-08T09:00:00Z DELETE /path/trashed: moved to trash /t/trashed (3.0 MB)",
+	}, "\n")
+
+	entries, err := ParseAuditLog(strings.NewReader(log))
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+
+	assert.Equal(t, ActionDelete, entries[0].Action)
+	assert.Equal(t, "/path/to/project", entries[0].Path)
+	assert.Equal(t, int64(1536), entries[0].Size)
+
+	assert.Equal(t, ActionModify, entries[1].Action)
+	assert.Equal(t, "/path/settings.local.json", entries[1].Path)
+	assert.Equal(t, "removed 2 duplicate entries", entries[1].Details)
+
+	assert.Equal(t, time.Date(2025, 12, 7, 10, 0, 0, 0, time.UTC), entries[2].Time)
+	assert.Equal(t, int64(42), entries[2].Size)
+
+	assert.Equal(t, "/path/trashed", entries[3].Path)
+	assert.Equal(t, "moved to trash /t/trashed (3.0 MB)", entries[3].Details)
+}
+
+func TestParseAuditLog_PathWithSeparator(t *testing.T) {
+	path := "/work/notes: draft/.claude"
+	var log strings.Builder
+	for _, entry := range []string{
+		FormatAuditEntry(ActionDelete, path, 2048, ""),
+		FormatAuditEntry(ActionModify, path, 0, "removed allow: Bash(git:*)"),
+		FormatAuditEntry(ActionDelete, `"quoted"`, 0, "moved to trash /t/x (1.0 KB)"),
+	} {
+		log.WriteString("2025-12-06T16:00:00Z " + entry + "\n")
+	}
+
+	entries, err := ParseAuditLog(strings.NewReader(log.String()))
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, path, entries[0].Path)
+	assert.Equal(t, int64(2048), entries[0].Size)
+	assert.Empty(t, entries[0].Details)
+
+	assert.Equal(t, path, entries[1].Path)
+	assert.Equal(t, "removed allow: Bash(git:*)", entries[1].Details)
+
+	assert.Equal(t, `"quoted"`, entries[2].Path)
+	assert.Equal(t, "moved to trash /t/x (1.0 KB)", entries[2].Details)
+}
+
+func TestFilterAuditEntries(t *testing.T) {
+	entries := []AuditEntry{
+		{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Action: ActionDelete, Path: "old"},
+		{Time: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), Action: ActionModify, Path: "modified"},
+		{Time: time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC), Action: ActionDelete, Path: "deleted"},
+	}
+
+	since := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	assert.Len(t, FilterAuditEntries(entries, since, nil), 2)
+
+	filtered := FilterAuditEntries(entries, since, []Action{ActionDelete})
+	require.Len(t, filtered, 1)
+	assert.Equal(t, "deleted", filtered[0].Path)
+
+	assert.Len(t, FilterAuditEntries(entries, time.Time{}, nil), 3)
+}
+
+func TestFormatAuditEntry(t *testing.T) {
+	assert.Equal(t, "DELETE /path/to/file (1.0 KB)", FormatAuditEntry(ActionDelete, "/path/to/file", 1024, ""))
+	assert.Equal(t, "MODIFY /path/to/config: removed 2 entries", FormatAuditEntry(ActionModify, "/path/to/config", 0, "removed 2 entries"))
+	assert.Equal(t, `DELETE "/path/a: b" (1.0 KB)`, FormatAuditEntry(ActionDelete, "/path/a: b", 1024, ""))
+}
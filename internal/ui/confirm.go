@@ -2,8 +2,11 @@ package ui
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"strconv"
 	"strings"
 )
 
@@ -15,6 +18,14 @@ const (
 	ConfirmNo
 )
 
+// ConfirmCountThreshold is the number of changes above which ConfirmChanges
+// asks the user to type the number of changes instead of y/N.
+const ConfirmCountThreshold = 100
+
+// ErrNoTTY is returned by ConfirmChanges when confirmation is required but
+// stdin is not a terminal.
+var ErrNoTTY = errors.New("no TTY and --yes not given")
+
 // Confirmer handles user confirmation prompts.
 type Confirmer struct {
 	In  io.Reader
@@ -25,35 +36,111 @@ type Confirmer struct {
 // Default is No (pressing Enter without input returns ConfirmNo).
 // Only "y" or "yes" (case-insensitive) returns ConfirmYes.
 func (c *Confirmer) Confirm(prompt string) ConfirmResult {
+	result, _ := c.confirm(prompt)
+	return result
+}
+
+// confirm is like Confirm but also returns the error from reading input.
+func (c *Confirmer) confirm(prompt string) (ConfirmResult, error) {
 	fmt.Fprint(c.Out, prompt)
 
 	reader := bufio.NewReader(c.In)
 	input, err := reader.ReadString('\n')
 	if err != nil {
-		return ConfirmNo
+		return ConfirmNo, err
 	}
 
 	input = strings.TrimSpace(strings.ToLower(input))
 	if input == "y" || input == "yes" {
-		return ConfirmYes
+		return ConfirmYes, nil
+	}
+
+	return ConfirmNo, nil
+}
+
+// ConfirmCount asks the user to type n to confirm an operation on n items and
+// returns the result. Any other input, including y, returns ConfirmNo.
+func (c *Confirmer) ConfirmCount(n int) ConfirmResult {
+	result, _ := c.confirmCount(n)
+	return result
+}
+
+// confirmCount is like ConfirmCount but also returns the error from reading
+// input.
+func (c *Confirmer) confirmCount(n int) (ConfirmResult, error) {
+	fmt.Fprintf(c.Out, "This affects %d items. Type %d to proceed: ", n, n)
+
+	reader := bufio.NewReader(c.In)
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		return ConfirmNo, err
 	}
 
-	return ConfirmNo
+	if strings.TrimSpace(input) == strconv.Itoa(n) {
+		return ConfirmYes, nil
+	}
+	return ConfirmNo, nil
 }
 
+// AutoApprove selects which previews ConfirmChanges approves without prompting.
+type AutoApprove int
+
+const (
+	AutoApproveNone   AutoApprove = iota // Always prompt
+	AutoApproveModify                    // Skip the prompt unless something is deleted
+	AutoApproveAll                       // Never prompt
+)
+
 // ConfirmChanges displays a preview and prompts for confirmation.
 // If autoYes is true, it displays the preview but skips the prompt.
+// If in is not a terminal (e.g. a pipe or /dev/null under cron) and runs out
+// of input before an answer is read, it aborts with ErrNoTTY without printing
+// it. Previews with
+// more than ConfirmCountThreshold changes must be confirmed by typing the
+// number of changes.
 func ConfirmChanges(preview *Preview, in io.Reader, out io.Writer, autoYes bool) (bool, error) {
+	auto := AutoApproveNone
+	if autoYes {
+		auto = AutoApproveAll
+	}
+	return ConfirmChangesWithAutoApprove(preview, in, out, out, auto)
+}
+
+// ConfirmChangesWithAutoApprove is like ConfirmChanges but lets the caller
+// approve previews that only modify files while still prompting before any
+// deletion. The prompt is written to prompt instead of out, so that it can
+// be kept out of a copy of the output, e.g. in an --output file.
+func ConfirmChangesWithAutoApprove(preview *Preview, in io.Reader, out, prompt io.Writer, auto AutoApprove) (bool, error) {
 	if err := preview.Display(out); err != nil {
 		return false, err
 	}
 
-	if autoYes {
+	switch auto {
+	case AutoApproveAll:
 		return true, nil
+	case AutoApproveModify:
+		if !preview.HasDeletions() {
+			return true, nil
+		}
+		fmt.Fprintln(prompt, "\nThese changes include deletions; confirmation is required.")
 	}
 
-	confirmer := &Confirmer{In: in, Out: out}
-	result := confirmer.Confirm("\nProceed? [y/N]: ")
+	fmt.Fprintf(prompt, "\n%s\n", confirmDigest(preview))
+
+	confirmer := &Confirmer{In: in, Out: prompt}
+	var result ConfirmResult
+	var err error
+	if n := len(preview.Changes); n > ConfirmCountThreshold {
+		result, err = confirmer.confirmCount(n)
+	} else {
+		result, err = confirmer.confirm("Proceed? [y/N]: ")
+	}
+	if err != nil && !IsInteractive(in) {
+		// End the unanswered prompt line; reporting the error is up to the
+		// caller, as it belongs on stderr
+		fmt.Fprintln(prompt)
+		return false, ErrNoTTY
+	}
 
 	if result != ConfirmYes {
 		fmt.Fprintln(out, "Aborted. No changes made.")
@@ -62,3 +149,73 @@ func ConfirmChanges(preview *Preview, in io.Reader, out io.Writer, autoYes bool)
 
 	return true, nil
 }
+
+// confirmDigest summarizes the changes of a preview in one line, e.g.
+// "About to DELETE 12 items (340.0 MB) and MODIFY 3 files.", so the
+// consequences are visible right above the prompt even after a long preview.
+func confirmDigest(preview *Preview) string {
+	var deleted, modified, created int
+	var deletedSize int64
+	for _, c := range preview.Changes {
+		switch c.Action {
+		case ActionDelete:
+			deleted++
+			deletedSize += c.Size
+		case ActionModify:
+			modified++
+		case ActionCreate:
+			created++
+		}
+	}
+
+	var parts []string
+	if deleted > 0 {
+		parts = append(parts, fmt.Sprintf("DELETE %s (%s)", pluralItems(deleted), FormatSize(deletedSize)))
+	}
+	if modified > 0 {
+		parts = append(parts, "MODIFY "+PluralFiles(modified))
+	}
+	if created > 0 {
+		parts = append(parts, "CREATE "+PluralFiles(created))
+	}
+	if len(parts) == 0 {
+		return "Nothing to change."
+	}
+
+	digest := parts[len(parts)-1]
+	if len(parts) > 1 {
+		digest = strings.Join(parts[:len(parts)-1], ", ") + " and " + digest
+	}
+	return "About to " + digest + "."
+}
+
+// PluralFiles formats "n file(s)".
+func PluralFiles(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
+
+// IsInteractive reports whether in is a terminal. Readers that are not
+// files (e.g. in tests) are treated as interactive.
+func IsInteractive(in io.Reader) bool {
+	f, ok := in.(*os.File)
+	if !ok {
+		return true
+	}
+	return isTerminal(f)
+}
+
+// isTerminal reports whether f refers to a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	// /dev/null is a character device too, but never a terminal
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(info, null) {
+		return false
+	}
+	return true
+}
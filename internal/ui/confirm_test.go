@@ -2,6 +2,9 @@ package ui
 
 import (
 	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -185,3 +188,200 @@ func TestConfirmChanges_ShowsAbortedMessage(t *testing.T) {
 
 	assert.Contains(t, output.String(), "Aborted")
 }
+
+func TestConfirmChanges_NonTTYEOFAborts(t *testing.T) {
+	// An empty regular file stands in for a redirected stdin with no input
+	inPath := filepath.Join(t.TempDir(), "stdin")
+	require.NoError(t, os.WriteFile(inPath, nil, 0644))
+	in, err := os.Open(inPath)
+	require.NoError(t, err)
+	defer in.Close()
+
+	output := &bytes.Buffer{}
+	preview := &Preview{
+		Title:   "Test",
+		Changes: []Change{{Action: ActionDelete, Path: "/test", Size: 100}},
+	}
+
+	confirmed, err := ConfirmChanges(preview, in, output, false)
+
+	assert.ErrorIs(t, err, ErrNoTTY)
+	assert.False(t, confirmed)
+	assert.NotContains(t, output.String(), "No TTY", "the caller reports the error")
+}
+
+func TestConfirmChanges_NonTTYWithPipedAnswer(t *testing.T) {
+	inPath := filepath.Join(t.TempDir(), "stdin")
+	require.NoError(t, os.WriteFile(inPath, []byte("y\n"), 0644))
+	in, err := os.Open(inPath)
+	require.NoError(t, err)
+	defer in.Close()
+
+	output := &bytes.Buffer{}
+	preview := &Preview{Title: "Test"}
+
+	confirmed, err := ConfirmChanges(preview, in, output, false)
+
+	require.NoError(t, err)
+	assert.True(t, confirmed)
+}
+
+func TestConfirmChanges_DevNullAborts(t *testing.T) {
+	in, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	defer in.Close()
+
+	output := &bytes.Buffer{}
+	preview := &Preview{Title: "Test"}
+
+	confirmed, err := ConfirmChanges(preview, in, output, false)
+
+	assert.ErrorIs(t, err, ErrNoTTY)
+	assert.False(t, confirmed)
+}
+
+func TestConfirmChanges_NonTTYFileWithAutoYes(t *testing.T) {
+	inPath := filepath.Join(t.TempDir(), "stdin")
+	require.NoError(t, os.WriteFile(inPath, nil, 0644))
+	in, err := os.Open(inPath)
+	require.NoError(t, err)
+	defer in.Close()
+
+	output := &bytes.Buffer{}
+	preview := &Preview{Title: "Test"}
+
+	confirmed, err := ConfirmChanges(preview, in, output, true)
+
+	require.NoError(t, err)
+	assert.True(t, confirmed)
+}
+
+func TestConfirmChangesWithAutoApprove_ModifyOnly(t *testing.T) {
+	preview := &Preview{
+		Title:   "Test",
+		Changes: []Change{{Action: ActionModify, Path: "/test/settings.local.json"}},
+	}
+
+	input := strings.NewReader("") // No input provided
+	output := &bytes.Buffer{}
+
+	confirmed, err := ConfirmChangesWithAutoApprove(preview, input, output, output, AutoApproveModify)
+	require.NoError(t, err)
+
+	assert.True(t, confirmed, "modify-only previews should be approved without prompting")
+	assert.NotContains(t, output.String(), "Proceed?")
+}
+
+func TestConfirmChangesWithAutoApprove_DeletePrompts(t *testing.T) {
+	preview := &Preview{
+		Title: "Test",
+		Changes: []Change{
+			{Action: ActionModify, Path: "/test/settings.local.json"},
+			{Action: ActionDelete, Path: "/test/other.json"},
+		},
+	}
+
+	input := strings.NewReader("n\n")
+	output := &bytes.Buffer{}
+
+	confirmed, err := ConfirmChangesWithAutoApprove(preview, input, output, output, AutoApproveModify)
+	require.NoError(t, err)
+
+	assert.False(t, confirmed, "previews with deletions must still prompt")
+	assert.Contains(t, output.String(), "Proceed?")
+	assert.Contains(t, output.String(), "Aborted")
+}
+
+func TestConfirmChangesWithAutoApprove_PromptWriter(t *testing.T) {
+	preview := &Preview{
+		Title:   "Test",
+		Changes: []Change{{Action: ActionDelete, Path: "/test/other.json"}},
+	}
+
+	var out, prompt bytes.Buffer
+	confirmed, err := ConfirmChangesWithAutoApprove(preview, strings.NewReader("y\n"), &out, &prompt, AutoApproveNone)
+	require.NoError(t, err)
+
+	assert.True(t, confirmed)
+	assert.Contains(t, out.String(), "/test/other.json")
+	assert.NotContains(t, out.String(), "Proceed?")
+	assert.NotContains(t, out.String(), "About to DELETE")
+	assert.Contains(t, prompt.String(), "About to DELETE 1 item")
+	assert.Contains(t, prompt.String(), "Proceed? [y/N]: ")
+}
+
+func TestConfirmChanges_PrintsDigestAbovePrompt(t *testing.T) {
+	preview := &Preview{
+		Title: "Test",
+		Changes: []Change{
+			{Action: ActionDelete, Path: "/a", Size: 1024},
+			{Action: ActionDelete, Path: "/b", Size: 1024},
+			{Action: ActionModify, Path: "/c"},
+		},
+	}
+
+	output := &bytes.Buffer{}
+	_, err := ConfirmChanges(preview, strings.NewReader("n\n"), output, false)
+	require.NoError(t, err)
+
+	assert.Contains(t, output.String(), "About to DELETE 2 items (2.0 KB) and MODIFY 1 file.\nProceed? [y/N]: ")
+}
+
+func TestConfirmDigest(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes []Change
+		want    string
+	}{
+		{"empty", nil, "Nothing to change."},
+		{"delete only", []Change{{Action: ActionDelete, Size: 10}}, "About to DELETE 1 item (10 B)."},
+		{"modify only", []Change{{Action: ActionModify}, {Action: ActionModify}}, "About to MODIFY 2 files."},
+		{
+			"all actions",
+			[]Change{{Action: ActionDelete}, {Action: ActionModify}, {Action: ActionCreate}},
+			"About to DELETE 1 item (0 B), MODIFY 1 file and CREATE 1 file.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, confirmDigest(&Preview{Changes: tt.changes}))
+		})
+	}
+}
+
+func TestConfirmer_ConfirmCount(t *testing.T) {
+	tests := []struct {
+		input string
+		want  ConfirmResult
+	}{
+		{"150\n", ConfirmYes},
+		{" 150 \n", ConfirmYes},
+		{"y\n", ConfirmNo},
+		{"149\n", ConfirmNo},
+		{"", ConfirmNo},
+	}
+
+	for _, tc := range tests {
+		output := &bytes.Buffer{}
+		confirmer := &Confirmer{In: strings.NewReader(tc.input), Out: output}
+		assert.Equal(t, tc.want, confirmer.ConfirmCount(150), "input %q", tc.input)
+		assert.Equal(t, "This affects 150 items. Type 150 to proceed: ", output.String())
+	}
+}
+
+func TestConfirmChanges_LargePreviewRequiresCount(t *testing.T) {
+	preview := &Preview{Title: "Test"}
+	for i := 0; i <= ConfirmCountThreshold; i++ {
+		preview.Changes = append(preview.Changes, Change{Action: ActionDelete, Path: fmt.Sprintf("/test/%d", i)})
+	}
+	n := len(preview.Changes)
+
+	confirmed, err := ConfirmChanges(preview, strings.NewReader("y\n"), &bytes.Buffer{}, false)
+	require.NoError(t, err)
+	assert.False(t, confirmed, "y must not confirm a large cleanup")
+
+	confirmed, err = ConfirmChanges(preview, strings.NewReader(fmt.Sprintf("%d\n", n)), &bytes.Buffer{}, false)
+	require.NoError(t, err)
+	assert.True(t, confirmed)
+}
@@ -1,8 +1,11 @@
 package ui
 
 import (
+	"cmp"
 	"fmt"
 	"io"
+	"slices"
+	"time"
 )
 
 // Action represents the type of change.
@@ -12,6 +15,9 @@ const (
 	ActionDelete Action = "DELETE"
 	ActionModify Action = "MODIFY"
 	ActionCreate Action = "CREATE"
+	// ActionSkip is only used in the audit log, for items that were
+	// confirmed but left alone, e.g. because they became active.
+	ActionSkip Action = "SKIP"
 )
 
 // Change represents a single change to be made.
@@ -20,6 +26,7 @@ type Change struct {
 	Path        string
 	Description string
 	Size        int64
+	Note        string // Informational line listed below the change, e.g. follow-up cleanups
 }
 
 // Preview represents a set of changes to be previewed and confirmed.
@@ -27,6 +34,10 @@ type Preview struct {
 	Title   string
 	Changes []Change
 	Kept    []Change // Items that will NOT be changed (for context)
+
+	SummaryOnly bool // Display counts per action instead of listing every change
+	PageSize    int  // If > 0, Display lists at most this many changes, see DisplayPaged
+	HideKept    bool // Display only the changes, not the Kept items
 }
 
 // TotalSize returns the total size of all changes.
@@ -38,23 +49,69 @@ func (p *Preview) TotalSize() int64 {
 	return total
 }
 
+// SortBySize orders the changes by size, largest first, so the items that
+// free the most space are listed at the top. Changes of equal size keep their
+// order. Kept items are not reordered.
+func (p *Preview) SortBySize() {
+	slices.SortStableFunc(p.Changes, func(a, b Change) int {
+		return cmp.Compare(b.Size, a.Size)
+	})
+}
+
+// HasDeletions returns true if any change deletes a file or directory.
+func (p *Preview) HasDeletions() bool {
+	for _, c := range p.Changes {
+		if c.Action == ActionDelete {
+			return true
+		}
+	}
+	return false
+}
+
 // Display writes a formatted preview to the given writer.
+// If SummaryOnly is set, it writes the summary from DisplaySummary instead;
+// if PageSize is set, it writes the first page from DisplayPaged.
 func (p *Preview) Display(w io.Writer) error {
+	if p.SummaryOnly {
+		return p.DisplaySummary(w)
+	}
+	if p.PageSize > 0 {
+		return p.DisplayPaged(w, p.PageSize)
+	}
+	return p.displayPage(w, len(p.Changes))
+}
+
+// DisplayPaged is like Display but lists only the first pageSize changes and
+// summarizes the remaining ones per action, so that previews of tens of
+// thousands of items stay readable and quick to print.
+func (p *Preview) DisplayPaged(w io.Writer, pageSize int) error {
+	return p.displayPage(w, max(pageSize, 1))
+}
+
+// displayPage writes the preview with the first n changes listed.
+func (p *Preview) displayPage(w io.Writer, n int) error {
 	fmt.Fprintf(w, "=== %s ===\n\n", p.Title)
 
 	if len(p.Changes) > 0 {
 		fmt.Fprintln(w, "Changes:")
-		for i, c := range p.Changes {
+		for i, c := range p.Changes[:min(n, len(p.Changes))] {
 			fmt.Fprintf(w, "  %d. [%s] %s\n", i+1, c.Action, c.Path)
 			if c.Description != "" {
 				fmt.Fprintf(w, "     %s\n", c.Description)
 			}
 			fmt.Fprintf(w, "     Size: %s\n", FormatSize(c.Size))
+			if c.Note != "" {
+				fmt.Fprintf(w, "     Note: %s\n", c.Note)
+			}
+		}
+		if rest := p.Changes[min(n, len(p.Changes)):]; len(rest) > 0 {
+			fmt.Fprintf(w, "  ... %s not listed (use --verbose to list all):\n", pluralItems(len(rest)))
+			writeActionCounts(w, "     ", rest)
 		}
 		fmt.Fprintln(w)
 	}
 
-	if len(p.Kept) > 0 {
+	if len(p.Kept) > 0 && !p.HideKept {
 		fmt.Fprintln(w, "Kept (no changes):")
 		for i, c := range p.Kept {
 			fmt.Fprintf(w, "  %d. %s\n", i+1, c.Path)
@@ -69,6 +126,51 @@ func (p *Preview) Display(w io.Writer) error {
 	return nil
 }
 
+// DisplaySummary writes the title, the number and size of changes per
+// action, and the total size, without enumerating individual paths.
+func (p *Preview) DisplaySummary(w io.Writer) error {
+	fmt.Fprintf(w, "=== %s ===\n\n", p.Title)
+
+	if len(p.Changes) > 0 {
+		fmt.Fprintln(w, "Changes:")
+		writeActionCounts(w, "  ", p.Changes)
+		fmt.Fprintln(w)
+	}
+
+	if len(p.Kept) > 0 && !p.HideKept {
+		fmt.Fprintf(w, "Kept (no changes): %s\n\n", pluralItems(len(p.Kept)))
+	}
+
+	fmt.Fprintf(w, "Total: %s\n", FormatSize(p.TotalSize()))
+	return nil
+}
+
+// writeActionCounts writes the number and size of changes per action, one
+// indented line each.
+func writeActionCounts(w io.Writer, indent string, changes []Change) {
+	for _, action := range []Action{ActionDelete, ActionModify, ActionCreate} {
+		var count int
+		var size int64
+		for _, c := range changes {
+			if c.Action == action {
+				count++
+				size += c.Size
+			}
+		}
+		if count > 0 {
+			fmt.Fprintf(w, "%s[%s] %s (%s)\n", indent, action, pluralItems(count), FormatSize(size))
+		}
+	}
+}
+
+// pluralItems formats "n item(s)".
+func pluralItems(n int) string {
+	if n == 1 {
+		return "1 item"
+	}
+	return fmt.Sprintf("%d items", n)
+}
+
 // FormatSize formats a byte size as a human-readable string (e.g., "14 MB").
 func FormatSize(bytes int64) string {
 	const (
@@ -88,3 +190,46 @@ func FormatSize(bytes int64) string {
 		return fmt.Sprintf("%d B", bytes)
 	}
 }
+
+// FormatRelativeTime formats t relative to now (e.g., "3 months ago").
+// A zero time is formatted as "never".
+func FormatRelativeTime(t time.Time) string {
+	return formatRelativeTime(t, time.Now())
+}
+
+// formatRelativeTime formats t relative to the given current time.
+func formatRelativeTime(t, now time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+
+	const (
+		day   = 24 * time.Hour
+		month = 30 * day
+		year  = 365 * day
+	)
+
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return pluralAgo(int(d/time.Minute), "minute")
+	case d < day:
+		return pluralAgo(int(d/time.Hour), "hour")
+	case d < month:
+		return pluralAgo(int(d/day), "day")
+	case d < year:
+		return pluralAgo(int(d/month), "month")
+	default:
+		return pluralAgo(int(d/year), "year")
+	}
+}
+
+// pluralAgo formats "n unit(s) ago".
+func pluralAgo(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s ago", unit)
+	}
+	return fmt.Sprintf("%d %ss ago", n, unit)
+}
@@ -3,6 +3,7 @@ package ui
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -84,6 +85,39 @@ func TestPreview_Display_ShowsKeptItems(t *testing.T) {
 	assert.Contains(t, buf.String(), "/path/to/keep")
 }
 
+func TestPreview_Display_HideKept(t *testing.T) {
+	preview := &Preview{
+		Title:    "Test",
+		Changes:  []Change{{Action: ActionDelete, Path: "/path/to/delete", Size: 1000}},
+		Kept:     []Change{{Path: "/path/to/keep", Description: "Active project", Size: 500}},
+		HideKept: true,
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, preview.Display(&buf))
+	assert.Contains(t, buf.String(), "/path/to/delete")
+	assert.NotContains(t, buf.String(), "Kept")
+
+	buf.Reset()
+	require.NoError(t, preview.DisplaySummary(&buf))
+	assert.NotContains(t, buf.String(), "Kept")
+}
+
+func TestPreview_Display_ShowsNote(t *testing.T) {
+	preview := &Preview{
+		Title: "Test",
+		Changes: []Change{
+			{Action: ActionDelete, Path: "/path/a", Size: 1000, Note: "cleaning this orphans 2 todos"},
+			{Action: ActionDelete, Path: "/path/b", Size: 1000},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, preview.Display(&buf))
+	assert.Contains(t, buf.String(), "     Size: 1000 B\n     Note: cleaning this orphans 2 todos\n")
+	assert.NotContains(t, buf.String(), "/path/b\n     Size: 1000 B\n     Note:")
+}
+
 func TestPreview_Display_ShowsTotalSize(t *testing.T) {
 	preview := &Preview{
 		Title: "Test",
@@ -131,3 +165,126 @@ func TestFormatSize_Zero(t *testing.T) {
 
 	assert.Equal(t, "0 B", result)
 }
+
+func TestPreview_HasDeletions(t *testing.T) {
+	preview := &Preview{
+		Changes: []Change{{Action: ActionModify, Path: "/path1"}},
+	}
+	assert.False(t, preview.HasDeletions())
+
+	preview.Changes = append(preview.Changes, Change{Action: ActionDelete, Path: "/path2"})
+	assert.True(t, preview.HasDeletions())
+}
+
+func TestFormatRelativeTime(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		t        time.Time
+		expected string
+	}{
+		{time.Time{}, "never"},
+		{now.Add(-30 * time.Second), "just now"},
+		{now.Add(-time.Minute), "1 minute ago"},
+		{now.Add(-5 * time.Hour), "5 hours ago"},
+		{now.Add(-3 * 24 * time.Hour), "3 days ago"},
+		{now.Add(-95 * 24 * time.Hour), "3 months ago"},
+		{now.Add(-800 * 24 * time.Hour), "2 years ago"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, formatRelativeTime(tc.t, now))
+	}
+}
+
+func TestPreview_DisplaySummary_CountsPerAction(t *testing.T) {
+	preview := &Preview{
+		Title: "Summary",
+		Changes: []Change{
+			{Action: ActionDelete, Path: "/path/a", Size: 1024},
+			{Action: ActionDelete, Path: "/path/b", Size: 1024},
+			{Action: ActionModify, Path: "/path/c", Size: 10},
+		},
+		Kept: []Change{{Path: "/path/kept"}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, preview.DisplaySummary(&buf))
+
+	output := buf.String()
+	assert.Contains(t, output, "=== Summary ===")
+	assert.Contains(t, output, "[DELETE] 2 items (2.0 KB)")
+	assert.Contains(t, output, "[MODIFY] 1 item (10 B)")
+	assert.NotContains(t, output, "CREATE")
+	assert.Contains(t, output, "Kept (no changes): 1 item")
+	assert.Contains(t, output, "Total: 2.0 KB")
+	assert.NotContains(t, output, "/path/")
+}
+
+func TestPreview_DisplayPaged(t *testing.T) {
+	preview := &Preview{
+		Title: "Paged",
+		Changes: []Change{
+			{Action: ActionDelete, Path: "/path/a", Size: 1024},
+			{Action: ActionDelete, Path: "/path/b", Size: 1024},
+			{Action: ActionDelete, Path: "/path/c", Size: 1024},
+			{Action: ActionModify, Path: "/path/d", Size: 10},
+		},
+		PageSize: 1,
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, preview.Display(&buf))
+
+	output := buf.String()
+	assert.Contains(t, output, "  1. [DELETE] /path/a\n")
+	assert.NotContains(t, output, "/path/b")
+	assert.Contains(t, output, "  ... 3 items not listed (use --verbose to list all):\n"+
+		"     [DELETE] 2 items (2.0 KB)\n"+
+		"     [MODIFY] 1 item (10 B)\n")
+	assert.Contains(t, output, "Total: 3.0 KB")
+
+	// A page that fits all changes lists them all
+	buf.Reset()
+	require.NoError(t, preview.DisplayPaged(&buf, 4))
+	assert.Contains(t, buf.String(), "  4. [MODIFY] /path/d\n")
+	assert.NotContains(t, buf.String(), "not listed")
+}
+
+func TestPreview_Display_SummaryOnly(t *testing.T) {
+	preview := &Preview{
+		Title:       "Test",
+		Changes:     []Change{{Action: ActionDelete, Path: "/path/to/delete", Size: 1}},
+		SummaryOnly: true,
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, preview.Display(&buf))
+
+	assert.Contains(t, buf.String(), "[DELETE] 1 item")
+	assert.NotContains(t, buf.String(), "/path/to/delete")
+}
+
+func TestPreview_SortBySize(t *testing.T) {
+	preview := &Preview{
+		Changes: []Change{
+			{Action: ActionDelete, Path: "/small", Size: 10},
+			{Action: ActionDelete, Path: "/large", Size: 1000},
+			{Action: ActionModify, Path: "/first-medium", Size: 100},
+			{Action: ActionDelete, Path: "/second-medium", Size: 100},
+		},
+		Kept: []Change{
+			{Path: "/kept-small", Size: 1},
+			{Path: "/kept-large", Size: 500},
+		},
+	}
+
+	preview.SortBySize()
+
+	var order []string
+	for _, c := range preview.Changes {
+		order = append(order, c.Path)
+	}
+	assert.Equal(t, []string{"/large", "/first-medium", "/second-medium", "/small"}, order)
+	assert.Equal(t, "/kept-small", preview.Kept[0].Path)
+}
@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Progress reports incremental progress of a long-running operation.
+// On a terminal it redraws a single line; otherwise it emits periodic lines
+// so logs stay readable.
+type Progress struct {
+	Out   io.Writer
+	Total int
+	tty   bool
+	every int
+}
+
+// NewProgress creates a progress reporter for total items writing to out.
+func NewProgress(out io.Writer, total int) *Progress {
+	every := total / 10
+	if every < 1 {
+		every = 1
+	}
+
+	tty := false
+	if f, ok := out.(*os.File); ok {
+		tty = isTerminal(f)
+	}
+
+	return &Progress{
+		Out:   out,
+		Total: total,
+		tty:   tty,
+		every: every,
+	}
+}
+
+// Step reports that item n (1-based) at path is being processed.
+func (p *Progress) Step(n int, path string) {
+	if p == nil {
+		return
+	}
+
+	if p.tty {
+		// Clear the line and redraw in place
+		fmt.Fprintf(p.Out, "\r\033[KCleaning %d/%d: %s", n, p.Total, path)
+		return
+	}
+
+	if n == 1 || n == p.Total || n%p.every == 0 {
+		fmt.Fprintf(p.Out, "Cleaning %d/%d: %s\n", n, p.Total, path)
+	}
+}
+
+// Done finishes the progress output.
+func (p *Progress) Done() {
+	if p == nil {
+		return
+	}
+	if p.tty {
+		fmt.Fprint(p.Out, "\r\033[K")
+	}
+}
@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress_NonTTYEmitsPeriodicLines(t *testing.T) {
+	output := &bytes.Buffer{}
+	progress := NewProgress(output, 20)
+
+	for i := 1; i <= 20; i++ {
+		progress.Step(i, "/path")
+	}
+	progress.Done()
+
+	// The first item plus every 2nd item (20/10), which includes the last
+	assert.Equal(t, 11, bytes.Count(output.Bytes(), []byte("\n")))
+	assert.Contains(t, output.String(), "Cleaning 1/20: /path\n")
+	assert.Contains(t, output.String(), "Cleaning 20/20: /path\n")
+	assert.NotContains(t, output.String(), "\r")
+}
+
+func TestProgress_SmallTotalReportsEveryItem(t *testing.T) {
+	output := &bytes.Buffer{}
+	progress := NewProgress(output, 3)
+
+	progress.Step(1, "/a")
+	progress.Step(2, "/b")
+	progress.Step(3, "/c")
+
+	assert.Equal(t, "Cleaning 1/3: /a\nCleaning 2/3: /b\nCleaning 3/3: /c\n", output.String())
+}
+
+func TestProgress_NilIsNoop(t *testing.T) {
+	var progress *Progress
+	progress.Step(1, "/path")
+	progress.Done()
+}
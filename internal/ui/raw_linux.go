@@ -0,0 +1,9 @@
+package ui
+
+import "syscall"
+
+// ioctl requests to get and set terminal attributes.
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package ui
+
+import (
+	"errors"
+	"os"
+)
+
+// makeRaw is not supported on this platform, so SelectChanges falls back to
+// asking about every change.
+func makeRaw(*os.File) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported")
+}
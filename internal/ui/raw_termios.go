@@ -0,0 +1,39 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package ui
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// makeRaw switches the terminal f to raw mode, so key presses are read
+// immediately and not echoed, and returns a function restoring the previous
+// mode. Output processing is left on, so "\n" still starts a new line.
+func makeRaw(f *os.File) (restore func(), err error) {
+	fd := f.Fd()
+	var old syscall.Termios
+	if err := termiosIoctl(fd, ioctlGetTermios, &old); err != nil {
+		return nil, err
+	}
+
+	raw := old
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Iflag &^= syscall.IXON | syscall.ICRNL
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := termiosIoctl(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() { _ = termiosIoctl(fd, ioctlSetTermios, &old) }, nil
+}
+
+// termiosIoctl gets or sets the terminal attributes of fd.
+func termiosIoctl(fd, req uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(t))); errno != 0 { // #nosec G103 -- ioctl needs a pointer to the termios struct
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// RenderMarkdownReport writes the previews as a markdown cleanup plan, with
+// one table of changes per preview and a summary table with the totals.
+func RenderMarkdownReport(w io.Writer, generated time.Time, previews ...*Preview) error {
+	var b strings.Builder
+
+	b.WriteString("# Cleanup Plan\n\n")
+	fmt.Fprintf(&b, "Generated %s. No changes have been made.\n", generated.UTC().Format("2006-01-02 15:04 MST"))
+
+	var totalItems int
+	var totalSize int64
+	for _, p := range previews {
+		fmt.Fprintf(&b, "\n## %s\n\n", p.Title)
+
+		if len(p.Changes) == 0 {
+			b.WriteString("Nothing to clean.\n")
+		} else {
+			b.WriteString("| Action | Path | Details | Size |\n")
+			b.WriteString("|--------|------|---------|-----:|\n")
+			for _, c := range p.Changes {
+				fmt.Fprintf(&b, "| %s | %s | %s | %s |\n",
+					c.Action, markdownCode(c.Path), markdownCell(c.Description), FormatSize(c.Size))
+			}
+			fmt.Fprintf(&b, "\n**Total: %s, %s**\n", pluralItems(len(p.Changes)), FormatSize(p.TotalSize()))
+		}
+
+		if len(p.Kept) > 0 {
+			fmt.Fprintf(&b, "\nKept (no changes): %s\n", pluralItems(len(p.Kept)))
+		}
+
+		totalItems += len(p.Changes)
+		totalSize += p.TotalSize()
+	}
+
+	b.WriteString("\n## Summary\n\n")
+	b.WriteString("| Section | Items | Size |\n")
+	b.WriteString("|---------|------:|-----:|\n")
+	for _, p := range previews {
+		fmt.Fprintf(&b, "| %s | %d | %s |\n", markdownCell(p.Title), len(p.Changes), FormatSize(p.TotalSize()))
+	}
+	fmt.Fprintf(&b, "| **Total** | **%d** | **%s** |\n", totalItems, FormatSize(totalSize))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// DiskUsage is the space a clean frees on one filesystem.
+type DiskUsage struct {
+	MountPoint string
+	Items      int
+	Size       int64
+}
+
+// RenderMarkdownDiskUsage writes the space freed per filesystem as a
+// markdown section, to be appended to a report.
+func RenderMarkdownDiskUsage(w io.Writer, usage []DiskUsage) error {
+	var b strings.Builder
+
+	b.WriteString("\n## Reclaimable by Disk\n\n")
+	if len(usage) == 0 {
+		b.WriteString("Nothing to clean.\n")
+	} else {
+		b.WriteString("| Mount point | Items | Size |\n")
+		b.WriteString("|-------------|------:|-----:|\n")
+		for _, u := range usage {
+			fmt.Fprintf(&b, "| %s | %d | %s |\n", markdownCode(u.MountPoint), u.Items, FormatSize(u.Size))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCell escapes text for use in a markdown table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// markdownCode formats a path as inline code inside a table cell.
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + markdownCell(s) + "`"
+}
@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderMarkdownReport(t *testing.T) {
+	stale := &Preview{
+		Title: "Stale Project Cleanup",
+		Changes: []Change{
+			{Action: ActionDelete, Path: "/gone/project", Description: "3 files | old", Size: 2048},
+		},
+		Kept: []Change{{Path: "/kept"}},
+	}
+	config := &Preview{Title: "Config Deduplication"}
+	generated := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderMarkdownReport(&buf, generated, stale, config))
+
+	output := buf.String()
+	assert.Contains(t, output, "# Cleanup Plan\n")
+	assert.Contains(t, output, "Generated 2025-06-01 12:00 UTC.")
+	assert.Contains(t, output, "## Stale Project Cleanup\n")
+	assert.Contains(t, output, "| DELETE | `/gone/project` | 3 files \\| old | 2.0 KB |\n")
+	assert.Contains(t, output, "**Total: 1 item, 2.0 KB**")
+	assert.Contains(t, output, "Kept (no changes): 1 item")
+	assert.Contains(t, output, "## Config Deduplication\n\nNothing to clean.\n")
+	assert.Contains(t, output, "| Config Deduplication | 0 | 0 B |\n")
+	assert.Contains(t, output, "| **Total** | **1** | **2.0 KB** |\n")
+}
+
+func TestRenderMarkdownDiskUsage(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, RenderMarkdownDiskUsage(&buf, []DiskUsage{
+		{MountPoint: "/", Items: 3, Size: 2048},
+		{MountPoint: "/Volumes/Data", Items: 1, Size: 10},
+	}))
+
+	output := buf.String()
+	assert.Contains(t, output, "## Reclaimable by Disk\n")
+	assert.Contains(t, output, "| `/` | 3 | 2.0 KB |\n")
+	assert.Contains(t, output, "| `/Volumes/Data` | 1 | 10 B |\n")
+
+	buf.Reset()
+	require.NoError(t, RenderMarkdownDiskUsage(&buf, nil))
+	assert.Contains(t, buf.String(), "Nothing to clean.")
+}
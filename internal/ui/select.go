@@ -0,0 +1,199 @@
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrSelectionAborted is returned by SelectChanges if the user quits the
+// selection without confirming it.
+var ErrSelectionAborted = errors.New("selection aborted")
+
+// selectPageSize is the number of changes the selection list shows at once.
+const selectPageSize = 15
+
+// SelectChanges lets the user pick which changes to apply and returns the
+// indices of the picked ones, in order. On a terminal it shows a checkbox
+// list (arrow keys or j/k to move, space to toggle, a to toggle all, enter
+// to confirm, q to quit); nothing is picked initially. Otherwise, or if the
+// terminal cannot be switched to raw mode, it asks about every change in
+// turn. If in is not a terminal and runs out of input, it returns ErrNoTTY.
+func SelectChanges(changes []Change, in io.Reader, out io.Writer) ([]int, error) {
+	if f, ok := in.(*os.File); ok && isTerminal(f) {
+		if restore, err := makeRaw(f); err == nil {
+			defer restore()
+			return selectInteractive(changes, bufio.NewReader(in), out)
+		}
+	}
+	return selectByPrompt(changes, in, out)
+}
+
+// selectionList is the state of the checkbox list.
+type selectionList struct {
+	changes  []Change
+	selected []bool
+	cursor   int
+	top      int // First change shown
+	drawn    int // Lines drawn by the last render
+}
+
+// selectInteractive runs the checkbox list, reading keys from r, which must
+// deliver them unbuffered by the terminal (raw mode).
+func selectInteractive(changes []Change, r *bufio.Reader, out io.Writer) ([]int, error) {
+	l := &selectionList{changes: changes, selected: make([]bool, len(changes))}
+	for {
+		l.render(out)
+
+		key, err := readKey(r)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "up", "k":
+			l.move(-1)
+		case "down", "j":
+			l.move(1)
+		case " ":
+			if len(changes) > 0 {
+				l.selected[l.cursor] = !l.selected[l.cursor]
+			}
+		case "a":
+			all := !allTrue(l.selected)
+			for i := range l.selected {
+				l.selected[i] = all
+			}
+		case "enter":
+			var picked []int
+			for i, s := range l.selected {
+				if s {
+					picked = append(picked, i)
+				}
+			}
+			return picked, nil
+		case "q", "esc", "ctrl-c":
+			return nil, ErrSelectionAborted
+		}
+	}
+}
+
+// move moves the cursor by delta, scrolling the visible page along.
+func (l *selectionList) move(delta int) {
+	l.cursor = min(max(l.cursor+delta, 0), max(len(l.changes)-1, 0))
+	if l.cursor < l.top {
+		l.top = l.cursor
+	}
+	if l.cursor >= l.top+selectPageSize {
+		l.top = l.cursor - selectPageSize + 1
+	}
+}
+
+// render redraws the list in place of the previous rendering.
+func (l *selectionList) render(out io.Writer) {
+	var b strings.Builder
+	if l.drawn > 0 {
+		fmt.Fprintf(&b, "\033[%dA", l.drawn)
+	}
+	b.WriteString("\r\033[J")
+
+	var count int
+	var size int64
+	for i, s := range l.selected {
+		if s {
+			count++
+			size += l.changes[i].Size
+		}
+	}
+	fmt.Fprintf(&b, "Select items (↑/↓ move, space toggle, a all, enter confirm, q quit): %d selected, %s\n", count, FormatSize(size))
+	lines := 1
+
+	end := min(l.top+selectPageSize, len(l.changes))
+	for i := l.top; i < end; i++ {
+		cursor, box := " ", "[ ]"
+		if i == l.cursor {
+			cursor = ">"
+		}
+		if l.selected[i] {
+			box = "[x]"
+		}
+		fmt.Fprintf(&b, "%s %s %s (%s)\n", cursor, box, l.changes[i].Path, FormatSize(l.changes[i].Size))
+		lines++
+	}
+	if hidden := len(l.changes) - (end - l.top); hidden > 0 {
+		fmt.Fprintf(&b, "  ... %d more\n", hidden)
+		lines++
+	}
+
+	l.drawn = lines
+	_, _ = io.WriteString(out, b.String())
+}
+
+// readKey reads one key press and returns its name, or the character
+// itself for printable keys.
+func readKey(r *bufio.Reader) (string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch c {
+	case '\r', '\n':
+		return "enter", nil
+	case 3:
+		return "ctrl-c", nil
+	case 27:
+		// Arrow keys are sent as ESC [ A/B; a lone ESC has nothing buffered
+		if r.Buffered() < 2 {
+			return "esc", nil
+		}
+		seq := make([]byte, 2)
+		if _, err := io.ReadFull(r, seq); err != nil {
+			return "", err
+		}
+		switch string(seq) {
+		case "[A", "OA":
+			return "up", nil
+		case "[B", "OB":
+			return "down", nil
+		}
+		return "", nil
+	}
+	return string(c), nil
+}
+
+// selectByPrompt asks about every change in turn, reading the answers line
+// by line from in.
+func selectByPrompt(changes []Change, in io.Reader, out io.Writer) ([]int, error) {
+	reader := bufio.NewReader(in)
+	var picked []int
+	for i, c := range changes {
+		fmt.Fprintf(out, "%s %s (%s)? [y/N/q]: ", c.Action, c.Path, FormatSize(c.Size))
+		input, err := reader.ReadString('\n')
+		if err != nil && input == "" {
+			if !IsInteractive(in) {
+				fmt.Fprintln(out)
+				return nil, ErrNoTTY
+			}
+			return nil, err
+		}
+		switch strings.TrimSpace(strings.ToLower(input)) {
+		case "y", "yes":
+			picked = append(picked, i)
+		case "q", "quit":
+			return nil, ErrSelectionAborted
+		}
+	}
+	return picked, nil
+}
+
+// allTrue reports whether every value is true.
+func allTrue(values []bool) bool {
+	for _, v := range values {
+		if !v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testChanges() []Change {
+	return []Change{
+		{Action: ActionDelete, Path: "/a", Size: 10},
+		{Action: ActionDelete, Path: "/b", Size: 20},
+		{Action: ActionDelete, Path: "/c", Size: 30},
+	}
+}
+
+func TestSelectInteractive(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   string
+		picked []int
+		err    error
+	}{
+		{"nothing picked", "\r", nil, nil},
+		{"toggle first", " \r", []int{0}, nil},
+		{"arrow down", "\x1b[B \x1b[B \r", []int{1, 2}, nil},
+		{"j and k", "jjk \r", []int{1}, nil},
+		{"toggle twice", "  \r", nil, nil},
+		{"all", "a\r", []int{0, 1, 2}, nil},
+		{"all toggles back", "aa\r", nil, nil},
+		{"cursor stops at the end", "jjjjj \r", []int{2}, nil},
+		{"quit", " q", nil, ErrSelectionAborted},
+		{"ctrl-c", " \x03", nil, ErrSelectionAborted},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			picked, err := selectInteractive(testChanges(), bufio.NewReader(strings.NewReader(tc.keys)), &out)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.picked, picked)
+		})
+	}
+}
+
+func TestSelectInteractive_Render(t *testing.T) {
+	var out bytes.Buffer
+	_, err := selectInteractive(testChanges(), bufio.NewReader(strings.NewReader("j \r")), &out)
+	require.NoError(t, err)
+
+	output := out.String()
+	assert.Contains(t, output, "> [ ] /a (10 B)")
+	assert.Contains(t, output, "> [x] /b (20 B)")
+	assert.Contains(t, output, "1 selected, 20 B")
+	// Later renders move back up over the previous one
+	assert.Contains(t, output, "\033[4A")
+}
+
+func TestSelectInteractive_Scrolls(t *testing.T) {
+	var changes []Change
+	for i := range selectPageSize + 5 {
+		changes = append(changes, Change{Path: filepath.Join("/p", string(rune('a'+i)))})
+	}
+
+	var out bytes.Buffer
+	keys := strings.Repeat("j", selectPageSize) + " \r"
+	picked, err := selectInteractive(changes, bufio.NewReader(strings.NewReader(keys)), &out)
+	require.NoError(t, err)
+	assert.Equal(t, []int{selectPageSize}, picked)
+	assert.Contains(t, out.String(), "... 5 more")
+}
+
+func TestSelectChanges_PromptFallback(t *testing.T) {
+	var out bytes.Buffer
+	picked, err := SelectChanges(testChanges(), strings.NewReader("y\n\nyes\n"), &out)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 2}, picked)
+	assert.Contains(t, out.String(), "DELETE /b (20 B)? [y/N/q]: ")
+
+	_, err = SelectChanges(testChanges(), strings.NewReader("y\nq\n"), &out)
+	assert.ErrorIs(t, err, ErrSelectionAborted)
+}
+
+func TestSelectChanges_NoTTY(t *testing.T) {
+	devNull, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	defer devNull.Close()
+
+	var out bytes.Buffer
+	_, err = SelectChanges(testChanges(), devNull, &out)
+	assert.ErrorIs(t, err, ErrNoTTY)
+}
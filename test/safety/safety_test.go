@@ -66,7 +66,7 @@ func TestSafety_NeverDeletesExistingProject(t *testing.T) {
 	if len(stale) != 0 {
 		t.Errorf("expected 0 stale projects, got %d", len(stale))
 		for _, p := range stale {
-			t.Errorf("  incorrectly marked stale: %s (exists=%v)", p.ActualPath, p.Exists())
+			t.Errorf("  incorrectly marked stale: %s (status=%v)", p.ActualPath, p.Status())
 		}
 	}
 
@@ -186,8 +186,8 @@ func TestSafety_AuditLogAlwaysWritten(t *testing.T) {
 	}
 }
 
-// TestSafety_PartialFailureStops verifies that cleanup stops on error.
-func TestSafety_PartialFailureStops(t *testing.T) {
+// TestSafety_PartialFailureReported verifies that a failed removal is reported.
+func TestSafety_PartialFailureReported(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	// Create orphan items, one of which will be read-only (can't delete)
@@ -283,7 +283,7 @@ func TestSafety_PreviewMatchesAction(t *testing.T) {
 	stale := cleaner.FindStaleProjects(projects)
 	var kept []claude.Project
 	for _, p := range projects {
-		if p.Exists() {
+		if p.Status() == claude.StatusActive {
 			kept = append(kept, p)
 		}
 	}
@@ -417,9 +417,9 @@ func TestSafety_EmptyPathNeverDeletes(t *testing.T) {
 		FileCount:   1,
 	}
 
-	// Exists() should return false for empty path
-	if project.Exists() {
-		t.Errorf("project with empty path should not report as existing")
+	// Status() should report the missing cwd for an empty path
+	if status := project.Status(); status != claude.StatusNoCWD {
+		t.Errorf("project with empty path should have status NoCWD, got %v", status)
 	}
 
 	// FindStaleProjects should mark this as stale